@@ -4,7 +4,6 @@ package main
 
 import (
 	"flag"
-	"log"
 	"math/rand"
 	"syscall"
 	"time"
@@ -12,6 +11,7 @@ import (
 	"github.com/kumasuke120/mockuma/internal"
 	"github.com/kumasuke120/mockuma/internal/loader"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 	"github.com/kumasuke120/mockuma/internal/myos"
 	"github.com/kumasuke120/mockuma/internal/server"
 	"github.com/ztrue/shutdown"
@@ -23,38 +23,56 @@ var port = flag.Int("p", 3214,
 var mapfile = flag.String("mapfile", "",
 	"sets the name of a json file which defines mockuMappings")
 var showVersion = flag.Bool("version", false, "shows the version information for MocKuma")
+var logLevel = flag.String("loglevel", "info",
+	"sets the minimum level of logs to output, one of debug, info, warn and error; "+
+		"overrides the value in the config of mockuMappings")
+var logFormat = flag.String("logformat", "text",
+	"sets the format of logs, either text or json; overrides the value in the config of mockuMappings")
+var logBody = flag.Int("logbody", 0,
+	"sets the maximum number of bytes of request and response bodies recorded in access logs, "+
+		"0 disables body recording; overrides the value in the config of mockuMappings")
+var seed = flag.Int64("seed", 0,
+	"sets the seed for random latencies and policy selections, which makes runs reproducible; "+
+		"a time-based seed is used if not specified")
 
-func init() {
-	// set random seed
-	rand.Seed(time.Now().UnixNano())
+var mainLog = mylog.New("main")
 
+func init() {
 	// initialize current working directory
 	err := myos.InitWd()
 	if err != nil {
-		log.Fatalln("[main    ] cannot get current working directory")
+		mainLog.Fatalf("cannot get current working directory")
 	}
 }
 
 func main() {
 	flag.Parse()
+	checkLogFlags()
 
 	if *showVersion {
 		internal.PrintVersion()
 	} else {
+		initRandomSeed()
+
 		ld := loader.New(*mapfile)
 		mappings := loadMappings(ld)
+		configureLogging(mappings)
 
 		// adds a shutdown hook
 		shutdown.Add(func() {
 			if err := ld.Clean(); err != nil {
-				log.Println("[main    ] fail to clean temporary directories: " + err.Error())
+				mainLog.Errorf("fail to clean temporary directories: %v", err)
 			}
 		})
 
 		// starts mock server
 		s := server.NewMockServer(*port)
-		if err := ld.EnableAutoReload(s.SetMappings); err != nil {
-			log.Fatalln("[main    ] cannot enable automatic reloading:", err)
+		reload := func(mappings *mckmaps.MockuMappings) {
+			configureLogging(mappings)
+			s.SetMappings(mappings)
+		}
+		if err := ld.EnableAutoReload(reload); err != nil {
+			mainLog.Fatalf("cannot enable automatic reloading: %v", err)
 		}
 		go s.ListenAndServe(mappings)
 
@@ -65,10 +83,66 @@ func main() {
 func loadMappings(ld *loader.Loader) *mckmaps.MockuMappings {
 	mappings, err := ld.Load()
 	if err != nil {
-		log.Fatalln("[main    ] cannot load mockuMappings:", err)
+		mainLog.Fatalf("cannot load mockuMappings: %v", err)
 	}
 	if mappings.IsEmpty() {
-		log.Fatalln("[main    ] cannot started with the given empty mockuMappings")
+		mainLog.Fatalf("cannot started with the given empty mockuMappings")
 	}
 	return mappings
 }
+
+// sets the random seed, logs it so that the run could be reproduced with -seed
+func initRandomSeed() {
+	s := *seed
+	if !isFlagSet("seed") {
+		s = time.Now().UnixNano()
+	}
+	rand.Seed(s)
+	mainLog.Infof("random seed = %d", s)
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func checkLogFlags() {
+	if _, err := mylog.ParseLevel(*logLevel); err != nil {
+		mainLog.Fatalf("invalid argument -loglevel: %v", err)
+	}
+	if _, err := mylog.ParseFormat(*logFormat); err != nil {
+		mainLog.Fatalf("invalid argument -logformat: %v", err)
+	}
+	if *logBody < 0 {
+		mainLog.Fatalf("invalid argument -logbody: %d, must not be negative", *logBody)
+	}
+	configureLogging(nil)
+}
+
+// applies the log options in the config of mappings, options explicitly given
+// by the command-line arguments take precedence
+func configureLogging(mappings *mckmaps.MockuMappings) {
+	level, format, bodyLimit := mylog.LevelInfo, mylog.FormatText, 0
+	if mappings != nil && mappings.Config != nil && mappings.Config.Log != nil {
+		lo := mappings.Config.Log
+		level, format, bodyLimit = lo.Level, lo.Format, lo.BodyLimit
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "loglevel":
+			level, _ = mylog.ParseLevel(*logLevel)
+		case "logformat":
+			format, _ = mylog.ParseFormat(*logFormat)
+		case "logbody":
+			bodyLimit = *logBody
+		}
+	})
+
+	mylog.Configure(level, format, bodyLimit)
+}
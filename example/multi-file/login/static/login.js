@@ -96,7 +96,7 @@
             type: "post",
             data: {username},
             headers: {
-                "Authorization": `Basic ${btoa(username + "/" + password)}`
+                "Authorization": `Basic ${btoa(username + ":" + password)}`
             },
             success: function (data) {
                 if (isRemember) {
@@ -8,7 +8,16 @@ require (
 	github.com/rs/cors v1.7.0
 
 	github.com/fsnotify/fsnotify v1.4.7
-	golang.org/x/sys v0.0.0-20191206220618-eeba5f6aabab // indirect
+	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
+
+	github.com/gorilla/websocket v1.4.2
+
+	golang.org/x/net v0.0.0-20200813134508-3edf25e44fcc
+	google.golang.org/protobuf v1.27.1
+
+	github.com/antchfx/xmlquery v1.3.5
+	github.com/antchfx/xpath v1.1.10
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 
 	github.com/stretchr/testify v1.5.1
 	github.com/stretchr/objx v0.2.0 // indirect
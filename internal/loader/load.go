@@ -1,15 +1,17 @@
 package loader
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 	"github.com/kumasuke120/mockuma/internal/myos"
 )
 
+var logger = mylog.New("loader")
+
 var defaultMapfile = []string{
 	"mockuMappings.json",
 	"mockuMappings.main.json",
@@ -71,7 +73,7 @@ func (l *Loader) absFilename() error {
 func (l *Loader) beforeLoadZip() error {
 	err := l.Clean()
 	if err != nil {
-		log.Println("[loader  ] fail to clean temporary directories: " + err.Error())
+		logger.Warnf("fail to clean temporary directories: %v", err)
 	}
 
 	dir, err := unzip(l.filename)
@@ -196,7 +198,7 @@ func chdirBasedOnDir(dir string) error {
 			return err
 		}
 
-		log.Println("[loader  ] chdir    : working directory changed:", dir)
+		logger.Infof("chdir    : working directory changed: %s", dir)
 	}
 
 	return nil
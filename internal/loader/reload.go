@@ -1,7 +1,6 @@
 package loader
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
@@ -10,6 +9,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/metrics"
 	"github.com/kumasuke120/mockuma/internal/myos"
 )
 
@@ -119,7 +119,7 @@ func (w *fileWatcher) addListener(listener fileChangeListener) {
 func (w *fileWatcher) watch() {
 	defer func() {
 		if err := w.watcher.Close(); err != nil {
-			log.Println("[loader  ] fail to close watcher:", err)
+			logger.Warnf("fail to close watcher: %v", err)
 		}
 	}()
 
@@ -145,13 +145,13 @@ func (w *fileWatcher) doWatch() (stop bool) {
 				if abs, err := filepath.Abs(name); err == nil {
 					name = abs
 				} else {
-					log.Fatalln("[loader  ] cannot retrieve absolute path:", err)
+					logger.Fatalf("cannot retrieve absolute path: %v", err)
 				}
 			}
 
 			if event.Op&fsnotify.Create == fsnotify.Create {
 				if err := w.addWatchRecursively(name); err != nil { // adds the newly-created file
-					log.Fatalln("[loader  ] cannot add new file for automatic reloading:", err)
+					logger.Fatalf("cannot add new file for automatic reloading: %v", err)
 				}
 			}
 			if w.isConcernedFile(name) {
@@ -160,7 +160,7 @@ func (w *fileWatcher) doWatch() (stop bool) {
 		}
 	case err, ok = <-w.watcher.Errors:
 		if ok {
-			log.Println("[loader  ] failure encountered when watching files:", err)
+			logger.Errorf("failure encountered when watching files: %v", err)
 		}
 	default:
 		time.Sleep(watchInterval)
@@ -238,7 +238,7 @@ type autoReloadListener struct {
 }
 
 func (l *autoReloadListener) onFileChange(path string) {
-	log.Println("[loader  ] changed  :", path)
+	logger.Infof("changed  : %s", path)
 
 	// there can be only one goroutine reloading at the same time
 	l.reloadMux.Lock()
@@ -250,12 +250,15 @@ func (l *autoReloadListener) onFileChange(path string) {
 	var mappings *mckmaps.MockuMappings
 	var err error
 	if mappings, err = l.l.Load(); err != nil {
-		log.Println("[loader  ] fail to load mockuMappings after changing:", err)
+		logger.Errorf("fail to load mockuMappings after changing: %v", err)
+		metrics.Reloads.Inc(metrics.ReloadFailure)
+	} else {
+		metrics.Reloads.Inc(metrics.ReloadSuccess)
 	}
 
 	// starts a new watcher goroutine, preventing from exiting
 	if err = l.l.EnableAutoReload(l.callback); err != nil {
-		log.Fatalln("[loader  ] cannot enable automatic reloading:", err)
+		logger.Fatalf("cannot enable automatic reloading: %v", err)
 	}
 
 	if mappings != nil {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/metrics"
 	"github.com/kumasuke120/mockuma/internal/myos"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -173,9 +174,11 @@ func TestLoader_EnableAutoReload(t *testing.T) {
 	})
 	assert.Nil(e1)
 
+	reloads := metrics.Reloads.Value(metrics.ReloadSuccess)
 	time.Sleep(watchInterval * 2)
 	require.Nil(ioutil.WriteFile(n1, []byte(`{"type": "main","include": {"mappings": []}}`), 0644))
 	assert.True(<-okChan)
+	assert.Equal(reloads+1, metrics.Reloads.Value(metrics.ReloadSuccess))
 
 	time.Sleep(watchInterval * 2)
 	require.Nil(ioutil.WriteFile(n1, []byte(`{}`), 0644))
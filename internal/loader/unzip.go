@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 )
@@ -17,7 +16,7 @@ func unzip(filename string) (string, error) {
 	}
 	defer func() {
 		if err := r.Close(); err != nil {
-			log.Println("[loader  ] fail to close zip reader")
+			logger.Warnf("fail to close zip reader")
 		}
 	}()
 
@@ -32,7 +31,7 @@ func unzip(filename string) (string, error) {
 			return "", err
 		}
 	}
-	log.Println("[loader  ] unzip    : archive extracted to:", dir)
+	logger.Infof("unzip    : archive extracted to: %s", dir)
 
 	return dir, nil
 }
@@ -49,7 +48,7 @@ func unzipToDir(f *zip.File, dir string) error {
 	}
 	defer func() {
 		if err := srcFR.Close(); err != nil {
-			log.Println("[loader  ] fail to close a file in the zip reader")
+			logger.Warnf("fail to close a file in the zip reader")
 		}
 	}()
 
@@ -70,7 +69,7 @@ func unzipToDir(f *zip.File, dir string) error {
 		}
 		defer func() {
 			if err := dstF.Close(); err != nil {
-				log.Fatalln("[loader  ] cannot close the newly-created file in temporary directory")
+				logger.Fatalf("cannot close the newly-created file in temporary directory")
 			}
 		}()
 
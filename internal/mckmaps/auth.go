@@ -0,0 +1,154 @@
+package mckmaps
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myjwt"
+)
+
+// Auth matches credentials carried by the header 'Authorization', all of the
+// present matchers must be matched
+type Auth struct {
+	Basic  *myjson.ExtJSONMatcher // matches '{"username": ..., "password": ...}' of Basic credentials
+	Bearer *myjson.ExtJSONMatcher // matches the Bearer token as a string
+	JWT    *JWTMatcher            // matches the Bearer token as a JWT
+}
+
+// JWTMatcher matches claims of a JWT, its signature and time-based claims
+// ('exp' and 'nbf') are verified only if a secret or a public key is given
+type JWTMatcher struct {
+	Claims    *myjson.ExtJSONMatcher // nil matches any claims
+	Secret    []byte                 // verifies HMAC signatures, i.e. HS256, HS384 and HS512
+	PublicKey crypto.PublicKey       // verifies RSA (RS*, PS*) or ECDSA (ES*) signatures
+}
+
+func (p *mappingsParser) parseAuth(v interface{}) (*Auth, error) {
+	vo, ok := v.(myjson.Object)
+	if !ok {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	p.jsonPath.Append("")
+
+	auth := new(Auth)
+
+	p.jsonPath.SetLast(pBasic)
+	if vo.Has(pBasic) {
+		switch vo.Get(pBasic).(type) {
+		case myjson.Object, myjson.ExtJSONMatcher:
+		default:
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		basic, err := p.parseAuthMatcher(vo.Get(pBasic))
+		if err != nil {
+			return nil, err
+		}
+		auth.Basic = basic
+	}
+
+	p.jsonPath.SetLast(pBearer)
+	if vo.Has(pBearer) {
+		if vo.Get(pBearer) == nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		bearer, err := p.parseAuthMatcher(vo.Get(pBearer))
+		if err != nil {
+			return nil, err
+		}
+		auth.Bearer = bearer
+	}
+
+	p.jsonPath.SetLast(pJWT)
+	if vo.Has(pJWT) {
+		jwt, err := p.parseJWTMatcher(vo.Get(pJWT))
+		if err != nil {
+			return nil, err
+		}
+		auth.JWT = jwt
+	}
+
+	if auth.Basic != nil && (auth.Bearer != nil || auth.JWT != nil) {
+		p.jsonPath.SetLast(pBasic)
+		return nil, &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("'%s' cannot be used together with '%s' or '%s'", pBasic, pBearer, pJWT),
+		}
+	}
+
+	p.jsonPath.RemoveLast()
+	return auth, nil
+}
+
+func (p *mappingsParser) parseJWTMatcher(v interface{}) (*JWTMatcher, error) {
+	vo, ok := v.(myjson.Object)
+	if !ok {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	p.jsonPath.Append("")
+
+	jwt := new(JWTMatcher)
+
+	p.jsonPath.SetLast(pSecret)
+	if vo.Has(pSecret) {
+		secret, err := vo.GetString(pSecret)
+		if err != nil || secret == "" {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		jwt.Secret = []byte(secret)
+	}
+
+	p.jsonPath.SetLast(pPublicKey)
+	if vo.Has(pPublicKey) {
+		if jwt.Secret != nil {
+			return nil, &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      errors.New("only one of 'secret' and 'publicKey' can be specified"),
+			}
+		}
+		rawKey, err := vo.GetString(pPublicKey) // usually loaded by '@file'
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		key, err := myjwt.ParsePublicKey([]byte(rawKey))
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+		jwt.PublicKey = key
+	}
+
+	p.jsonPath.SetLast(pClaims)
+	if vo.Has(pClaims) {
+		switch vo.Get(pClaims).(type) {
+		case myjson.Object, myjson.ExtJSONMatcher:
+		default:
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		claims, err := p.parseAuthMatcher(vo.Get(pClaims))
+		if err != nil {
+			return nil, err
+		}
+		jwt.Claims = claims
+	}
+
+	p.jsonPath.RemoveLast()
+	return jwt, nil
+}
+
+// values are always matched as json, checking arguments of operators
+func (p *mappingsParser) parseAuthMatcher(v interface{}) (*myjson.ExtJSONMatcher, error) {
+	var matcher myjson.ExtJSONMatcher
+	switch v.(type) {
+	case myjson.ExtJSONMatcher:
+		matcher = v.(myjson.ExtJSONMatcher)
+	default:
+		matcher = myjson.MakeExtJSONMatcher(v)
+	}
+	if err := matcher.Check(); err != nil {
+		return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+	}
+	return &matcher, nil
+}
@@ -16,6 +16,10 @@ const (
 	dVars     = "@vars"
 	dRegexp   = "@regexp"
 	dJSON     = "@json"
+	dXML      = "@xml"
+	dContains = "@contains"
+	dPrefix   = "@prefix"
+	dTokens   = "@tokens"
 )
 
 // attributes
@@ -26,10 +30,26 @@ const (
 
 	aConfigCORS               = "cors"
 	aConfigMatchTrailingSlash = "matchTrailingSlash"
+	aConfigMatchParams        = "matchParams"
+	aConfigAdmin              = "admin"
+	aConfigLog                = "log"
+	aConfigHTTP2              = "http2"
+	aConfigTLS                = "tls"
+	aConfigOAuth2             = "oauth2"
 
 	aMapURI      = "uri"
 	aMapMethod   = "method"
 	aMapPolicies = "policies"
+	aMapGRPC     = "grpc"
+	aMapGraphQL  = "graphql"
+	aMapPriority = "priority"
+
+	aMapMatchParams = aConfigMatchParams
+)
+
+const (
+	grpcDescriptorSet = "descriptorSet"
+	grpcMethod        = "method"
 )
 
 const (
@@ -42,23 +62,118 @@ const (
 	corsExposedHeaders   = "exposedHeaders"
 )
 
+const (
+	adminEnabled = "enabled"
+	adminPath    = "path"
+)
+
+const (
+	oauth2Path         = "path"
+	oauth2Issuer       = "issuer"
+	oauth2ExpiresIn    = "expiresIn"
+	oauth2Clients      = "clients"
+	oauth2ClientID     = "clientId"
+	oauth2ClientSecret = "clientSecret"
+	oauth2RedirectURIs = "redirectUris"
+	oauth2Users        = "users"
+	oauth2Username     = "username"
+	oauth2Password     = "password"
+	oauth2Claims       = "claims"
+)
+
+const (
+	tlsCertFile = "certFile"
+	tlsKeyFile  = "keyFile"
+)
+
+const (
+	logLevel     = "level"
+	logFormat    = "format"
+	logBodyLimit = "bodyLimit"
+)
+
 const (
 	mapPolicyWhen      = "when"
 	mapPolicyReturns   = "returns"
 	mapPolicyForwards  = "forwards"
 	mapPolicyRedirects = "redirects"
+	mapPolicySequence  = "sequence"
+	mapPolicyOneOf     = "oneOf"
+	mapPolicyFaults    = "faults"
+	mapPolicyEvents    = "events"
+	mapPolicyWebSocket = "websocket"
+
+	mapPolicyTimes       = "times"
+	mapPolicyProbability = "probability"
 )
 
 // commands of mappings policies
-var mapPolicyCommands = []string{mapPolicyReturns, mapPolicyForwards, mapPolicyRedirects}
+var mapPolicyCommands = []string{mapPolicyReturns, mapPolicyForwards, mapPolicyRedirects, mapPolicySequence,
+	mapPolicyOneOf, mapPolicyFaults, mapPolicyEvents, mapPolicyWebSocket}
 
 // attributes for mappings policies
 const (
-	pStatusCode = "statusCode"
-	pHeaders    = "headers"
-	pParams     = "params"
-	pPathVars   = "pathVars"
-	pBody       = "body"
-	pLatency    = "latency"
-	pPath       = "path"
+	pStatusCode    = "statusCode"
+	pHeaders       = "headers"
+	pParams        = "params"
+	pPathVars      = "pathVars"
+	pCookies       = "cookies"
+	pBody          = "body"
+	pBodyPaths     = "bodyPaths"
+	pBodyXPath     = "bodyXPath"
+	pFiles         = "files"
+	pAnyOf         = "anyOf"
+	pAllOf         = "allOf"
+	pNot           = "not"
+	pAuth          = "auth"
+	pBasic         = "basic"
+	pBearer        = "bearer"
+	pJWT           = "jwt"
+	pSecret        = "secret"
+	pPublicKey     = "publicKey"
+	pClaims        = "claims"
+	pFilename      = "filename"
+	pContentType   = "contentType"
+	pContent       = "content"
+	pLatency       = "latency"
+	pPath          = "path"
+	pCycle         = "cycle"
+	pWeight        = "weight"
+	pType          = "type"
+	pSize          = "size"
+	pInterval      = "interval"
+	pBytes         = "bytes"
+	pThrottle      = "throttle"
+	pChunks        = "chunks"
+	pCount         = "count"
+	pDelay         = "delay"
+	pID            = "id"
+	pEvent         = "event"
+	pData          = "data"
+	pRetry         = "retry"
+	pRepeat        = "repeat"
+	pOnConnect     = "onConnect"
+	pReplies       = "replies"
+	pReply         = "reply"
+	pPushes        = "pushes"
+	pMessage       = "message"
+	pBinary        = "binary"
+	pClose         = "close"
+	pAfter         = "after"
+	pCode          = "code"
+	pReason        = "reason"
+	pSubprotocols  = "subprotocols"
+	pOperationName = "operationName"
+	pFields        = "fields"
+	pVariables     = "variables"
+	pErrors        = "errors"
+	pPush          = "push"
+	pName          = "name"
+	pValue         = "value"
+	pDomain        = "domain"
+	pExpires       = "expires"
+	pMaxAge        = "maxAge"
+	pHTTPOnly      = "httpOnly"
+	pSecure        = "secure"
+	pSameSite      = "sameSite"
 )
@@ -0,0 +1,117 @@
+package mckmaps
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+// GraphQLWhen matches GraphQL operations, which are sent as JSON in the body of
+// POST requests or as parameters of GET requests
+type GraphQLWhen struct {
+	OperationName       string
+	OperationNameRegexp *regexp.Regexp
+	Fields              []string // root fields which the operation must select
+	Variables           *myjson.ExtJSONMatcher
+}
+
+// parses 'operationName', 'fields' and 'variables' of the given when, returns nil
+// if none of them is present
+func (p *mappingsParser) parseGraphQLWhen(v myjson.Object) (*GraphQLWhen, error) {
+	if !v.Has(pOperationName) && !v.Has(pFields) && !v.Has(pVariables) {
+		return nil, nil
+	}
+
+	graphQL := new(GraphQLWhen)
+
+	p.jsonPath.SetLast(pOperationName)
+	switch rawName := v.Get(pOperationName); rawName.(type) {
+	case nil:
+	case myjson.String:
+		graphQL.OperationName = string(rawName.(myjson.String))
+	case myjson.ExtRegexp:
+		graphQL.OperationNameRegexp = rawName.(myjson.ExtRegexp)
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	p.jsonPath.SetLast(pFields)
+	if v.Has(pFields) {
+		rawFields := ensureJSONArray(v.Get(pFields))
+		if len(rawFields) == 0 || !myjson.IsAllString(rawFields) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		for _, f := range rawFields {
+			graphQL.Fields = append(graphQL.Fields, string(f.(myjson.String)))
+		}
+	}
+
+	p.jsonPath.SetLast(pVariables)
+	switch rawVariables := v.Get(pVariables); rawVariables.(type) {
+	case nil:
+	case myjson.ExtJSONMatcher:
+		_v := rawVariables.(myjson.ExtJSONMatcher)
+		graphQL.Variables = &_v
+	case myjson.Object: // variables are always matched as json
+		graphQL.Variables = myjson.NewExtJSONMatcher(rawVariables)
+		if err := graphQL.Variables.Check(); err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	return graphQL, nil
+}
+
+// sets the body of the returns to a GraphQL response made of 'data' and 'errors'
+func (p *mappingsParser) parseGraphQLReturns(returns *Returns, v myjson.Object) error {
+	if v.Has(pBody) {
+		p.jsonPath.SetLast(pBody)
+		return &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("'%s' cannot be used together with '%s' or '%s'", pBody, pData, pErrors),
+		}
+	}
+
+	response := make(myjson.Object)
+	if v.Has(pData) {
+		response[pData] = v.Get(pData)
+	}
+
+	p.jsonPath.SetLast(pErrors)
+	if v.Has(pErrors) {
+		rawErrors, err := v.GetArray(pErrors)
+		if err != nil {
+			return p.newJSONParseError(p.jsonPath)
+		}
+		response[pErrors] = rawErrors
+	}
+
+	body, err := p.parseJSONToBytes(response)
+	if err != nil {
+		return err
+	}
+	returns.Body = body
+
+	if !hasHeader(returns.Headers, myhttp.HeaderContentType) {
+		returns.Headers = append(returns.Headers, &NameValuesPair{
+			Name:   myhttp.HeaderContentType,
+			Values: []string{myhttp.ContentTypeJSON},
+		})
+	}
+	return nil
+}
+
+func hasHeader(headers []*NameValuesPair, name string) bool {
+	for _, h := range headers {
+		if http.CanonicalHeaderKey(h.Name) == name {
+			return true
+		}
+	}
+	return false
+}
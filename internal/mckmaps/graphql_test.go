@@ -0,0 +1,75 @@
+package mckmaps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingsParser_parseGraphQL(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	fb27, e27 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-27.json"))
+	require.Nil(e27)
+	j27, e27 := myjson.Unmarshal(fb27)
+	if assert.Nil(e27) {
+		m27 := &mappingsParser{json: j27}
+		p27, e27 := m27.parse()
+		if assert.Nil(e27) && assert.Len(p27, 1) {
+			m := p27[0]
+			assert.Equal("/graphql", m.URI)
+			assert.True(m.GraphQL)
+
+			if assert.Len(m.Policies, 2) {
+				assert.Equal(&GraphQLWhen{
+					OperationName: "GetUser",
+					Fields:        []string{"user"},
+					Variables:     myjson.NewExtJSONMatcher(myjson.Object{"id": myjson.String("1")}),
+				}, m.Policies[0].When.GraphQL)
+				assert.JSONEq(`{"data": {"user": {"id": "1", "name": "kuma"}}}`,
+					string(m.Policies[0].Returns.Body))
+				assert.Equal([]*NameValuesPair{
+					{Name: myhttp.HeaderContentType, Values: []string{myhttp.ContentTypeJSON}},
+				}, m.Policies[0].Returns.Headers)
+
+				if assert.NotNil(m.Policies[1].When.GraphQL) {
+					assert.Equal("^Get", m.Policies[1].When.GraphQL.OperationNameRegexp.String())
+				}
+				assert.JSONEq(`{"errors": [{"message": "not found"}]}`, string(m.Policies[1].Returns.Body))
+				assert.Equal([]*NameValuesPair{
+					{Name: "Content-Type", Values: []string{"application/graphql-response+json"}},
+				}, m.Policies[1].Returns.Headers)
+			}
+		}
+	}
+
+	fb28, e28 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-28.json"))
+	require.Nil(e28)
+	j28, e28 := myjson.Unmarshal(fb28)
+	if assert.Nil(e28) {
+		m28 := &mappingsParser{json: j28}
+		_, e28 := m28.parse()
+		if assert.NotNil(e28) {
+			assert.Equal("$.mappings[0].policies[0].returns.body", e28.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb53, e53 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-53.json"))
+	require.Nil(e53)
+	j53, e53 := myjson.Unmarshal(fb53)
+	if assert.Nil(e53) {
+		m53 := &mappingsParser{json: j53}
+		_, e53 := m53.parse()
+		if assert.NotNil(e53) {
+			assert.Equal("$.mappings[0].policies[0].when.variables", e53.(*parserError).jsonPath.String())
+		}
+	}
+}
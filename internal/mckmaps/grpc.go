@@ -0,0 +1,199 @@
+package mckmaps
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCMethod is a unary gRPC method served by a mapping, whose messages are
+// converted from and to JSON
+type GRPCMethod struct {
+	Descriptor protoreflect.MethodDescriptor
+}
+
+// Path returns the uri the method is served on, i.e. /package.Service/Method
+func (m *GRPCMethod) Path() string {
+	return fmt.Sprintf("/%s/%s", m.Descriptor.Parent().FullName(), m.Descriptor.Name())
+}
+
+// DecodeRequest converts a request message in the wire format to JSON, fields
+// with zero values are included, so that they could be matched as well
+func (m *GRPCMethod) DecodeRequest(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(m.Descriptor.Input())
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+}
+
+// EncodeResponse converts a response message in JSON to the wire format
+func (m *GRPCMethod) EncodeResponse(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(m.Descriptor.Output())
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(msg)
+}
+
+// parses a mapping serving a gRPC method, whose uri and method are derived from
+// the gRPC method, and whose bodies of returns are response messages in JSON
+func (p *mappingsParser) parseGRPCMapping(mapping *Mapping, v myjson.Object) (*Mapping, error) {
+	p.jsonPath.SetLast(aMapGRPC)
+	if v.Has(aMapURI) || v.Has(aMapMethod) {
+		return nil, &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("'%s' cannot be used together with '%s' or '%s'", aMapGRPC, aMapURI, aMapMethod),
+		}
+	}
+	rawGRPC, err := v.GetObject(aMapGRPC)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	method, err := p.parseGRPC(rawGRPC)
+	if err != nil {
+		return nil, err
+	}
+	mapping.GRPC = method
+	mapping.URI = method.Path()
+	mapping.Method = myhttp.MethodPost
+
+	policies, err := p.parsePolicies(v)
+	if err != nil {
+		return nil, err
+	}
+	p.jsonPath.SetLast(aMapPolicies)
+	p.jsonPath.Append(0)
+	for idx, policy := range policies {
+		p.jsonPath.SetLast(idx)
+		if err := p.encodeGRPCReturns(method, policy); err != nil {
+			return nil, err
+		}
+	}
+	p.jsonPath.RemoveLast()
+	mapping.Policies = policies
+
+	p.jsonPath.RemoveLast()
+	return mapping, nil
+}
+
+// encodes bodies of returns of the policy, which are response messages in JSON
+func (p *mappingsParser) encodeGRPCReturns(method *GRPCMethod, policy *Policy) error {
+	var allReturns []*Returns
+	switch policy.CmdType {
+	case CmdTypeReturns:
+		allReturns = []*Returns{policy.Returns}
+	case CmdTypeSequence:
+		allReturns = policy.Sequence.Returns
+	case CmdTypeOneOf:
+		for _, c := range policy.OneOf.Choices {
+			allReturns = append(allReturns, c.Returns)
+		}
+	default:
+		return &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("command '%s' is not supported by gRPC mappings", string(policy.CmdType)),
+		}
+	}
+
+	for _, returns := range allReturns {
+		if returns.Body == nil {
+			continue
+		}
+		body, err := method.EncodeResponse(returns.Body)
+		if err != nil {
+			return &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      fmt.Errorf("cannot encode the body as '%s': %v", method.Descriptor.Output().FullName(), err),
+			}
+		}
+		returns.Body = body
+	}
+	return nil
+}
+
+func (p *mappingsParser) parseGRPC(v myjson.Object) (*GRPCMethod, error) {
+	p.jsonPath.Append("")
+
+	p.jsonPath.SetLast(grpcDescriptorSet)
+	filename, err := v.GetString(grpcDescriptorSet)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	files, err := loadDescriptorSet(string(filename))
+	if err != nil {
+		return nil, &loadError{filename: string(filename), err: err}
+	}
+
+	p.jsonPath.SetLast(grpcMethod)
+	name, err := v.GetString(grpcMethod)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	md, err := findMethodDescriptor(files, string(name))
+	if err != nil {
+		return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+	}
+
+	p.jsonPath.RemoveLast()
+	return &GRPCMethod{Descriptor: md}, nil
+}
+
+// loads a FileDescriptorSet, which is compiled by 'protoc --include_imports --descriptor_set_out'
+func loadDescriptorSet(filename string) (*protoregistry.Files, error) {
+	if err := checkFilepath(filename); err != nil {
+		return nil, err
+	}
+
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	recordLoadedFile(filename)
+
+	set := new(descriptorpb.FileDescriptorSet)
+	if err := proto.Unmarshal(bytes, set); err != nil {
+		return nil, errors.New("not a FileDescriptorSet: " + err.Error())
+	}
+	return protodesc.NewFiles(set)
+}
+
+// finds the method of the given name, which is in the form of 'package.Service/Method'
+func findMethodDescriptor(files *protoregistry.Files, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	idx := strings.LastIndex(name, "/")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid method name '%s', must be in the form of 'package.Service/Method'", name)
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name[:idx]))
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the service '%s'", name[:idx])
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a service", name[:idx])
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name[idx+1:]))
+	if md == nil {
+		return nil, fmt.Errorf("cannot find the method '%s'", name)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("the method '%s' is not unary, only unary methods are supported", name)
+	}
+	return md, nil
+}
@@ -0,0 +1,109 @@
+package mckmaps
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestMappingsParser_parseGRPCMapping(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	fb24, e24 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-24.json"))
+	require.Nil(e24)
+	j24, e24 := myjson.Unmarshal(fb24)
+	if assert.Nil(e24) {
+		m24 := &mappingsParser{json: j24}
+		p24, e24 := m24.parse()
+		if assert.Nil(e24) && assert.Len(p24, 1) {
+			m := p24[0]
+			assert.Equal("/helloworld.Greeter/SayHello", m.URI)
+			assert.Equal(myhttp.MethodPost, m.Method)
+			if assert.NotNil(m.GRPC) {
+				assert.Equal("helloworld.Greeter.SayHello", string(m.GRPC.Descriptor.FullName()))
+			}
+
+			if assert.Len(m.Policies, 2) {
+				assert.Equal(myjson.NewExtJSONMatcher(myjson.Object{"name": myjson.String("kuma")}),
+					m.Policies[0].When.BodyJSON)
+				reply := dynamicpb.NewMessage(m.GRPC.Descriptor.Output())
+				if assert.Nil(proto.Unmarshal(m.Policies[0].Returns.Body, reply)) {
+					body, err := protojson.Marshal(reply)
+					if assert.Nil(err) {
+						assert.JSONEq(`{"message": "Hello, kuma!", "tags": ["bear"]}`, string(body))
+					}
+				}
+
+				assert.Nil(m.Policies[1].Returns.Body)
+				assert.ElementsMatch([]*NameValuesPair{
+					{Name: "grpc-message", Values: []string{"not found"}},
+					{Name: "grpc-status", Values: []string{"5"}},
+				}, m.Policies[1].Returns.Headers)
+			}
+		}
+	}
+
+	fb25, e25 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-25.json"))
+	require.Nil(e25)
+	j25, e25 := myjson.Unmarshal(fb25)
+	if assert.Nil(e25) {
+		m25 := &mappingsParser{json: j25}
+		_, e25 := m25.parse()
+		if assert.NotNil(e25) {
+			assert.Equal("$.mappings[0].grpc.method", e25.(*parserError).jsonPath.String())
+			assert.Contains(e25.Error(), "only unary methods are supported")
+		}
+	}
+
+	fb26, e26 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-26.json"))
+	require.Nil(e26)
+	j26, e26 := myjson.Unmarshal(fb26)
+	if assert.Nil(e26) {
+		m26 := &mappingsParser{json: j26}
+		_, e26 := m26.parse()
+		if assert.NotNil(e26) {
+			assert.Equal("$.mappings[0].policies[0]", e26.(*parserError).jsonPath.String())
+			assert.Contains(e26.Error(), "cannot encode the body as 'helloworld.HelloReply'")
+		}
+	}
+}
+
+func TestGRPCMethod_DecodeRequest(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	files, err := loadDescriptorSet(filepath.Join("testdata", "grpc", "greeter.protoset"))
+	require.Nil(err)
+	md, err := findMethodDescriptor(files, "/helloworld.Greeter/SayHello")
+	require.Nil(err)
+	m := &GRPCMethod{Descriptor: md}
+
+	// HelloRequest{name: "kuma"}
+	r1, err := m.DecodeRequest([]byte{0x0a, 0x04, 'k', 'u', 'm', 'a'})
+	if assert.Nil(err) {
+		assert.JSONEq(`{"name": "kuma", "age": 0}`, string(r1))
+	}
+
+	_, err = m.DecodeRequest([]byte{0x0a, 0x04})
+	assert.NotNil(err)
+
+	_, err = findMethodDescriptor(files, "helloworld.Greeter")
+	assert.NotNil(err)
+	_, err = findMethodDescriptor(files, "helloworld.Greeter/SayGoodbye")
+	assert.NotNil(err)
+	_, err = findMethodDescriptor(files, "helloworld.HelloRequest/SayHello")
+	assert.NotNil(err)
+}
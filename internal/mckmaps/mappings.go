@@ -3,34 +3,76 @@ package mckmaps
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"net/textproto"
 	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/antchfx/xpath"
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myxml"
 	"github.com/kumasuke120/mockuma/internal/types"
 )
 
 type Mapping struct {
-	URI      string
-	Method   myhttp.HTTPMethod
-	Policies []*Policy
+	URI         string
+	Method      myhttp.HTTPMethod
+	Policies    []*Policy
+	GRPC        *GRPCMethod // not nil if the mapping serves a gRPC method
+	GraphQL     bool        // whether requests are matched and responded as GraphQL operations
+	MatchParams ParamsMode  // overrides the mode in the config if not empty
+	Priority    int         // uri patterns of higher priorities are matched first
+}
+
+type ParamsMode string
+
+const (
+	// ParamsExact matches if values of each param equal the expected ones, ignoring the order
+	ParamsExact = ParamsMode("exact")
+	// ParamsPartial matches if values of each param contain the expected ones
+	ParamsPartial = ParamsMode("partial")
+	// ParamsStrict matches as ParamsExact, and rejects requests carrying params
+	// not declared in the when of the policy
+	ParamsStrict = ParamsMode("strict")
+)
+
+func parseParamsMode(v interface{}) (ParamsMode, bool) {
+	s, ok := v.(myjson.String)
+	if !ok {
+		return "", false
+	}
+	switch mode := ParamsMode(s); mode {
+	case ParamsExact, ParamsPartial, ParamsStrict:
+		return mode, true
+	default:
+		return "", false
+	}
 }
 
 type Policy struct {
-	When     *When
-	CmdType  CmdType
-	Returns  *Returns
-	Forwards *Forwards
+	When        *When
+	Times       int      // the policy only matches the first Times requests, 0 means unlimited
+	Probability *float64 // the probability that the policy matches when its conditions are met, nil means always
+	CmdType     CmdType
+	Returns     *Returns
+	Forwards    *Forwards
+	Sequence    *Sequence
+	OneOf       *OneOf
+	Faults      *Faults
+	Events      *Events
+	WebSocket   *WebSocket
 }
 
 type When struct {
-	Headers       []*NameValuesPair
+	Headers       []*NameValuesPair // names of headers are canonicalized
 	HeaderRegexps []*NameRegexpPair
 	HeaderJSONs   []*NameJSONPair
+	HeaderModes   []*NameModePair
 
 	Params       []*NameValuesPair
 	ParamRegexps []*NameRegexpPair
@@ -39,9 +81,55 @@ type When struct {
 	PathVars       []*NameValuesPair
 	PathVarRegexps []*NameRegexpPair
 
+	Cookies       []*NameValuesPair
+	CookieRegexps []*NameRegexpPair
+	CookieJSONs   []*NameJSONPair
+
 	Body       []byte
 	BodyRegexp *regexp.Regexp
 	BodyJSON   *myjson.ExtJSONMatcher
+	BodyXML    *myxml.Element
+	BodyPaths  []*BodyPathMatcher
+	BodyXPath  []*XPathMatcher
+
+	Files []*FileMatcher // files uploaded in a multipart form
+
+	Auth *Auth
+
+	GraphQL *GraphQLWhen // only for mappings in the GraphQL mode
+
+	// combinators of nested conditions, which are matched along with the above
+	AnyOf []*When
+	AllOf []*When
+	Not   *When
+}
+
+// BodyPathMatcher matches values selected by a JSONPath from the JSON body, it
+// matches if any of the selected values matches
+type BodyPathMatcher struct {
+	Path    *myjson.Path
+	Matcher myjson.ExtJSONMatcher
+}
+
+// XPathMatcher matches the result of a XPath expression evaluated on the XML
+// body, it matches if any of the selected nodes matches
+type XPathMatcher struct {
+	Expr   *xpath.Expr
+	Value  string
+	Regexp *regexp.Regexp // the value is ignored if set
+}
+
+// FileMatcher matches files uploaded with the field name in a multipart form,
+// it matches if any of the files matches, unset attributes are not matched
+type FileMatcher struct {
+	Name              string
+	Filename          string
+	FilenameRegexp    *regexp.Regexp
+	ContentType       string
+	ContentTypeRegexp *regexp.Regexp
+	Size              *Interval
+	Content           string
+	ContentRegexp     *regexp.Regexp
 }
 
 type CmdType string
@@ -50,18 +138,163 @@ const (
 	CmdTypeReturns   = CmdType(mapPolicyReturns)
 	CmdTypeForwards  = CmdType(mapPolicyForwards)
 	CmdTypeRedirects = CmdType(mapPolicyRedirects)
+	CmdTypeSequence  = CmdType(mapPolicySequence)
+	CmdTypeOneOf     = CmdType(mapPolicyOneOf)
+	CmdTypeFaults    = CmdType(mapPolicyFaults)
+	CmdTypeEvents    = CmdType(mapPolicyEvents)
+	CmdTypeWebSocket = CmdType(mapPolicyWebSocket)
 )
 
 type Returns struct {
 	StatusCode myhttp.StatusCode
 	Headers    []*NameValuesPair
+	Cookies    []*http.Cookie
 	Body       []byte
 	Latency    *Interval
+	Pacing     *Pacing
+	Push       []string // paths of resources pushed to HTTP/2 clients along with the response
 }
 
 type Forwards struct {
 	Path    string
 	Latency *Interval
+	Pacing  *Pacing // only applies to remote forwards, local ones use the pacing of their own
+}
+
+// Pacing slows down the writing of a response body
+type Pacing struct {
+	Throttle int       // the maximum number of bytes written per second, 0 means unlimited
+	Chunks   int       // the number of chunks the body is split into, 0 means not split
+	Delay    *Interval // the delay between two chunks
+}
+
+// Sequence returns its Returns in order on successive calls
+type Sequence struct {
+	Returns []*Returns
+	Cycle   bool // starts over after the last one if true, otherwise repeats the last one
+}
+
+// At returns the Returns for the n-th (0-based) call
+func (s *Sequence) At(n int) *Returns {
+	l := len(s.Returns)
+	if n < l {
+		return s.Returns[n]
+	}
+
+	if s.Cycle {
+		return s.Returns[n%l]
+	}
+	return s.Returns[l-1]
+}
+
+// OneOf returns one of its Returns randomly, according to their weights
+type OneOf struct {
+	Choices []*Choice
+}
+
+type Choice struct {
+	Weight  float64
+	Returns *Returns
+}
+
+// Faults simulates a connection-level failure instead of a well-formed response
+type Faults struct {
+	Type     FaultType
+	Returns  *Returns  // the response written partially or slowly before the failure
+	Size     int       // the number of random bytes sent for FaultGarbage
+	Interval *Interval // the delay between two drips for FaultDrip
+	Bytes    int       // the number of bytes of each drip for FaultDrip
+}
+
+type FaultType string
+
+const (
+	FaultReset             = FaultType("reset")             // resets the connection
+	FaultCloseAfterHeaders = FaultType("closeAfterHeaders") // closes the connection after headers are sent
+	FaultGarbage           = FaultType("garbage")           // sends malformed bytes then closes the connection
+	FaultStall             = FaultType("stall")             // sends nothing until the client gives up
+	FaultDrip              = FaultType("drip")              // sends the response slowly, a few bytes at a time
+)
+
+var faultTypes = []FaultType{FaultReset, FaultCloseAfterHeaders, FaultGarbage, FaultStall, FaultDrip}
+
+const (
+	defaultGarbageSize  = 1024
+	defaultDripInterval = 1000
+	defaultDripBytes    = 1
+)
+
+// Events streams server-sent events instead of a static body
+type Events struct {
+	Returns *Returns // the statusCode, headers and latency of the stream
+	Events  []*Event
+	Repeat  int // the number of times the events are sent, 0 means forever
+}
+
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int       // the reconnection time in milliseconds, 0 means not sent
+	Delay *Interval // the delay before the event is sent
+}
+
+// WebSocket upgrades the connection and talks to the client with scripted messages
+type WebSocket struct {
+	Headers      []*NameValuesPair
+	Subprotocols []string
+	OnConnect    []*WSMessage // sent once the connection is established
+	Replies      []*WSReply   // the first reply matching an incoming message is sent
+	Pushes       []*WSPush
+	Close        *WSClose // closes the connection some time after it is established
+}
+
+type WSMessage struct {
+	Data   []byte
+	Binary bool
+	Delay  *Interval // the delay before the message is sent
+}
+
+// WSReply replies to the incoming messages it matches, it matches all messages
+// if none of Body, BodyRegexp and BodyJSON is set
+type WSReply struct {
+	Body       []byte
+	BodyRegexp *regexp.Regexp
+	BodyJSON   *myjson.ExtJSONMatcher
+	Messages   []*WSMessage
+	Close      *WSClose // closes the connection after the messages are sent
+}
+
+// WSPush sends the message periodically
+type WSPush struct {
+	Interval *Interval
+	Message  *WSMessage
+	Times    int // the number of times the message is sent, 0 means forever
+}
+
+type WSClose struct {
+	After  *Interval // the delay since the connection is established, only for WebSocket.Close
+	Code   int
+	Reason string
+}
+
+const defaultWSCloseCode = 1000 // normal closure
+
+// Pick returns the Returns chosen by the given random number in [0, 1)
+func (o *OneOf) Pick(r float64) *Returns {
+	var total float64
+	for _, c := range o.Choices {
+		total += c.Weight
+	}
+
+	r *= total
+	for _, c := range o.Choices {
+		if r < c.Weight {
+			return c.Returns
+		}
+		r -= c.Weight
+	}
+	return o.Choices[len(o.Choices)-1].Returns // in case of rounding errors
 }
 
 type NameValuesPair struct {
@@ -79,6 +312,25 @@ type NameJSONPair struct {
 	JSON myjson.ExtJSONMatcher
 }
 
+// NameModePair matches values with a mode other than the exact equality
+type NameModePair struct {
+	Name   string
+	Mode   ValueMode
+	Values []string
+}
+
+type ValueMode string
+
+const (
+	// ValueContains matches if any value contains the expected one
+	ValueContains = ValueMode(dContains)
+	// ValuePrefix matches if any value starts with the expected one
+	ValuePrefix = ValueMode(dPrefix)
+	// ValueTokens matches if all expected tokens are found in the comma-separated
+	// values, ignoring cases and parameters like ';q=0.9'
+	ValueTokens = ValueMode(dTokens)
+)
+
 type Interval struct {
 	Min int64
 	Max int64
@@ -86,14 +338,163 @@ type Interval struct {
 
 var (
 	// refers to: https://tools.ietf.org/html/rfc7230#section-3.2.6
-	methodRegexp  = regexp.MustCompile("(?i)^[-!#$%&'*+._`|~\\da-z]+$")
-	pathRegexp    = regexp.MustCompile("^(?:https?://)?.+$")
-	pathVarRegexp = regexp.MustCompile("{[^}]*}")
+	methodRegexp = regexp.MustCompile("(?i)^[-!#$%&'*+._`|~\\da-z]+$")
+	pathRegexp   = regexp.MustCompile("^(?:https?://)?.+$")
+)
+
+// the patterns of built-in types of pathVars, e.g. '{id:int}'; pathVars
+// without a type match a single segment, '{rest:*}' matches the remaining
+// segments, and any other type is taken as a regexp, e.g. '{slug:[a-z-]+}'
+var pathVarTypes = map[string]string{
+	"":     "[^/]+",
+	"int":  "-?[0-9]+",
+	"uuid": "[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}",
+	"*":    ".*",
+}
+
+// pathVar locates a pathVar '{name}' or '{name:type}' in an uri
+type pathVar struct {
+	start int // the index of '{'
+	end   int // the index after '}'
+	name  string
+	typ   string
+}
+
+// finds pathVars in the uri, braces are balanced so that regexps with
+// repetitions like '{code:[A-Z]{3}}' are allowed
+func findPathVars(uri string) []*pathVar {
+	var pVars []*pathVar
+	for start := strings.IndexByte(uri, '{'); start >= 0; {
+		depth := 0
+		end := -1
+		for i := start; i < len(uri) && end < 0; i++ {
+			switch uri[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i + 1
+				}
+			}
+		}
+		if end < 0 { // unclosed, the rest is not a pathVar
+			break
+		}
+
+		pVar := &pathVar{start: start, end: end, name: uri[start+1 : end-1]}
+		if idx := strings.IndexByte(pVar.name, ':'); idx >= 0 {
+			pVar.name, pVar.typ = pVar.name[:idx], pVar.name[idx+1:]
+		}
+		pVars = append(pVars, pVar)
+
+		next := strings.IndexByte(uri[end:], '{')
+		if next < 0 {
+			break
+		}
+		start = end + next
+	}
+	return pVars
+}
+
+// returns the pattern matched by the pathVar of the type
+func pathVarPattern(typ string) (string, error) {
+	if pattern, ok := pathVarTypes[typ]; ok {
+		return pattern, nil
+	}
+
+	re, err := regexp.Compile(typ)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return "", fmt.Errorf("named group '%s' is not allowed", name)
+		}
+	}
+	return "(?:" + typ + ")", nil
+}
+
+// URIPattern converts the uri with numbered pathVars into a regexp, of which
+// each pathVar is captured by a group named 'v' followed by its index.
+// An empty string is returned if the uri has no pathVars.
+func URIPattern(uri string) string {
+	pVars := findPathVars(uri)
+	if len(pVars) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("^")
+	last := 0
+	for _, pVar := range pVars {
+		builder.WriteString(regexp.QuoteMeta(uri[last:pVar.start]))
+		pattern, err := pathVarPattern(pVar.typ)
+		if err != nil {
+			panic("Shouldn't happen") // validated by encodeURI
+		}
+		builder.WriteString(fmt.Sprintf("(?P<v%s>%s)", pVar.name, pattern))
+		last = pVar.end
+	}
+	builder.WriteString(regexp.QuoteMeta(uri[last:]))
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// ranks of segments of uris, the higher the rank is, the more specific the segment is
+const (
+	SegmentCatchAll = iota // contains a catch-all pathVar, e.g. '{rest:*}'
+	SegmentVar             // a single pathVar without a type, e.g. '{id}'
+	SegmentTypedVar        // a single pathVar with a type, e.g. '{id:int}'
+	SegmentMixed           // pathVars mixed with literals, e.g. '{name}.json'
+	SegmentLiteral         // contains no pathVars
 )
 
+// URISpecificity ranks each segment of the uri, slashes in pathVars are not
+// taken as separators of segments
+func URISpecificity(uri string) []int {
+	var ranks []int
+	pVars := findPathVars(uri)
+
+	literal, vars, catchAll, typed := false, 0, false, false
+	endSegment := func() {
+		var rank int
+		switch {
+		case catchAll:
+			rank = SegmentCatchAll
+		case vars == 0:
+			rank = SegmentLiteral
+		case vars > 1 || literal:
+			rank = SegmentMixed
+		case typed:
+			rank = SegmentTypedVar
+		default:
+			rank = SegmentVar
+		}
+		ranks = append(ranks, rank)
+		literal, vars, catchAll, typed = false, 0, false, false
+	}
+
+	for i := 1; i < len(uri); i++ { // skips the leading '/'
+		if len(pVars) != 0 && i == pVars[0].start {
+			vars++
+			catchAll = catchAll || pVars[0].typ == "*"
+			typed = pVars[0].typ != ""
+			i = pVars[0].end - 1
+			pVars = pVars[1:]
+		} else if uri[i] == '/' {
+			endSegment()
+		} else {
+			literal = true
+		}
+	}
+	endSegment()
+	return ranks
+}
+
 type mappingsParser struct {
 	json     interface{}
 	jsonPath *myjson.Path
+	graphQL  bool // whether the mapping being parsed is in the GraphQL mode
 	Parser
 }
 
@@ -154,6 +555,11 @@ func (p *mappingsParser) parseMapping(v myjson.Object) (*Mapping, error) {
 	p.jsonPath.Append("")
 
 	mapping := new(Mapping)
+	p.graphQL = false
+
+	if v.Has(aMapGRPC) {
+		return p.parseGRPCMapping(mapping, v)
+	}
 
 	p.jsonPath.SetLast(aMapURI)
 	uri, err := v.GetString(aMapURI)
@@ -182,6 +588,47 @@ func (p *mappingsParser) parseMapping(v myjson.Object) (*Mapping, error) {
 		mapping.Method = myhttp.MethodAny
 	}
 
+	p.jsonPath.SetLast(aMapGraphQL)
+	if v.Has(aMapGraphQL) {
+		graphQL, err := v.GetBoolean(aMapGraphQL)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		mapping.GraphQL = bool(graphQL)
+		p.graphQL = mapping.GraphQL
+	}
+
+	p.jsonPath.SetLast(aMapMatchParams)
+	if v.Has(aMapMatchParams) {
+		mode, ok := parseParamsMode(v.Get(aMapMatchParams))
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		mapping.MatchParams = mode
+	}
+
+	p.jsonPath.SetLast(aMapPriority)
+	if v.Has(aMapPriority) {
+		priority, err := v.GetNumber(aMapPriority)
+		if err != nil || priority != myjson.Number(int(priority)) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		mapping.Priority = int(priority)
+	}
+
+	policies, err := p.parsePolicies(v)
+	if err != nil {
+		return nil, err
+	}
+	mapping.Policies = policies
+
+	p.renamePathVars(mapping)
+
+	p.jsonPath.RemoveLast()
+	return mapping, nil
+}
+
+func (p *mappingsParser) parsePolicies(v myjson.Object) ([]*Policy, error) {
 	p.jsonPath.SetLast(aMapPolicies)
 	p.jsonPath.Append(0)
 	var policies []*Policy
@@ -200,34 +647,38 @@ func (p *mappingsParser) parseMapping(v myjson.Object) (*Mapping, error) {
 		}
 	}
 	p.jsonPath.RemoveLast()
-	mapping.Policies = policies
-
-	p.renamePathVars(mapping)
-
-	p.jsonPath.RemoveLast()
-	return mapping, nil
+	return policies, nil
 }
 
 func encodeURI(uri string) (string, error) {
 	if strings.HasPrefix(uri, "/") {
-		indices := pathVarRegexp.FindAllStringIndex(uri, -1)
-		if indices == nil {
+		pVars := findPathVars(uri)
+		if pVars == nil {
 			return doEncodeURI(uri), nil
 		} else {
 			var builder strings.Builder
-			for i, loc := range indices {
+			types := make(map[string]string, len(pVars))
+			for i, pVar := range pVars {
+				if _, err := pathVarPattern(pVar.typ); err != nil {
+					return "", fmt.Errorf("invalid type of pathVar '%s': %v", pVar.name, err)
+				}
+				if typ, ok := types[pVar.name]; ok && typ != pVar.typ {
+					return "", fmt.Errorf("pathVar '%s' is declared with different types", pVar.name)
+				}
+				types[pVar.name] = pVar.typ
+
 				var startPos int
 				if i == 0 {
 					startPos = 0
 				} else {
-					startPos = indices[i-1][1]
+					startPos = pVars[i-1].end
 				}
-				builder.WriteString(doEncodeURI(uri[startPos:loc[0]]))
+				builder.WriteString(doEncodeURI(uri[startPos:pVar.start]))
 
-				builder.WriteString(uri[loc[0]:loc[1]]) // skips pathVars
+				builder.WriteString(uri[pVar.start:pVar.end]) // skips pathVars
 
-				if i == len(indices)-1 {
-					builder.WriteString(doEncodeURI(uri[loc[1]:]))
+				if i == len(pVars)-1 {
+					builder.WriteString(doEncodeURI(uri[pVar.end:]))
 				}
 			}
 			return builder.String(), nil
@@ -261,6 +712,23 @@ func (p *mappingsParser) parsePolicy(v myjson.Object) (*Policy, error) {
 		policy.When = when
 	}
 
+	p.jsonPath.SetLast(mapPolicyTimes)
+	times, err := p.getPositiveInt(v, mapPolicyTimes, 0)
+	if err != nil {
+		return nil, err
+	}
+	policy.Times = times
+
+	p.jsonPath.SetLast(mapPolicyProbability)
+	if v.Has(mapPolicyProbability) {
+		probability, err := v.GetNumber(mapPolicyProbability)
+		if err != nil || probability < 0 || probability > 1 {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		_probability := float64(probability)
+		policy.Probability = &_probability
+	}
+
 	cntCommands := p.countCommands(v, mapPolicyCommands...)
 	if cntCommands == 0 { // sets the default command when no command found in the policy
 		policy.Returns = &Returns{
@@ -298,6 +766,58 @@ func (p *mappingsParser) parseCommand(dst *Policy, v myjson.Object) error {
 		}
 		dst.Returns = returns
 		dst.CmdType = CmdTypeReturns
+	} else if v.Has(mapPolicySequence) {
+		p.jsonPath.SetLast(mapPolicySequence)
+		sequence, err := p.parseSequence(v.Get(mapPolicySequence))
+		if err != nil {
+			return err
+		}
+		dst.Sequence = sequence
+		dst.CmdType = CmdTypeSequence
+	} else if v.Has(mapPolicyOneOf) {
+		p.jsonPath.SetLast(mapPolicyOneOf)
+		rawOneOf, err := v.GetArray(mapPolicyOneOf)
+		if err != nil {
+			return p.newJSONParseError(p.jsonPath)
+		}
+		oneOf, err := p.parseOneOf(rawOneOf)
+		if err != nil {
+			return err
+		}
+		dst.OneOf = oneOf
+		dst.CmdType = CmdTypeOneOf
+	} else if v.Has(mapPolicyFaults) {
+		p.jsonPath.SetLast(mapPolicyFaults)
+		rawFaults, err := v.GetObject(mapPolicyFaults)
+		if err != nil {
+			return p.newJSONParseError(p.jsonPath)
+		}
+		faults, err := p.parseFaults(rawFaults)
+		if err != nil {
+			return err
+		}
+		dst.Faults = faults
+		dst.CmdType = CmdTypeFaults
+	} else if v.Has(mapPolicyEvents) {
+		p.jsonPath.SetLast(mapPolicyEvents)
+		events, err := p.parseEvents(v.Get(mapPolicyEvents))
+		if err != nil {
+			return err
+		}
+		dst.Events = events
+		dst.CmdType = CmdTypeEvents
+	} else if v.Has(mapPolicyWebSocket) {
+		p.jsonPath.SetLast(mapPolicyWebSocket)
+		rawWebSocket, err := v.GetObject(mapPolicyWebSocket)
+		if err != nil {
+			return p.newJSONParseError(p.jsonPath)
+		}
+		webSocket, err := p.parseWebSocket(rawWebSocket)
+		if err != nil {
+			return err
+		}
+		dst.WebSocket = webSocket
+		dst.CmdType = CmdTypeWebSocket
 	} else if v.Has(mapPolicyRedirects) {
 		p.jsonPath.SetLast(mapPolicyRedirects)
 		rawRedirects, err := v.GetObject(mapPolicyRedirects)
@@ -360,6 +880,16 @@ func (p *mappingsParser) parseWhen(v myjson.Object) (*When, error) {
 			return nil, p.newJSONParseError(p.jsonPath)
 		}
 
+		rawHeaders, err = p.canonicalizeHeaderNames(rawHeaders)
+		if err != nil {
+			return nil, err
+		}
+		headerModes, err := p.parseNameModePairs(rawHeaders)
+		if err != nil {
+			return nil, err
+		}
+		when.HeaderModes = headerModes
+
 		normalHeaders, regexpHeaders, jsonMHeaders := divideIntoWhenMatchers(rawHeaders)
 		when.Headers = parseAsNameValuesPairs(normalHeaders)
 		when.HeaderRegexps = parseAsNameRegexpPairs(regexpHeaders)
@@ -394,132 +924,1260 @@ func (p *mappingsParser) parseWhen(v myjson.Object) (*When, error) {
 		}
 	}
 
+	p.jsonPath.SetLast(pCookies)
+	if v.Has(pCookies) {
+		rawCookies, err := v.GetObject(pCookies)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+
+		normalCookies, regexpCookies, jsonMCookies := divideIntoWhenMatchers(rawCookies)
+		when.Cookies = parseAsNameValuesPairs(normalCookies)
+		when.CookieRegexps = parseAsNameRegexpPairs(regexpCookies)
+		when.CookieJSONs = parseAsNameJSONPairs(jsonMCookies)
+	}
+
 	p.jsonPath.SetLast(pBody)
 	if v.Has(pBody) {
 		rawBody := v.Get(pBody)
-		bytes, bodyRegexp, jMatcher := p.parseWhenBody(rawBody)
-		when.Body = bytes
-		when.BodyRegexp = bodyRegexp
-		when.BodyJSON = jMatcher
-	}
-
-	p.jsonPath.RemoveLast()
-	return when, nil
-}
-
-func (p *mappingsParser) parseWhenBody(v interface{}) ([]byte, myjson.ExtRegexp, *myjson.ExtJSONMatcher) {
-	switch v.(type) {
-	case myjson.String:
-		return []byte(v.(myjson.String)), nil, nil
-	case myjson.ExtRegexp:
-		return nil, v.(myjson.ExtRegexp), nil
-	case myjson.ExtJSONMatcher:
-		_v := v.(myjson.ExtJSONMatcher)
-		return nil, nil, &_v
-	default:
-		return []byte(types.ToString(v)), nil, nil
+		if xmlBody, ok := p.asXMLBody(rawBody); ok {
+			element, err := p.parseWhenBodyXML(xmlBody)
+			if err != nil {
+				return nil, err
+			}
+			when.BodyXML = element
+		} else {
+			bytes, bodyRegexp, jMatcher := p.parseWhenBody(rawBody)
+			when.Body = bytes
+			when.BodyRegexp = bodyRegexp
+			when.BodyJSON = jMatcher
+		}
 	}
-}
-
-func (p *mappingsParser) parseReturns(v myjson.Object) (*Returns, error) {
-	p.jsonPath.Append("")
-
-	returns := new(Returns)
 
-	p.jsonPath.SetLast(pStatusCode)
-	if v.Has(pStatusCode) {
-		statusCode, err := v.GetNumber(pStatusCode)
+	p.jsonPath.SetLast(pBodyPaths)
+	if v.Has(pBodyPaths) {
+		rawBodyPaths, err := v.GetObject(pBodyPaths)
 		if err != nil {
 			return nil, p.newJSONParseError(p.jsonPath)
 		}
-		returns.StatusCode = myhttp.StatusCode(int(statusCode))
-	} else {
-		returns.StatusCode = myhttp.StatusOK
+		bodyPaths, err := p.parseBodyPaths(rawBodyPaths)
+		if err != nil {
+			return nil, err
+		}
+		when.BodyPaths = bodyPaths
 	}
 
-	p.jsonPath.SetLast(pHeaders)
-	if v.Has(pHeaders) {
-		rawHeaders, err := v.GetObject(pHeaders)
+	p.jsonPath.SetLast(pBodyXPath)
+	if v.Has(pBodyXPath) {
+		rawBodyXPath, err := v.GetObject(pBodyXPath)
 		if err != nil {
 			return nil, p.newJSONParseError(p.jsonPath)
 		}
-		returns.Headers = parseAsNameValuesPairs(rawHeaders)
-	}
-
-	p.jsonPath.SetLast(pBody)
-	rawBody := v.Get(pBody)
-	body, err := p.parseReturnsBody(rawBody)
-	if err != nil {
-		return nil, p.newJSONParseError(p.jsonPath)
+		bodyXPath, err := p.parseBodyXPath(rawBodyXPath)
+		if err != nil {
+			return nil, err
+		}
+		when.BodyXPath = bodyXPath
 	}
-	returns.Body = body
 
-	p.jsonPath.SetLast(pLatency)
-	if v.Has(pLatency) {
-		rawLatency := v.Get(pLatency)
-		latency, err := p.parseLatency(rawLatency)
+	p.jsonPath.SetLast(pFiles)
+	if v.Has(pFiles) {
+		rawFiles, err := v.GetObject(pFiles)
 		if err != nil {
 			return nil, p.newJSONParseError(p.jsonPath)
 		}
-		returns.Latency = latency
+		files, err := p.parseFiles(rawFiles)
+		if err != nil {
+			return nil, err
+		}
+		when.Files = files
 	}
 
-	p.jsonPath.RemoveLast()
+	p.jsonPath.SetLast(pAuth)
+	if v.Has(pAuth) {
+		auth, err := p.parseAuth(v.Get(pAuth))
+		if err != nil {
+			return nil, err
+		}
+		when.Auth = auth
+	}
+
+	if p.graphQL {
+		graphQL, err := p.parseGraphQLWhen(v)
+		if err != nil {
+			return nil, err
+		}
+		when.GraphQL = graphQL
+	}
+
+	p.jsonPath.SetLast(pAnyOf)
+	if v.Has(pAnyOf) {
+		anyOf, err := p.parseWhens(v.Get(pAnyOf))
+		if err != nil {
+			return nil, err
+		}
+		when.AnyOf = anyOf
+	}
+
+	p.jsonPath.SetLast(pAllOf)
+	if v.Has(pAllOf) {
+		allOf, err := p.parseWhens(v.Get(pAllOf))
+		if err != nil {
+			return nil, err
+		}
+		when.AllOf = allOf
+	}
+
+	p.jsonPath.SetLast(pNot)
+	if v.Has(pNot) {
+		rawNot, err := v.GetObject(pNot)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		not, err := p.parseWhen(rawNot)
+		if err != nil {
+			return nil, err
+		}
+		when.Not = not
+	}
+
+	p.jsonPath.RemoveLast()
+	return when, nil
+}
+
+// parses a non-empty array of nested whens for combinators
+func (p *mappingsParser) parseWhens(v interface{}) ([]*When, error) {
+	rawWhens, ok := v.(myjson.Array)
+	if !ok || len(rawWhens) == 0 {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	p.jsonPath.Append(0)
+	whens := make([]*When, len(rawWhens))
+	for idx, rawWhen := range rawWhens {
+		p.jsonPath.SetLast(idx)
+		rWhen, ok := rawWhen.(myjson.Object)
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		when, err := p.parseWhen(rWhen)
+		if err != nil {
+			return nil, err
+		}
+		whens[idx] = when
+	}
+	p.jsonPath.RemoveLast()
+
+	return whens, nil
+}
+
+// parses JSONPaths and expected values, which are matched as json, in the order of paths
+func (p *mappingsParser) parseBodyPaths(v myjson.Object) ([]*BodyPathMatcher, error) {
+	rawPaths := make([]string, 0, len(v))
+	for rawPath := range v {
+		rawPaths = append(rawPaths, rawPath)
+	}
+	sort.Strings(rawPaths)
+
+	p.jsonPath.Append("")
+	bodyPaths := make([]*BodyPathMatcher, len(rawPaths))
+	for idx, rawPath := range rawPaths {
+		p.jsonPath.SetLast(rawPath)
+		path, err := myjson.ParsePath(rawPath)
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+
+		var matcher myjson.ExtJSONMatcher
+		switch expected := v.Get(rawPath); expected.(type) {
+		case myjson.ExtJSONMatcher:
+			matcher = expected.(myjson.ExtJSONMatcher)
+		default:
+			matcher = myjson.MakeExtJSONMatcher(expected)
+		}
+		if err := matcher.Check(); err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+		bodyPaths[idx] = &BodyPathMatcher{Path: path, Matcher: matcher}
+	}
+	p.jsonPath.RemoveLast()
+
+	return bodyPaths, nil
+}
+
+// parses XPath expressions and expected values, in the order of expressions
+func (p *mappingsParser) parseBodyXPath(v myjson.Object) ([]*XPathMatcher, error) {
+	rawExprs := make([]string, 0, len(v))
+	for rawExpr := range v {
+		rawExprs = append(rawExprs, rawExpr)
+	}
+	sort.Strings(rawExprs)
+
+	p.jsonPath.Append("")
+	bodyXPath := make([]*XPathMatcher, len(rawExprs))
+	for idx, rawExpr := range rawExprs {
+		p.jsonPath.SetLast(rawExpr)
+		expr, err := xpath.Compile(rawExpr)
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+
+		matcher := &XPathMatcher{Expr: expr}
+		switch expected := v.Get(rawExpr); expected.(type) {
+		case myjson.ExtRegexp:
+			matcher.Regexp = expected.(myjson.ExtRegexp)
+		case myjson.String:
+			matcher.Value = string(expected.(myjson.String))
+		case myjson.Number, myjson.Boolean:
+			matcher.Value = types.ToString(expected)
+		default:
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		bodyXPath[idx] = matcher
+	}
+	p.jsonPath.RemoveLast()
+
+	return bodyXPath, nil
+}
+
+// parses matchers of uploaded files, in the order of field names
+func (p *mappingsParser) parseFiles(v myjson.Object) ([]*FileMatcher, error) {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p.jsonPath.Append("")
+	files := make([]*FileMatcher, len(names))
+	for idx, name := range names {
+		p.jsonPath.SetLast(name)
+		rawFile, err := v.GetObject(name)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		file, err := p.parseFile(name, rawFile)
+		if err != nil {
+			return nil, err
+		}
+		files[idx] = file
+	}
+	p.jsonPath.RemoveLast()
+
+	return files, nil
+}
+
+func (p *mappingsParser) parseFile(name string, v myjson.Object) (*FileMatcher, error) {
+	p.jsonPath.Append("")
+
+	file := &FileMatcher{Name: name}
+	var err error
+
+	p.jsonPath.SetLast(pFilename)
+	if v.Has(pFilename) {
+		file.Filename, file.FilenameRegexp, err = p.parseStringOrRegexp(v.Get(pFilename))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.jsonPath.SetLast(pContentType)
+	if v.Has(pContentType) {
+		file.ContentType, file.ContentTypeRegexp, err = p.parseStringOrRegexp(v.Get(pContentType))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.jsonPath.SetLast(pSize)
+	if v.Has(pSize) {
+		file.Size, err = p.parseLatency(v.Get(pSize))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.jsonPath.SetLast(pContent)
+	if v.Has(pContent) {
+		file.Content, file.ContentRegexp, err = p.parseStringOrRegexp(v.Get(pContent))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.jsonPath.RemoveLast()
+	return file, nil
+}
+
+func (p *mappingsParser) parseStringOrRegexp(v interface{}) (string, *regexp.Regexp, error) {
+	switch v.(type) {
+	case myjson.String:
+		return string(v.(myjson.String)), nil, nil
+	case myjson.ExtRegexp:
+		return "", v.(myjson.ExtRegexp), nil
+	default:
+		return "", nil, p.newJSONParseError(p.jsonPath)
+	}
+}
+
+// checks if the body is in the form of {"@xml": "<...>"}
+func (p *mappingsParser) asXMLBody(v interface{}) (myjson.Object, bool) {
+	if o, ok := v.(myjson.Object); ok && len(o) == 1 && o.Has(dXML) {
+		return o, true
+	}
+	return nil, false
+}
+
+func (p *mappingsParser) parseWhenBodyXML(v myjson.Object) (*myxml.Element, error) {
+	p.jsonPath.Append(dXML)
+
+	rawXML, err := v.GetString(dXML)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	element, err := myxml.Parse([]byte(rawXML))
+	if err != nil {
+		return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+	}
+
+	p.jsonPath.RemoveLast()
+	return element, nil
+}
+
+func (p *mappingsParser) parseWhenBody(v interface{}) ([]byte, myjson.ExtRegexp, *myjson.ExtJSONMatcher) {
+	switch v.(type) {
+	case myjson.String:
+		return []byte(v.(myjson.String)), nil, nil
+	case myjson.ExtRegexp:
+		return nil, v.(myjson.ExtRegexp), nil
+	case myjson.ExtJSONMatcher:
+		_v := v.(myjson.ExtJSONMatcher)
+		return nil, nil, &_v
+	default:
+		return []byte(types.ToString(v)), nil, nil
+	}
+}
+
+func (p *mappingsParser) parseReturns(v myjson.Object) (*Returns, error) {
+	p.jsonPath.Append("")
+
+	returns := new(Returns)
+
+	p.jsonPath.SetLast(pStatusCode)
+	if v.Has(pStatusCode) {
+		statusCode, err := v.GetNumber(pStatusCode)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		returns.StatusCode = myhttp.StatusCode(int(statusCode))
+	} else {
+		returns.StatusCode = myhttp.StatusOK
+	}
+
+	p.jsonPath.SetLast(pHeaders)
+	if v.Has(pHeaders) {
+		rawHeaders, err := v.GetObject(pHeaders)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		returns.Headers = parseAsNameValuesPairs(rawHeaders)
+	}
+
+	p.jsonPath.SetLast(pCookies)
+	if v.Has(pCookies) {
+		cookies, err := p.parseCookies(v.Get(pCookies))
+		if err != nil {
+			return nil, err
+		}
+		returns.Cookies = cookies
+	}
+
+	p.jsonPath.SetLast(pBody)
+	rawBody := v.Get(pBody)
+	body, err := p.parseReturnsBody(rawBody)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	returns.Body = body
+
+	if p.graphQL && (v.Has(pData) || v.Has(pErrors)) {
+		if err := p.parseGraphQLReturns(returns, v); err != nil {
+			return nil, err
+		}
+	}
+
+	p.jsonPath.SetLast(pLatency)
+	if v.Has(pLatency) {
+		rawLatency := v.Get(pLatency)
+		latency, err := p.parseLatency(rawLatency)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		returns.Latency = latency
+	}
+
+	pacing, err := p.parsePacing(v)
+	if err != nil {
+		return nil, err
+	}
+	returns.Pacing = pacing
+
+	p.jsonPath.SetLast(pPush)
+	if v.Has(pPush) {
+		rawPush := ensureJSONArray(v.Get(pPush))
+		if !myjson.IsAllString(rawPush) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		for _, rp := range rawPush {
+			path := string(rp.(myjson.String))
+			if !strings.HasPrefix(path, "/") {
+				return nil, &parserError{
+					filename: p.filename,
+					jsonPath: p.jsonPath,
+					err:      errors.New("paths of pushed resources must start with '/'"),
+				}
+			}
+			returns.Push = append(returns.Push, path)
+		}
+	}
+
+	p.jsonPath.RemoveLast()
 	return returns, nil
 }
 
-func (p *mappingsParser) parseJSONToBytes(v interface{}) ([]byte, error) {
-	bytes, err := myjson.Marshal(v)
+// parses cookies set by the response, which could be an object or an array of objects
+func (p *mappingsParser) parseCookies(v interface{}) ([]*http.Cookie, error) {
+	rawCookies := ensureJSONArray(v)
+
+	p.jsonPath.Append(0)
+	cookies := make([]*http.Cookie, len(rawCookies))
+	for idx, rawCookie := range rawCookies {
+		p.jsonPath.SetLast(idx)
+		rc, ok := rawCookie.(myjson.Object)
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie, err := p.parseCookie(rc)
+		if err != nil {
+			return nil, err
+		}
+		cookies[idx] = cookie
+	}
+	p.jsonPath.RemoveLast()
+
+	return cookies, nil
+}
+
+func (p *mappingsParser) parseCookie(v myjson.Object) (*http.Cookie, error) {
+	p.jsonPath.Append("")
+
+	cookie := new(http.Cookie)
+
+	p.jsonPath.SetLast(pName)
+	name, err := v.GetString(pName)
+	if err != nil || name == "" {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	cookie.Name = string(name)
+
+	p.jsonPath.SetLast(pValue)
+	if v.Has(pValue) {
+		value, err := v.GetString(pValue)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie.Value = string(value)
+	}
+
+	p.jsonPath.SetLast(pPath)
+	if v.Has(pPath) {
+		value, err := v.GetString(pPath)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie.Path = string(value)
+	}
+
+	p.jsonPath.SetLast(pDomain)
+	if v.Has(pDomain) {
+		value, err := v.GetString(pDomain)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie.Domain = string(value)
+	}
+
+	p.jsonPath.SetLast(pExpires)
+	if v.Has(pExpires) {
+		rawExpires, err := v.GetString(pExpires)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		expires, err := time.Parse(time.RFC3339, string(rawExpires))
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+		cookie.Expires = expires
+	}
+
+	p.jsonPath.SetLast(pMaxAge)
+	if v.Has(pMaxAge) {
+		maxAge, err := v.GetNumber(pMaxAge)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		if maxAge == 0 { // 'Max-Age=0' deletes the cookie
+			cookie.MaxAge = -1
+		} else {
+			cookie.MaxAge = int(maxAge)
+		}
+	}
+
+	p.jsonPath.SetLast(pHTTPOnly)
+	if v.Has(pHTTPOnly) {
+		value, err := v.GetBoolean(pHTTPOnly)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie.HttpOnly = bool(value)
+	}
+
+	p.jsonPath.SetLast(pSecure)
+	if v.Has(pSecure) {
+		value, err := v.GetBoolean(pSecure)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		cookie.Secure = bool(value)
+	}
+
+	p.jsonPath.SetLast(pSameSite)
+	if v.Has(pSameSite) {
+		sameSite, err := v.GetString(pSameSite)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		switch strings.ToLower(string(sameSite)) {
+		case "lax":
+			cookie.SameSite = http.SameSiteLaxMode
+		case "strict":
+			cookie.SameSite = http.SameSiteStrictMode
+		case "none":
+			cookie.SameSite = http.SameSiteNoneMode
+		default:
+			return nil, &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      fmt.Errorf("unknown sameSite '%s', must be one of [Lax Strict None]", string(sameSite)),
+			}
+		}
+	}
+
+	if cookie.String() == "" { // the name is invalid
+		p.jsonPath.SetLast(pName)
+		return nil, &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("invalid cookie name '%s'", cookie.Name),
+		}
+	}
+
+	p.jsonPath.RemoveLast()
+	return cookie, nil
+}
+
+// parses 'throttle' and 'chunks' of the given object, returns nil if neither is present
+func (p *mappingsParser) parsePacing(v myjson.Object) (*Pacing, error) {
+	if !v.Has(pThrottle) && !v.Has(pChunks) {
+		return nil, nil
+	}
+
+	pacing := new(Pacing)
+
+	p.jsonPath.SetLast(pThrottle)
+	throttle, err := p.getPositiveInt(v, pThrottle, 0)
+	if err != nil {
+		return nil, err
+	}
+	pacing.Throttle = throttle
+
+	p.jsonPath.SetLast(pChunks)
+	switch rawChunks := v.Get(pChunks); rawChunks.(type) {
+	case nil:
+	case myjson.Number: // the number of chunks, without delays
+		chunks, err := p.getPositiveInt(v, pChunks, 0)
+		if err != nil {
+			return nil, err
+		}
+		pacing.Chunks = chunks
+	case myjson.Object:
+		chunksV := rawChunks.(myjson.Object)
+		p.jsonPath.Append(pCount)
+		count, err := chunksV.GetNumber(pCount)
+		if err != nil || count < 1 || count != myjson.Number(int(count)) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		pacing.Chunks = int(count)
+
+		if chunksV.Has(pDelay) {
+			p.jsonPath.SetLast(pDelay)
+			delay, err := p.parseLatency(chunksV.Get(pDelay))
+			if err != nil {
+				return nil, err
+			}
+			pacing.Delay = delay
+		}
+		p.jsonPath.RemoveLast()
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	return pacing, nil
+}
+
+func (p *mappingsParser) parseJSONToBytes(v interface{}) ([]byte, error) {
+	bytes, err := myjson.Marshal(v)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	return bytes, nil
+}
+
+func (p *mappingsParser) parseReturnsBody(v interface{}) ([]byte, error) {
+	v, err := types.DoFiltersOnV(v, ppLoadFile)
+	if err != nil {
+		return nil, &loadError{filename: p.filename, err: err}
+	}
+
+	switch v.(type) {
+	case nil:
+		return nil, nil
+	case myjson.String:
+		return []byte(string(v.(myjson.String))), nil
+	case myjson.Object:
+		return p.parseJSONToBytes(v)
+	case myjson.Array:
+		return p.parseJSONToBytes(v)
+	}
+
+	return nil, p.newJSONParseError(p.jsonPath)
+}
+
+func (p *mappingsParser) parseForwards(v myjson.Object) (*Forwards, error) {
+	p.jsonPath.Append("")
+
+	forwards := new(Forwards)
+
+	p.jsonPath.SetLast(pPath)
+	path, err := v.GetString(pPath)
+	if err != nil || !pathRegexp.MatchString(string(path)) { // checks path
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	forwards.Path = string(path)
+
+	p.jsonPath.SetLast(pLatency)
+	if v.Has(pLatency) {
+		rawLatency := v.Get(pLatency)
+		latency, err := p.parseLatency(rawLatency)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		forwards.Latency = latency
+	}
+
+	pacing, err := p.parsePacing(v)
+	if err != nil {
+		return nil, err
+	}
+	forwards.Pacing = pacing
+
+	p.jsonPath.RemoveLast()
+	return forwards, nil
+}
+
+// parses a sequence, which is either an array of returns or an object with
+// the array as 'returns' and a boolean 'cycle'
+func (p *mappingsParser) parseSequence(v interface{}) (*Sequence, error) {
+	sequence := new(Sequence)
+
+	var rawReturns myjson.Array
+	switch v.(type) {
+	case myjson.Array:
+		rawReturns = v.(myjson.Array)
+	case myjson.Object:
+		vo := v.(myjson.Object)
+		p.jsonPath.Append("")
+
+		p.jsonPath.SetLast(pCycle)
+		if vo.Has(pCycle) {
+			cycle, err := vo.GetBoolean(pCycle)
+			if err != nil {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			sequence.Cycle = bool(cycle)
+		}
+
+		p.jsonPath.SetLast(mapPolicyReturns)
+		_rawReturns, err := vo.GetArray(mapPolicyReturns)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		rawReturns = _rawReturns
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	if len(rawReturns) == 0 {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	p.jsonPath.Append(0)
+	for idx, rr := range rawReturns {
+		p.jsonPath.SetLast(idx)
+
+		rrObj, ok := rr.(myjson.Object)
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		returns, err := p.parseReturns(rrObj)
+		if err != nil {
+			return nil, err
+		}
+		sequence.Returns = append(sequence.Returns, returns)
+	}
+	p.jsonPath.RemoveLast()
+
+	if _, ok := v.(myjson.Object); ok {
+		p.jsonPath.RemoveLast()
+	}
+	return sequence, nil
+}
+
+// parses an array of returns, each of which may have a positive 'weight', 1 by default
+func (p *mappingsParser) parseOneOf(v myjson.Array) (*OneOf, error) {
+	if len(v) == 0 {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	oneOf := new(OneOf)
+
+	p.jsonPath.Append(0)
+	for idx, rc := range v {
+		p.jsonPath.SetLast(idx)
+
+		rcObj, ok := rc.(myjson.Object)
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+
+		choice := &Choice{Weight: 1}
+		if rcObj.Has(pWeight) {
+			p.jsonPath.Append(pWeight)
+			weight, err := rcObj.GetNumber(pWeight)
+			if err != nil || weight <= 0 {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			choice.Weight = float64(weight)
+			p.jsonPath.RemoveLast()
+		}
+
+		returns, err := p.parseReturns(rcObj)
+		if err != nil {
+			return nil, err
+		}
+		choice.Returns = returns
+		oneOf.Choices = append(oneOf.Choices, choice)
+	}
+	p.jsonPath.RemoveLast()
+
+	return oneOf, nil
+}
+
+func (p *mappingsParser) parseFaults(v myjson.Object) (*Faults, error) {
+	// statusCode, headers, body and latency are parsed as returns
+	returns, err := p.parseReturns(v)
+	if err != nil {
+		return nil, err
+	}
+	faults := &Faults{Returns: returns}
+
+	p.jsonPath.Append("")
+
+	p.jsonPath.SetLast(pType)
+	_type, err := v.GetString(pType)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	for _, ft := range faultTypes {
+		if string(ft) == string(_type) {
+			faults.Type = ft
+		}
+	}
+	if faults.Type == "" {
+		return nil, &parserError{
+			filename: p.filename,
+			jsonPath: p.jsonPath,
+			err:      fmt.Errorf("unknown fault type '%s', must be one of %v", string(_type), faultTypes),
+		}
+	}
+
+	switch faults.Type {
+	case FaultGarbage:
+		p.jsonPath.SetLast(pSize)
+		size, err := p.getPositiveInt(v, pSize, defaultGarbageSize)
+		if err != nil {
+			return nil, err
+		}
+		faults.Size = size
+	case FaultDrip:
+		p.jsonPath.SetLast(pInterval)
+		if v.Has(pInterval) {
+			interval, err := p.parseLatency(v.Get(pInterval))
+			if err != nil {
+				return nil, err
+			}
+			faults.Interval = interval
+		} else {
+			faults.Interval = &Interval{Min: defaultDripInterval, Max: defaultDripInterval}
+		}
+
+		p.jsonPath.SetLast(pBytes)
+		bytes, err := p.getPositiveInt(v, pBytes, defaultDripBytes)
+		if err != nil {
+			return nil, err
+		}
+		faults.Bytes = bytes
+	}
+
+	p.jsonPath.RemoveLast()
+	return faults, nil
+}
+
+// parses events, which are either an array of events or an object with the
+// array as 'events', a 'repeat' of a positive number or true (forever), and
+// the statusCode, headers and latency of the stream
+func (p *mappingsParser) parseEvents(v interface{}) (*Events, error) {
+	events := &Events{Repeat: 1}
+
+	var rawEvents myjson.Array
+	switch v.(type) {
+	case myjson.Array:
+		events.Returns = &Returns{StatusCode: myhttp.StatusOK}
+		rawEvents = v.(myjson.Array)
+	case myjson.Object:
+		vo := v.(myjson.Object)
+		returns, err := p.parseReturns(vo)
+		if err != nil {
+			return nil, err
+		}
+		events.Returns = returns
+
+		p.jsonPath.Append("")
+
+		p.jsonPath.SetLast(pRepeat)
+		switch rawRepeat := vo.Get(pRepeat); rawRepeat.(type) {
+		case nil:
+		case myjson.Boolean:
+			if !bool(rawRepeat.(myjson.Boolean)) {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			events.Repeat = 0
+		default:
+			repeat, err := p.getPositiveInt(vo, pRepeat, 1)
+			if err != nil {
+				return nil, err
+			}
+			events.Repeat = repeat
+		}
+
+		p.jsonPath.SetLast(mapPolicyEvents)
+		_rawEvents, err := vo.GetArray(mapPolicyEvents)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		rawEvents = _rawEvents
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	if len(rawEvents) == 0 {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	p.jsonPath.Append(0)
+	delayed := false
+	for idx, re := range rawEvents {
+		p.jsonPath.SetLast(idx)
+
+		reObj, ok := re.(myjson.Object)
+		if !ok {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		event, err := p.parseEvent(reObj)
+		if err != nil {
+			return nil, err
+		}
+		delayed = delayed || (event.Delay != nil && event.Delay.Max > 0)
+		events.Events = append(events.Events, event)
+	}
+	p.jsonPath.RemoveLast()
+
+	if _, ok := v.(myjson.Object); ok {
+		if events.Repeat == 0 && !delayed { // or the events would be sent as fast as possible, forever
+			p.jsonPath.SetLast(pRepeat)
+			return nil, &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      errors.New("events repeated forever must have at least one positive delay"),
+			}
+		}
+		p.jsonPath.RemoveLast()
+	}
+	return events, nil
+}
+
+func (p *mappingsParser) parseEvent(v myjson.Object) (*Event, error) {
+	p.jsonPath.Append("")
+
+	event := new(Event)
+
+	p.jsonPath.SetLast(pID)
+	switch rawID := v.Get(pID); rawID.(type) {
+	case nil:
+	case myjson.String:
+		event.ID = string(rawID.(myjson.String))
+	case myjson.Number:
+		event.ID = rawID.(myjson.Number).String()
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	p.jsonPath.SetLast(pEvent)
+	if v.Has(pEvent) {
+		_event, err := v.GetString(pEvent)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		event.Event = string(_event)
+	}
+
+	p.jsonPath.SetLast(pData)
+	data, err := p.parseReturnsBody(v.Get(pData))
 	if err != nil {
 		return nil, p.newJSONParseError(p.jsonPath)
 	}
-	return bytes, nil
-}
+	event.Data = string(data)
 
-func (p *mappingsParser) parseReturnsBody(v interface{}) ([]byte, error) {
-	v, err := types.DoFiltersOnV(v, ppLoadFile)
+	p.jsonPath.SetLast(pRetry)
+	retry, err := p.getPositiveInt(v, pRetry, 0)
 	if err != nil {
-		return nil, &loadError{filename: p.filename, err: err}
+		return nil, err
+	}
+	event.Retry = retry
+
+	p.jsonPath.SetLast(pDelay)
+	if v.Has(pDelay) {
+		delay, err := p.parseLatency(v.Get(pDelay))
+		if err != nil {
+			return nil, err
+		}
+		event.Delay = delay
+	}
+
+	p.jsonPath.RemoveLast()
+	return event, nil
+}
+
+func (p *mappingsParser) parseWebSocket(v myjson.Object) (*WebSocket, error) {
+	p.jsonPath.Append("")
+
+	webSocket := new(WebSocket)
+
+	p.jsonPath.SetLast(pHeaders)
+	if v.Has(pHeaders) {
+		rawHeaders, err := v.GetObject(pHeaders)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		webSocket.Headers = parseAsNameValuesPairs(rawHeaders)
+	}
+
+	p.jsonPath.SetLast(pSubprotocols)
+	if v.Has(pSubprotocols) {
+		rawSubprotocols, err := v.GetArray(pSubprotocols)
+		if err != nil || !myjson.IsAllString(rawSubprotocols) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		for _, s := range rawSubprotocols {
+			webSocket.Subprotocols = append(webSocket.Subprotocols, string(s.(myjson.String)))
+		}
+	}
+
+	p.jsonPath.SetLast(pOnConnect)
+	if v.Has(pOnConnect) {
+		onConnect, err := p.parseWSMessages(v.Get(pOnConnect))
+		if err != nil {
+			return nil, err
+		}
+		webSocket.OnConnect = onConnect
+	}
+
+	p.jsonPath.SetLast(pReplies)
+	if v.Has(pReplies) {
+		rawReplies, err := v.GetArray(pReplies)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+
+		p.jsonPath.Append(0)
+		for idx, rr := range rawReplies {
+			p.jsonPath.SetLast(idx)
+			rrObj, ok := rr.(myjson.Object)
+			if !ok {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			reply, err := p.parseWSReply(rrObj)
+			if err != nil {
+				return nil, err
+			}
+			webSocket.Replies = append(webSocket.Replies, reply)
+		}
+		p.jsonPath.RemoveLast()
+	}
+
+	p.jsonPath.SetLast(pPushes)
+	if v.Has(pPushes) {
+		rawPushes, err := v.GetArray(pPushes)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+
+		p.jsonPath.Append(0)
+		for idx, rp := range rawPushes {
+			p.jsonPath.SetLast(idx)
+			rpObj, ok := rp.(myjson.Object)
+			if !ok {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			push, err := p.parseWSPush(rpObj)
+			if err != nil {
+				return nil, err
+			}
+			webSocket.Pushes = append(webSocket.Pushes, push)
+		}
+		p.jsonPath.RemoveLast()
+	}
+
+	p.jsonPath.SetLast(pClose)
+	if v.Has(pClose) {
+		rawClose, err := v.GetObject(pClose)
+		if err != nil || !rawClose.Has(pAfter) {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		_close, err := p.parseWSClose(rawClose)
+		if err != nil {
+			return nil, err
+		}
+		webSocket.Close = _close
+	}
+
+	p.jsonPath.RemoveLast()
+	return webSocket, nil
+}
+
+// parses a message or an array of messages
+func (p *mappingsParser) parseWSMessages(v interface{}) ([]*WSMessage, error) {
+	rawMessages, ok := v.(myjson.Array)
+	if !ok {
+		message, err := p.parseWSMessage(v)
+		if err != nil {
+			return nil, err
+		}
+		return []*WSMessage{message}, nil
+	}
+
+	var messages []*WSMessage
+	p.jsonPath.Append(0)
+	for idx, rm := range rawMessages {
+		p.jsonPath.SetLast(idx)
+		message, err := p.parseWSMessage(rm)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
 	}
+	p.jsonPath.RemoveLast()
+	return messages, nil
+}
 
+// parses a message, which is either a string sent as a text message or an object
+// with 'data', 'binary' and 'delay'
+func (p *mappingsParser) parseWSMessage(v interface{}) (*WSMessage, error) {
 	switch v.(type) {
-	case nil:
-		return nil, nil
 	case myjson.String:
-		return []byte(string(v.(myjson.String))), nil
+		return &WSMessage{Data: []byte(string(v.(myjson.String)))}, nil
 	case myjson.Object:
-		return p.parseJSONToBytes(v)
-	case myjson.Array:
-		return p.parseJSONToBytes(v)
+		vo := v.(myjson.Object)
+		p.jsonPath.Append("")
+
+		message := new(WSMessage)
+
+		p.jsonPath.SetLast(pData)
+		data, err := p.parseReturnsBody(vo.Get(pData))
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		message.Data = data
+
+		p.jsonPath.SetLast(pBinary)
+		if vo.Has(pBinary) {
+			binary, err := vo.GetBoolean(pBinary)
+			if err != nil {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			message.Binary = bool(binary)
+		}
+
+		p.jsonPath.SetLast(pDelay)
+		if vo.Has(pDelay) {
+			delay, err := p.parseLatency(vo.Get(pDelay))
+			if err != nil {
+				return nil, err
+			}
+			message.Delay = delay
+		}
+
+		p.jsonPath.RemoveLast()
+		return message, nil
 	}
 
 	return nil, p.newJSONParseError(p.jsonPath)
 }
 
-func (p *mappingsParser) parseForwards(v myjson.Object) (*Forwards, error) {
+// parses a reply, whose 'when' is matched against incoming messages like the body
+// of a request
+func (p *mappingsParser) parseWSReply(v myjson.Object) (*WSReply, error) {
 	p.jsonPath.Append("")
 
-	forwards := new(Forwards)
+	reply := new(WSReply)
 
-	p.jsonPath.SetLast(pPath)
-	path, err := v.GetString(pPath)
-	if err != nil || !pathRegexp.MatchString(string(path)) { // checks path
+	p.jsonPath.SetLast(mapPolicyWhen)
+	if v.Has(mapPolicyWhen) {
+		rawWhen, err := types.DoFiltersOnV(v.Get(mapPolicyWhen), ppToJSONMatcher, ppParseRegexp, ppLoadFile)
+		if err != nil {
+			return nil, &loadError{filename: p.filename, err: err}
+		}
+		reply.Body, reply.BodyRegexp, reply.BodyJSON = p.parseWhenBody(rawWhen)
+	}
+
+	p.jsonPath.SetLast(pReply)
+	if v.Has(pReply) {
+		messages, err := p.parseWSMessages(v.Get(pReply))
+		if err != nil {
+			return nil, err
+		}
+		reply.Messages = messages
+	}
+
+	p.jsonPath.SetLast(pClose)
+	switch rawClose := v.Get(pClose); rawClose.(type) {
+	case nil:
+	case myjson.Boolean:
+		if bool(rawClose.(myjson.Boolean)) {
+			reply.Close = &WSClose{Code: defaultWSCloseCode}
+		}
+	case myjson.Object:
+		_close, err := p.parseWSClose(rawClose.(myjson.Object))
+		if err != nil {
+			return nil, err
+		}
+		reply.Close = _close
+	default:
 		return nil, p.newJSONParseError(p.jsonPath)
 	}
-	forwards.Path = string(path)
 
-	p.jsonPath.SetLast(pLatency)
-	if v.Has(pLatency) {
-		rawLatency := v.Get(pLatency)
-		latency, err := p.parseLatency(rawLatency)
+	p.jsonPath.RemoveLast()
+	return reply, nil
+}
+
+func (p *mappingsParser) parseWSPush(v myjson.Object) (*WSPush, error) {
+	p.jsonPath.Append("")
+
+	push := new(WSPush)
+
+	p.jsonPath.SetLast(pInterval)
+	interval, err := p.parseLatency(v.Get(pInterval))
+	if err != nil {
+		return nil, err
+	}
+	if interval.Max <= 0 { // or the message would be sent as fast as possible
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	push.Interval = interval
+
+	p.jsonPath.SetLast(pMessage)
+	message, err := p.parseWSMessage(v.Get(pMessage))
+	if err != nil {
+		return nil, err
+	}
+	push.Message = message
+
+	p.jsonPath.SetLast(mapPolicyTimes)
+	times, err := p.getPositiveInt(v, mapPolicyTimes, 0)
+	if err != nil {
+		return nil, err
+	}
+	push.Times = times
+
+	p.jsonPath.RemoveLast()
+	return push, nil
+}
+
+func (p *mappingsParser) parseWSClose(v myjson.Object) (*WSClose, error) {
+	p.jsonPath.Append("")
+
+	_close := &WSClose{Code: defaultWSCloseCode}
+
+	p.jsonPath.SetLast(pAfter)
+	if v.Has(pAfter) {
+		after, err := p.parseLatency(v.Get(pAfter))
+		if err != nil {
+			return nil, err
+		}
+		_close.After = after
+	}
+
+	p.jsonPath.SetLast(pCode)
+	code, err := p.getPositiveInt(v, pCode, defaultWSCloseCode)
+	if err != nil {
+		return nil, err
+	}
+	_close.Code = code
+
+	p.jsonPath.SetLast(pReason)
+	if v.Has(pReason) {
+		reason, err := v.GetString(pReason)
 		if err != nil {
 			return nil, p.newJSONParseError(p.jsonPath)
 		}
-		forwards.Latency = latency
+		_close.Reason = string(reason)
 	}
 
 	p.jsonPath.RemoveLast()
-	return forwards, nil
+	return _close, nil
+}
+
+// gets the positive integer of the given name, returns the default value if absent
+func (p *mappingsParser) getPositiveInt(v myjson.Object, name string, defaultValue int) (int, error) {
+	if !v.Has(name) {
+		return defaultValue, nil
+	}
+
+	n, err := v.GetNumber(name)
+	if err != nil || n < 1 || n != myjson.Number(int(n)) {
+		return 0, p.newJSONParseError(p.jsonPath)
+	}
+	return int(n), nil
 }
 
 func (p *mappingsParser) parseRedirects(v myjson.Object) (*Returns, error) {
@@ -594,25 +2252,39 @@ func (p *mappingsParser) renamePathVars(mapping *Mapping) {
 	mapping.URI = newURI
 
 	for _, pol := range mapping.Policies {
-		when := pol.When
-		if when != nil {
-			l := len(when.PathVars)
-			if l != 0 {
-				newPVars := p.numberForPathVars(l, when, var2Idx)
-				p.sortPathVars(newPVars)
-				when.PathVars = newPVars
-			}
-
-			l = len(when.PathVarRegexps)
-			if l != 0 {
-				newPVarRegexps := p.numberForPathVarRegexps(when, var2Idx)
-				p.sortPathVarRegexps(newPVarRegexps)
-				when.PathVarRegexps = newPVarRegexps
-			}
+		if pol.When != nil {
+			p.renameWhenPathVars(pol.When, var2Idx)
 		}
 	}
 }
 
+// renames pathVars of the when and its nested whens
+func (p *mappingsParser) renameWhenPathVars(when *When, var2Idx map[string]int) {
+	l := len(when.PathVars)
+	if l != 0 {
+		newPVars := p.numberForPathVars(l, when, var2Idx)
+		p.sortPathVars(newPVars)
+		when.PathVars = newPVars
+	}
+
+	l = len(when.PathVarRegexps)
+	if l != 0 {
+		newPVarRegexps := p.numberForPathVarRegexps(when, var2Idx)
+		p.sortPathVarRegexps(newPVarRegexps)
+		when.PathVarRegexps = newPVarRegexps
+	}
+
+	for _, w := range when.AnyOf {
+		p.renameWhenPathVars(w, var2Idx)
+	}
+	for _, w := range when.AllOf {
+		p.renameWhenPathVars(w, var2Idx)
+	}
+	if when.Not != nil {
+		p.renameWhenPathVars(when.Not, var2Idx)
+	}
+}
+
 func (p *mappingsParser) numberForPathVars(l int, when *When, var2Idx map[string]int) []*NameValuesPair {
 	newPVars := make([]*NameValuesPair, l)
 	for i, v := range when.PathVars {
@@ -675,25 +2347,100 @@ func (p *mappingsParser) sortPathVarRegexps(newPVars []*NameRegexpPair) {
 	})
 }
 
+// types of pathVars are kept, e.g. '/{id:int}' is numbered as '/{0:int}'
 func numberPathVars(uri string) (n string, m map[string]int) {
 	m = make(map[string]int)
-	n = pathVarRegexp.ReplaceAllStringFunc(uri, (func() func(string) string {
-		idx := 0
-		return func(s string) string {
-			var i int
-			var ok bool
-			name := s[1 : len(s)-1]
-			if i, ok = m[name]; !ok {
-				i = idx
-				idx++
-				m[name] = i
-			}
-			return fmt.Sprintf("{%d}", i)
+
+	var builder strings.Builder
+	idx, last := 0, 0
+	for _, pVar := range findPathVars(uri) {
+		i, ok := m[pVar.name]
+		if !ok {
+			i = idx
+			idx++
+			m[pVar.name] = i
 		}
-	})())
+
+		builder.WriteString(uri[last:pVar.start])
+		if pVar.typ == "" {
+			builder.WriteString(fmt.Sprintf("{%d}", i))
+		} else {
+			builder.WriteString(fmt.Sprintf("{%d:%s}", i, pVar.typ))
+		}
+		last = pVar.end
+	}
+	builder.WriteString(uri[last:])
+
+	n = builder.String()
 	return
 }
 
+// canonicalizes header names like 'content-type' to 'Content-Type', in which
+// the request headers are stored, names equal after canonicalization collide
+func (p *mappingsParser) canonicalizeHeaderNames(v myjson.Object) (myjson.Object, error) {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(myjson.Object, len(v))
+	for _, name := range names {
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if result.Has(canonical) {
+			p.jsonPath.Append(name)
+			err := &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      fmt.Errorf("header '%s' is declared more than once", canonical),
+			}
+			return nil, err
+		}
+		result[canonical] = v[name]
+	}
+	return result, nil
+}
+
+// takes values with modes out of the object, e.g. {"@prefix": "Bearer "}
+func (p *mappingsParser) parseNameModePairs(v myjson.Object) ([]*NameModePair, error) {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []*NameModePair
+	p.jsonPath.Append("")
+	for _, name := range names {
+		o, ok := v[name].(myjson.Object)
+		if !ok || len(o) != 1 {
+			continue
+		}
+
+		p.jsonPath.SetLast(name)
+		for _, mode := range []ValueMode{ValueContains, ValuePrefix, ValueTokens} {
+			if !o.Has(string(mode)) {
+				continue
+			}
+
+			values := ensureJSONArray(o.Get(string(mode))) // only @tokens accepts multiple values
+			if len(values) == 0 || !myjson.IsAllString(values) || (mode != ValueTokens && len(values) != 1) {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+
+			pair := &NameModePair{Name: name, Mode: mode, Values: make([]string, len(values))}
+			for idx, value := range values {
+				pair.Values[idx] = string(value.(myjson.String))
+			}
+			pairs = append(pairs, pair)
+			delete(v, name)
+		}
+	}
+	p.jsonPath.RemoveLast()
+
+	return pairs, nil
+}
+
 // divides matchers into normal, regexp, json
 func divideIntoWhenMatchers(v myjson.Object) (myjson.Object,
 	map[string]myjson.ExtRegexp, map[string]myjson.ExtJSONMatcher) {
@@ -1,6 +1,7 @@
 package mckmaps
 
 import (
+	"crypto/ecdsa"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myxml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -78,7 +80,7 @@ func TestMappingsParser_parse(t *testing.T) {
 							When: &When{
 								Headers: []*NameValuesPair{
 									{
-										Name:   "X-BC",
+										Name:   "X-Bc",
 										Values: []string{"2", "3"},
 									},
 								},
@@ -401,4 +403,829 @@ func TestMappingsParser_parse(t *testing.T) {
 		_, e10 := m10.parse()
 		assert.NotNil(e10)
 	}
+
+	fb11, e11 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-11.json"))
+	require.Nil(e11)
+	j11, e11 := myjson.Unmarshal(fb11)
+	if assert.Nil(e11) {
+		m11 := &mappingsParser{json: j11}
+		p11, e11 := m11.parse()
+		if assert.Nil(e11) {
+			expected11 := []*Mapping{
+				{
+					URI:    "/retry",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							Times:   1,
+							CmdType: mapPolicyReturns,
+							Returns: &Returns{StatusCode: myhttp.StatusCode(503)},
+						},
+						{
+							CmdType: mapPolicySequence,
+							Sequence: &Sequence{
+								Returns: []*Returns{
+									{StatusCode: myhttp.StatusCode(500)},
+									{StatusCode: myhttp.StatusOK, Body: []byte("ok")},
+								},
+							},
+						},
+					},
+				},
+				{
+					URI:    "/cycle",
+					Method: myhttp.MethodAny,
+					Policies: []*Policy{
+						{
+							CmdType: mapPolicySequence,
+							Sequence: &Sequence{
+								Returns: []*Returns{
+									{StatusCode: myhttp.StatusCode(201)},
+									{StatusCode: myhttp.StatusCode(202)},
+								},
+								Cycle: true,
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected11, p11)
+		}
+	}
+
+	fb12, e12 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-12.json"))
+	require.Nil(e12)
+	j12, e12 := myjson.Unmarshal(fb12)
+	if assert.Nil(e12) {
+		m12 := &mappingsParser{json: j12}
+		_, e12 := m12.parse()
+		if assert.NotNil(e12) {
+			assert.Equal("$.mappings[0].policies[0].times", e12.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb13, e13 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-13.json"))
+	require.Nil(e13)
+	j13, e13 := myjson.Unmarshal(fb13)
+	if assert.Nil(e13) {
+		m13 := &mappingsParser{json: j13}
+		_, e13 := m13.parse()
+		assert.NotNil(e13)
+	}
+
+	fb14, e14 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-14.json"))
+	require.Nil(e14)
+	j14, e14 := myjson.Unmarshal(fb14)
+	if assert.Nil(e14) {
+		m14 := &mappingsParser{json: j14}
+		p14, e14 := m14.parse()
+		if assert.Nil(e14) {
+			probability := 0.05
+			expected14 := []*Mapping{
+				{
+					URI:    "/flaky",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							Probability: &probability,
+							CmdType:     mapPolicyReturns,
+							Returns:     &Returns{StatusCode: myhttp.StatusCode(503)},
+						},
+						{
+							CmdType: mapPolicyOneOf,
+							OneOf: &OneOf{
+								Choices: []*Choice{
+									{Weight: 3, Returns: &Returns{StatusCode: myhttp.StatusOK, Body: []byte("a")}},
+									{Weight: 1, Returns: &Returns{StatusCode: myhttp.StatusCode(500)}},
+								},
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected14, p14)
+		}
+	}
+
+	fb15, e15 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-15.json"))
+	require.Nil(e15)
+	j15, e15 := myjson.Unmarshal(fb15)
+	if assert.Nil(e15) {
+		m15 := &mappingsParser{json: j15}
+		_, e15 := m15.parse()
+		if assert.NotNil(e15) {
+			assert.Equal("$.mappings[0].policies[0].oneOf[0].weight", e15.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb16, e16 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-16.json"))
+	require.Nil(e16)
+	j16, e16 := myjson.Unmarshal(fb16)
+	if assert.Nil(e16) {
+		m16 := &mappingsParser{json: j16}
+		p16, e16 := m16.parse()
+		if assert.Nil(e16) {
+			expected16 := []*Mapping{
+				{
+					URI:    "/faults",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							When: &When{
+								Params: []*NameValuesPair{
+									{
+										Name:   "type",
+										Values: []string{"garbage"},
+									},
+								},
+							},
+							CmdType: mapPolicyFaults,
+							Faults: &Faults{
+								Type:    FaultGarbage,
+								Returns: &Returns{StatusCode: myhttp.StatusOK},
+								Size:    1024,
+							},
+						},
+						{
+							CmdType: mapPolicyFaults,
+							Faults: &Faults{
+								Type: FaultDrip,
+								Returns: &Returns{
+									StatusCode: myhttp.StatusCode(201),
+									Body:       []byte("hello"),
+								},
+								Interval: &Interval{Min: 100, Max: 200},
+								Bytes:    2,
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected16, p16)
+		}
+	}
+
+	fb17, e17 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-17.json"))
+	require.Nil(e17)
+	j17, e17 := myjson.Unmarshal(fb17)
+	if assert.Nil(e17) {
+		m17 := &mappingsParser{json: j17}
+		_, e17 := m17.parse()
+		if assert.NotNil(e17) {
+			assert.Equal("$.mappings[0].policies[0].faults.type", e17.(*parserError).jsonPath.String())
+			assert.Contains(e17.Error(), "unknown fault type 'explode'")
+		}
+	}
+
+	fb18, e18 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-18.json"))
+	require.Nil(e18)
+	j18, e18 := myjson.Unmarshal(fb18)
+	if assert.Nil(e18) {
+		m18 := &mappingsParser{json: j18}
+		p18, e18 := m18.parse()
+		if assert.Nil(e18) {
+			expected18 := []*Mapping{
+				{
+					URI:    "/download",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							When: &When{
+								Params: []*NameValuesPair{
+									{
+										Name:   "slow",
+										Values: []string{"true"},
+									},
+								},
+							},
+							CmdType: mapPolicyReturns,
+							Returns: &Returns{
+								StatusCode: myhttp.StatusOK,
+								Body:       []byte("0123456789"),
+								Pacing: &Pacing{
+									Throttle: 1024,
+									Chunks:   5,
+									Delay:    &Interval{Min: 100, Max: 200},
+								},
+							},
+						},
+						{
+							CmdType: mapPolicyForwards,
+							Forwards: &Forwards{
+								Path:   "http://localhost:8080/download",
+								Pacing: &Pacing{Chunks: 3},
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected18, p18)
+		}
+	}
+
+	fb19, e19 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-19.json"))
+	require.Nil(e19)
+	j19, e19 := myjson.Unmarshal(fb19)
+	if assert.Nil(e19) {
+		m19 := &mappingsParser{json: j19}
+		_, e19 := m19.parse()
+		if assert.NotNil(e19) {
+			assert.Equal("$.mappings[0].policies[0].returns.chunks.count", e19.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb20, e20 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-20.json"))
+	require.Nil(e20)
+	j20, e20 := myjson.Unmarshal(fb20)
+	if assert.Nil(e20) {
+		m20 := &mappingsParser{json: j20}
+		p20, e20 := m20.parse()
+		if assert.Nil(e20) {
+			expected20 := []*Mapping{
+				{
+					URI:    "/events",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							When: &When{
+								Params: []*NameValuesPair{
+									{
+										Name:   "once",
+										Values: []string{"true"},
+									},
+								},
+							},
+							CmdType: mapPolicyEvents,
+							Events: &Events{
+								Returns: &Returns{StatusCode: myhttp.StatusOK},
+								Events:  []*Event{{ID: "1", Data: "hello"}},
+								Repeat:  1,
+							},
+						},
+						{
+							CmdType: mapPolicyEvents,
+							Events: &Events{
+								Returns: &Returns{
+									StatusCode: myhttp.StatusOK,
+									Headers: []*NameValuesPair{
+										{
+											Name:   "X-Stream",
+											Values: []string{"ticks"},
+										},
+									},
+								},
+								Events: []*Event{
+									{
+										ID:    "t",
+										Event: "tick",
+										Data:  `{"n":1}`,
+										Retry: 3000,
+										Delay: &Interval{Min: 100, Max: 200},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected20, p20)
+		}
+	}
+
+	fb21, e21 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-21.json"))
+	require.Nil(e21)
+	j21, e21 := myjson.Unmarshal(fb21)
+	if assert.Nil(e21) {
+		m21 := &mappingsParser{json: j21}
+		_, e21 := m21.parse()
+		if assert.NotNil(e21) {
+			assert.Equal("$.mappings[0].policies[0].events.repeat", e21.(*parserError).jsonPath.String())
+			assert.Contains(e21.Error(), "at least one positive delay")
+		}
+	}
+
+	fb22, e22 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-22.json"))
+	require.Nil(e22)
+	j22, e22 := myjson.Unmarshal(fb22)
+	if assert.Nil(e22) {
+		m22 := &mappingsParser{json: j22}
+		p22, e22 := m22.parse()
+		if assert.Nil(e22) {
+			expected22 := []*Mapping{
+				{
+					URI:    "/chat",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							CmdType: mapPolicyWebSocket,
+							WebSocket: &WebSocket{
+								Headers: []*NameValuesPair{
+									{
+										Name:   "X-Room",
+										Values: []string{"lobby"},
+									},
+								},
+								Subprotocols: []string{"chat"},
+								OnConnect:    []*WSMessage{{Data: []byte("welcome")}},
+								Replies: []*WSReply{
+									{
+										BodyRegexp: regexp.MustCompile("^ping$"),
+										Messages: []*WSMessage{
+											{Data: []byte("pong")},
+											{Data: []byte(`{"type":"pong"}`), Delay: &Interval{Min: 100, Max: 100}},
+										},
+									},
+									{
+										BodyJSON: myjson.NewExtJSONMatcher(myjson.Object{"type": myjson.String("leave")}),
+										Messages: []*WSMessage{{Data: []byte("bye"), Binary: true}},
+										Close:    &WSClose{Code: 1000},
+									},
+									{
+										Messages: []*WSMessage{{Data: []byte("unknown")}},
+									},
+								},
+								Pushes: []*WSPush{
+									{
+										Interval: &Interval{Min: 1000, Max: 2000},
+										Message:  &WSMessage{Data: []byte("tick")},
+										Times:    3,
+									},
+								},
+								Close: &WSClose{
+									After:  &Interval{Min: 60000, Max: 60000},
+									Code:   4000,
+									Reason: "timeout",
+								},
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected22, p22)
+		}
+	}
+
+	fb23, e23 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-23.json"))
+	require.Nil(e23)
+	j23, e23 := myjson.Unmarshal(fb23)
+	if assert.Nil(e23) {
+		m23 := &mappingsParser{json: j23}
+		_, e23 := m23.parse()
+		if assert.NotNil(e23) {
+			assert.Equal("$.mappings[0].policies[0].websocket.pushes[0].interval", e23.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb29, e29 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-29.json"))
+	require.Nil(e29)
+	j29, e29 := myjson.Unmarshal(fb29)
+	if assert.Nil(e29) {
+		m29 := &mappingsParser{json: j29}
+		p29, e29 := m29.parse()
+		if assert.Nil(e29) {
+			expected29 := []*Mapping{
+				{
+					URI:    "/index.html",
+					Method: myhttp.MethodGet,
+					Policies: []*Policy{
+						{
+							CmdType: mapPolicyReturns,
+							Returns: &Returns{
+								StatusCode: myhttp.StatusOK,
+								Body:       []byte("<html></html>"),
+								Push:       []string{"/app.js", "/app.css"},
+							},
+						},
+					},
+				},
+			}
+			assert.Equal(expected29, p29)
+		}
+	}
+
+	fb30, e30 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-30.json"))
+	require.Nil(e30)
+	j30, e30 := myjson.Unmarshal(fb30)
+	if assert.Nil(e30) {
+		m30 := &mappingsParser{json: j30}
+		_, e30 := m30.parse()
+		if assert.NotNil(e30) {
+			assert.Equal("$.mappings[0].policies[0].returns.push", e30.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb31, e31 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-31.json"))
+	require.Nil(e31)
+	j31, e31 := myjson.Unmarshal(fb31)
+	if assert.Nil(e31) {
+		m31 := &mappingsParser{json: j31}
+		p31, e31 := m31.parse()
+		if assert.Nil(e31) && assert.Len(p31, 1) {
+			bodyPaths := p31[0].Policies[0].When.BodyPaths
+			if assert.Len(bodyPaths, 3) {
+				assert.Equal("$..price", bodyPaths[0].Path.String())
+				assert.Equal("$.customer", bodyPaths[1].Path.String())
+				assert.Equal("$.items[0].id", bodyPaths[2].Path.String())
+
+				assert.True(bodyPaths[0].Matcher.Matches(myjson.Number(42)))
+				assert.False(bodyPaths[0].Matcher.Matches(myjson.String("4a")))
+				assert.True(bodyPaths[1].Matcher.Matches(myjson.Object{"vip": myjson.Boolean(true)}))
+				assert.Equal(myjson.MakeExtJSONMatcher(myjson.String("a1")), bodyPaths[2].Matcher)
+			}
+		}
+	}
+
+	fb32, e32 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-32.json"))
+	require.Nil(e32)
+	j32, e32 := myjson.Unmarshal(fb32)
+	if assert.Nil(e32) {
+		m32 := &mappingsParser{json: j32}
+		_, e32 := m32.parse()
+		if assert.NotNil(e32) {
+			assert.Equal("$.mappings[0].policies[0].when.bodyPaths['$.items[0']", e32.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb33, e33 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-33.json"))
+	require.Nil(e33)
+	j33, e33 := myjson.Unmarshal(fb33)
+	if assert.Nil(e33) {
+		m33 := &mappingsParser{json: j33}
+		p33, e33 := m33.parse()
+		if assert.Nil(e33) && assert.Len(p33, 1) && assert.Len(p33[0].Policies, 2) {
+			bodyXPath := p33[0].Policies[0].When.BodyXPath
+			if assert.Len(bodyXPath, 3) {
+				assert.Equal("//item/price", bodyXPath[0].Expr.String())
+				assert.Equal(`^\d+$`, bodyXPath[0].Regexp.String())
+				assert.Equal("/order/@id", bodyXPath[1].Expr.String())
+				assert.Equal("a1", bodyXPath[1].Value)
+				assert.Equal("count(//item)", bodyXPath[2].Expr.String())
+				assert.Equal("2", bodyXPath[2].Value)
+			}
+
+			bodyXML := p33[0].Policies[1].When.BodyXML
+			if assert.NotNil(bodyXML) {
+				expected, err := myxml.Parse([]byte(`<order status="new" id="a1"><item>book</item></order>`))
+				require.Nil(err)
+				assert.True(expected.Equal(bodyXML))
+			}
+		}
+	}
+
+	fb34, e34 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-34.json"))
+	require.Nil(e34)
+	j34, e34 := myjson.Unmarshal(fb34)
+	if assert.Nil(e34) {
+		m34 := &mappingsParser{json: j34}
+		_, e34 := m34.parse()
+		if assert.NotNil(e34) {
+			assert.Equal("$.mappings[0].policies[0].when.bodyXPath['/order[@id']", e34.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb35, e35 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-35.json"))
+	require.Nil(e35)
+	j35, e35 := myjson.Unmarshal(fb35)
+	if assert.Nil(e35) {
+		m35 := &mappingsParser{json: j35}
+		p35, e35 := m35.parse()
+		if assert.Nil(e35) && assert.Len(p35, 1) {
+			files := p35[0].Policies[0].When.Files
+			if assert.Len(files, 2) {
+				assert.Equal(&FileMatcher{
+					Name:           "avatar",
+					FilenameRegexp: regexp.MustCompile(`\.png$`),
+					ContentType:    "image/png",
+					Size:           &Interval{Min: 1, Max: 1024},
+				}, files[0])
+				assert.Equal(&FileMatcher{
+					Name:          "note",
+					ContentRegexp: regexp.MustCompile("^hello"),
+				}, files[1])
+			}
+		}
+	}
+
+	fb36, e36 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-36.json"))
+	require.Nil(e36)
+	j36, e36 := myjson.Unmarshal(fb36)
+	if assert.Nil(e36) {
+		m36 := &mappingsParser{json: j36}
+		_, e36 := m36.parse()
+		if assert.NotNil(e36) {
+			assert.Equal("$.mappings[0].policies[0].when.files.avatar.size", e36.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb37, e37 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-37.json"))
+	require.Nil(e37)
+	j37, e37 := myjson.Unmarshal(fb37)
+	if assert.Nil(e37) {
+		m37 := &mappingsParser{json: j37}
+		p37, e37 := m37.parse()
+		if assert.Nil(e37) && assert.Len(p37, 1) {
+			when := p37[0].Policies[0].When
+			assert.Equal([]*NameValuesPair{{Name: "verbose", Values: []string{"true"}}}, when.Params)
+			if assert.Len(when.AnyOf, 2) {
+				assert.Equal("X-Debug", when.AnyOf[0].HeaderRegexps[0].Name)
+				assert.Equal([]*NameValuesPair{{Name: "debug", Values: []string{"1"}}}, when.AnyOf[1].Params)
+			}
+			if assert.Len(when.AllOf, 1) {
+				assert.Equal([]*NameValuesPair{{Name: "1", Values: []string{"1"}}}, when.AllOf[0].PathVars)
+			}
+			if assert.NotNil(when.Not) {
+				assert.Equal([]*NameRegexpPair{{Name: "0", Regexp: regexp.MustCompile("^admin")}}, when.Not.PathVarRegexps)
+			}
+		}
+	}
+
+	fb38, e38 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-38.json"))
+	require.Nil(e38)
+	j38, e38 := myjson.Unmarshal(fb38)
+	if assert.Nil(e38) {
+		m38 := &mappingsParser{json: j38}
+		_, e38 := m38.parse()
+		if assert.NotNil(e38) {
+			assert.Equal("$.mappings[0].policies[0].when.anyOf[1]", e38.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb39, e39 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-39.json"))
+	require.Nil(e39)
+	j39, e39 := myjson.Unmarshal(fb39)
+	if assert.Nil(e39) {
+		m39 := &mappingsParser{json: j39}
+		p39, e39 := m39.parse()
+		if assert.Nil(e39) && assert.Len(p39, 1) {
+			when := p39[0].Policies[0].When
+			assert.Equal([]*NameValuesPair{{Name: "lang", Values: []string{"cn"}}}, when.Cookies)
+			assert.Equal([]*NameRegexpPair{{Name: "session", Regexp: regexp.MustCompile("^[0-9a-f]+$")}}, when.CookieRegexps)
+			if assert.Len(when.CookieJSONs, 1) {
+				assert.Equal("visits", when.CookieJSONs[0].Name)
+				assert.True(when.CookieJSONs[0].JSON.Matches(myjson.Number(3)))
+			}
+
+			cookies := p39[0].Policies[0].Returns.Cookies
+			if assert.Len(cookies, 2) {
+				assert.Equal("session=abc123; Path=/; Domain=example.com; Expires=Wed, 02 Jan 2030 03:04:05 GMT; "+
+					"Max-Age=3600; HttpOnly; Secure; SameSite=Strict", cookies[0].String())
+				assert.Equal("lang=; Max-Age=0", cookies[1].String())
+			}
+		}
+	}
+
+	fb40, e40 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-40.json"))
+	require.Nil(e40)
+	j40, e40 := myjson.Unmarshal(fb40)
+	if assert.Nil(e40) {
+		m40 := &mappingsParser{json: j40}
+		_, e40 := m40.parse()
+		if assert.NotNil(e40) {
+			assert.Equal("$.mappings[0].policies[0].returns.cookies[0].sameSite", e40.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb41, e41 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-41.json"))
+	require.Nil(e41)
+	j41, e41 := myjson.Unmarshal(fb41)
+	if assert.Nil(e41) {
+		m41 := &mappingsParser{json: j41}
+		p41, e41 := m41.parse()
+		if assert.Nil(e41) && assert.Len(p41, 1) {
+			when := p41[0].Policies[0].When
+			assert.Equal([]*NameValuesPair{{Name: "Content-Type", Values: []string{"application/json"}}}, when.Headers)
+			assert.Equal([]*NameRegexpPair{{Name: "X-Request-Id", Regexp: regexp.MustCompile(`^\d+$`)}}, when.HeaderRegexps)
+			assert.Equal([]*NameModePair{
+				{Name: "Accept", Mode: ValueTokens, Values: []string{"application/json", "text/csv"}},
+				{Name: "Accept-Encoding", Mode: ValueTokens, Values: []string{"gzip"}},
+				{Name: "Authorization", Mode: ValuePrefix, Values: []string{"Bearer "}},
+				{Name: "User-Agent", Mode: ValueContains, Values: []string{"Mobile"}},
+			}, when.HeaderModes)
+		}
+	}
+
+	fb42, e42 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-42.json"))
+	require.Nil(e42)
+	j42, e42 := myjson.Unmarshal(fb42)
+	if assert.Nil(e42) {
+		m42 := &mappingsParser{json: j42}
+		_, e42 := m42.parse()
+		if assert.NotNil(e42) {
+			assert.Equal("$.mappings[0].policies[0].when.headers.Authorization", e42.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb43, e43 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-43.json"))
+	require.Nil(e43)
+	j43, e43 := myjson.Unmarshal(fb43)
+	if assert.Nil(e43) {
+		m43 := &mappingsParser{json: j43}
+		p43, e43 := m43.parse()
+		if assert.Nil(e43) && assert.Len(p43, 2) {
+			assert.Equal(ParamsPartial, p43[0].MatchParams)
+			assert.Equal(ParamsMode(""), p43[1].MatchParams)
+		}
+	}
+
+	fb44, e44 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-44.json"))
+	require.Nil(e44)
+	j44, e44 := myjson.Unmarshal(fb44)
+	if assert.Nil(e44) {
+		m44 := &mappingsParser{json: j44}
+		_, e44 := m44.parse()
+		if assert.NotNil(e44) {
+			assert.Equal("$.mappings[0].matchParams", e44.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb45, e45 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-45.json"))
+	require.Nil(e45)
+	j45, e45 := myjson.Unmarshal(fb45)
+	if assert.Nil(e45) {
+		m45 := &mappingsParser{json: j45}
+		p45, e45 := m45.parse()
+		if assert.Nil(e45) && assert.Len(p45, 1) && assert.Len(p45[0].Policies, 3) {
+			basic := p45[0].Policies[0].When.Auth.Basic
+			if assert.NotNil(basic) {
+				assert.True(basic.Matches(myjson.Object{"username": myjson.String("kumasuke120"), "password": myjson.String("pa$$w0rd")}))
+				assert.False(basic.Matches(myjson.Object{"username": myjson.String("kumasuke120"), "password": myjson.String("short")}))
+			}
+
+			auth := p45[0].Policies[1].When.Auth
+			assert.Nil(auth.Basic)
+			if assert.NotNil(auth.Bearer) {
+				assert.True(auth.Bearer.Matches(myjson.String("a.b.c")))
+			}
+			if assert.NotNil(auth.JWT) {
+				assert.Equal([]byte("your-256-bit-secret"), auth.JWT.Secret)
+				assert.Nil(auth.JWT.PublicKey)
+				assert.True(auth.JWT.Claims.Matches(myjson.Object{"name": myjson.String("John Doe"), "iat": myjson.Number(1516239022)}))
+			}
+
+			jwt := p45[0].Policies[2].When.Auth.JWT
+			if assert.NotNil(jwt) {
+				assert.Nil(jwt.Claims)
+				assert.IsType(&ecdsa.PublicKey{}, jwt.PublicKey)
+			}
+		}
+	}
+
+	fb46, e46 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-46.json"))
+	require.Nil(e46)
+	j46, e46 := myjson.Unmarshal(fb46)
+	if assert.Nil(e46) {
+		m46 := &mappingsParser{json: j46}
+		_, e46 := m46.parse()
+		if assert.NotNil(e46) {
+			assert.Equal("$.mappings[0].policies[0].when.auth.jwt.publicKey", e46.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb47, e47 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-47.json"))
+	require.Nil(e47)
+	j47, e47 := myjson.Unmarshal(fb47)
+	if assert.Nil(e47) {
+		m47 := &mappingsParser{json: j47}
+		p47, e47 := m47.parse()
+		if assert.Nil(e47) && assert.Len(p47, 2) {
+			assert.Equal("/books/{0:int}/files/{1:*}", p47[0].URI)
+			assert.Equal([]*NameValuesPair{
+				{Name: "0", Values: []string{"1"}},
+				{Name: "1", Values: []string{"cover.png"}},
+			}, p47[0].Policies[0].When.PathVars)
+			assert.Equal("/currencies/{0:[A-Z]{3}}/{1}", p47[1].URI)
+		}
+	}
+
+	fb48, e48 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-48.json"))
+	require.Nil(e48)
+	j48, e48 := myjson.Unmarshal(fb48)
+	if assert.Nil(e48) {
+		m48 := &mappingsParser{json: j48}
+		_, e48 := m48.parse()
+		if assert.NotNil(e48) {
+			assert.Equal("$.mappings[0].uri", e48.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb49, e49 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-49.json"))
+	require.Nil(e49)
+	j49, e49 := myjson.Unmarshal(fb49)
+	if assert.Nil(e49) {
+		m49 := &mappingsParser{json: j49}
+		p49, e49 := m49.parse()
+		if assert.Nil(e49) && assert.Len(p49, 2) {
+			assert.Equal(10, p49[0].Priority)
+			assert.Equal(-1, p49[1].Priority)
+		}
+	}
+
+	fb50, e50 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-50.json"))
+	require.Nil(e50)
+	j50, e50 := myjson.Unmarshal(fb50)
+	if assert.Nil(e50) {
+		m50 := &mappingsParser{json: j50}
+		_, e50 := m50.parse()
+		if assert.NotNil(e50) {
+			assert.Equal("$.mappings[0].priority", e50.(*parserError).jsonPath.String())
+		}
+	}
+
+	fb51, e51 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-51.json"))
+	require.Nil(e51)
+	j51, e51 := myjson.Unmarshal(fb51)
+	if assert.Nil(e51) {
+		m51 := &mappingsParser{json: j51}
+		_, e51 := m51.parse()
+		if assert.NotNil(e51) {
+			assert.Equal("$.mappings[0].policies[0].events.repeat", e51.(*parserError).jsonPath.String())
+			assert.Contains(e51.Error(), "at least one positive delay")
+		}
+	}
+
+	fb52, e52 := ioutil.ReadFile(filepath.Join("testdata", "mappings", "mappings-52.json"))
+	require.Nil(e52)
+	j52, e52 := myjson.Unmarshal(fb52)
+	if assert.Nil(e52) {
+		m52 := &mappingsParser{json: j52}
+		_, e52 := m52.parse()
+		if assert.NotNil(e52) {
+			assert.Equal("$.mappings[0].policies[0].when.headers['content-type']", e52.(*parserError).jsonPath.String())
+			assert.Contains(e52.Error(), "'Content-Type' is declared more than once")
+		}
+	}
+}
+
+func TestEncodeURI(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	u1, e1 := encodeURI("/书/{id:int}/{slug:[a-z-]{2,}}")
+	if assert.Nil(e1) {
+		assert.Equal("/%E4%B9%A6/{id:int}/{slug:[a-z-]{2,}}", u1)
+	}
+	u2, e2 := encodeURI("/a/{b")
+	if assert.Nil(e2) {
+		assert.Equal("/a/%7Bb", u2)
+	}
+
+	_, e3 := encodeURI("/a/{id:[a-z}")
+	assert.NotNil(e3)
+	_, e4 := encodeURI("/a/{id:(?P<x>.*)}")
+	assert.NotNil(e4)
+	_, e5 := encodeURI("/a/{id:int}/b/{id}")
+	assert.NotNil(e5)
+}
+
+func TestURIPattern(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	assert.Equal("", URIPattern("/a/b"))
+	assert.Equal(`^/a\.json/(?P<v0>[^/]+)$`, URIPattern("/a.json/{0}"))
+	assert.Equal(`^/a/(?P<v0>-?[0-9]+)/(?P<v1>.*)$`, URIPattern("/a/{0:int}/{1:*}"))
+	assert.Equal(`^/a/(?P<v0>(?:[A-Z]{3}))$`, URIPattern("/a/{0:[A-Z]{3}}"))
+}
+
+func TestURISpecificity(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	assert.Equal([]int{SegmentLiteral}, URISpecificity("/"))
+	assert.Equal([]int{SegmentLiteral, SegmentVar, SegmentLiteral}, URISpecificity("/api/{0}/x"))
+	assert.Equal([]int{SegmentLiteral, SegmentTypedVar, SegmentMixed}, URISpecificity("/api/{0:int}/{1}.json"))
+	assert.Equal([]int{SegmentMixed, SegmentCatchAll}, URISpecificity("/{0}-{1}/{2:*}"))
+	assert.Equal([]int{SegmentLiteral, SegmentTypedVar}, URISpecificity("/a/{0:[a-z]+/[a-z]+}"))
+}
+
+func TestSequence_At(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	r0 := &Returns{StatusCode: myhttp.StatusCode(500)}
+	r1 := &Returns{StatusCode: myhttp.StatusOK}
+
+	s1 := &Sequence{Returns: []*Returns{r0, r1}}
+	assert.Equal(r0, s1.At(0))
+	assert.Equal(r1, s1.At(1))
+	assert.Equal(r1, s1.At(2))
+
+	s2 := &Sequence{Returns: []*Returns{r0, r1}, Cycle: true}
+	assert.Equal(r1, s2.At(1))
+	assert.Equal(r0, s2.At(2))
+	assert.Equal(r1, s2.At(5))
+}
+
+func TestOneOf_Pick(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	r0 := &Returns{StatusCode: myhttp.StatusCode(503)}
+	r1 := &Returns{StatusCode: myhttp.StatusOK}
+
+	o := &OneOf{Choices: []*Choice{{Weight: 1, Returns: r0}, {Weight: 19, Returns: r1}}}
+	assert.Equal(r0, o.Pick(0))
+	assert.Equal(r0, o.Pick(0.049))
+	assert.Equal(r1, o.Pick(0.05))
+	assert.Equal(r1, o.Pick(0.999))
 }
@@ -0,0 +1,218 @@
+package mckmaps
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+// DefaultOAuth2Path is the path under which the OAuth2/OIDC endpoints are served by default
+const DefaultOAuth2Path = "/oauth2"
+
+// OAuth2Options configures the built-in OAuth2/OIDC provider, which issues JWTs
+// signed with a RSA key generated on start
+type OAuth2Options struct {
+	Path      string // the path of the issuer, under which the endpoints are served
+	Issuer    string // the value of 'iss', derived from requests and Path if empty
+	ExpiresIn time.Duration
+	Clients   []*OAuth2Client // any client is accepted if empty
+	Users     []*OAuth2User   // any username and password are accepted if empty
+}
+
+// OAuth2Client is a registered client, any redirect uri is allowed if
+// RedirectURIs is empty, and the secret is not checked if empty
+type OAuth2Client struct {
+	ID           string
+	Secret       string
+	RedirectURIs []string
+}
+
+// OAuth2User is a resource owner, whose claims are put into both access tokens
+// and ID tokens, 'sub' defaults to the username
+type OAuth2User struct {
+	Username string
+	Password string
+	Claims   myjson.Object
+}
+
+func defaultOAuth2Options() *OAuth2Options {
+	return &OAuth2Options{Path: DefaultOAuth2Path, ExpiresIn: time.Hour}
+}
+
+// FindClient finds the client by its id, nil if not registered
+func (o *OAuth2Options) FindClient(id string) *OAuth2Client {
+	for _, c := range o.Clients {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// FindUser finds the user by the username, nil if not found
+func (o *OAuth2Options) FindUser(username string) *OAuth2User {
+	for _, u := range o.Users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+func (p *mainParser) parseOAuth2Options(v myjson.Object) (*OAuth2Options, error) {
+	_oo := v.Get(aConfigOAuth2)
+	switch _oo.(type) {
+	case nil:
+		return nil, nil
+	case myjson.Boolean:
+		if _oo.(myjson.Boolean) {
+			return defaultOAuth2Options(), nil
+		}
+		return nil, nil
+	case myjson.Object:
+		// continues parsing
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	oauth2V := _oo.(myjson.Object)
+	p.jsonPath.Append("")
+
+	oo := defaultOAuth2Options()
+
+	if oauth2V.Has(oauth2Path) {
+		p.jsonPath.SetLast(oauth2Path)
+		path, err := oauth2V.GetString(oauth2Path)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		_path := strings.TrimRight(string(path), "/")
+		if !strings.HasPrefix(_path, "/") {
+			return nil, &parserError{
+				filename: p.filename,
+				jsonPath: p.jsonPath,
+				err:      errors.New("oauth2 path must start with '/'"),
+			}
+		}
+		oo.Path = _path
+	}
+
+	if oauth2V.Has(oauth2Issuer) {
+		p.jsonPath.SetLast(oauth2Issuer)
+		issuer, err := oauth2V.GetString(oauth2Issuer)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		oo.Issuer = strings.TrimRight(string(issuer), "/")
+	}
+
+	if oauth2V.Has(oauth2ExpiresIn) {
+		p.jsonPath.SetLast(oauth2ExpiresIn)
+		expiresIn, err := oauth2V.GetNumber(oauth2ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		oo.ExpiresIn = time.Duration(expiresIn) * time.Second
+	}
+
+	if oauth2V.Has(oauth2Clients) {
+		p.jsonPath.SetLast(oauth2Clients)
+		p.jsonPath.Append(0)
+		for idx, rc := range ensureJSONArray(oauth2V.Get(oauth2Clients)) {
+			p.jsonPath.SetLast(idx)
+			rco, ok := rc.(myjson.Object)
+			if !ok {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			client, err := p.parseOAuth2Client(rco)
+			if err != nil {
+				return nil, err
+			}
+			oo.Clients = append(oo.Clients, client)
+		}
+		p.jsonPath.RemoveLast()
+	}
+
+	if oauth2V.Has(oauth2Users) {
+		p.jsonPath.SetLast(oauth2Users)
+		p.jsonPath.Append(0)
+		for idx, ru := range ensureJSONArray(oauth2V.Get(oauth2Users)) {
+			p.jsonPath.SetLast(idx)
+			ruo, ok := ru.(myjson.Object)
+			if !ok {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			user, err := p.parseOAuth2User(ruo)
+			if err != nil {
+				return nil, err
+			}
+			oo.Users = append(oo.Users, user)
+		}
+		p.jsonPath.RemoveLast()
+	}
+
+	p.jsonPath.RemoveLast()
+	return oo, nil
+}
+
+func (p *mainParser) parseOAuth2Client(v myjson.Object) (*OAuth2Client, error) {
+	p.jsonPath.Append("")
+
+	p.jsonPath.SetLast(oauth2ClientID)
+	id, err := v.GetString(oauth2ClientID)
+	if err != nil || id == "" {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	client := &OAuth2Client{ID: string(id)}
+
+	if v.Has(oauth2ClientSecret) {
+		p.jsonPath.SetLast(oauth2ClientSecret)
+		secret, err := v.GetString(oauth2ClientSecret)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		client.Secret = string(secret)
+	}
+
+	if v.Has(oauth2RedirectURIs) {
+		p.jsonPath.SetLast(oauth2RedirectURIs)
+		uris, err := p.getAsStringSlice(v, oauth2RedirectURIs)
+		if err != nil {
+			return nil, err
+		}
+		client.RedirectURIs = uris
+	}
+
+	p.jsonPath.RemoveLast()
+	return client, nil
+}
+
+func (p *mainParser) parseOAuth2User(v myjson.Object) (*OAuth2User, error) {
+	p.jsonPath.Append("")
+
+	p.jsonPath.SetLast(oauth2Username)
+	username, err := v.GetString(oauth2Username)
+	if err != nil || username == "" {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	p.jsonPath.SetLast(oauth2Password)
+	password, err := v.GetString(oauth2Password)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	user := &OAuth2User{Username: string(username), Password: string(password), Claims: myjson.Object{}}
+
+	if v.Has(oauth2Claims) {
+		p.jsonPath.SetLast(oauth2Claims)
+		claims, err := v.GetObject(oauth2Claims)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		user.Claims = claims
+	}
+
+	p.jsonPath.RemoveLast()
+	return user, nil
+}
@@ -1,6 +1,7 @@
 package mckmaps
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 	"github.com/kumasuke120/mockuma/internal/myos"
 	"github.com/kumasuke120/mockuma/internal/types"
 	"github.com/rs/cors"
@@ -37,15 +39,61 @@ func (m *MockuMappings) GroupMethodsByURI() map[string][]myhttp.HTTPMethod {
 type Config struct {
 	CORS               *CORSOptions
 	MatchTrailingSlash bool
+	MatchParams        ParamsMode // how params of requests are matched, may be overridden by mappings
+	Admin              *AdminOptions
+	Log                *LogOptions
+	HTTP2              bool           // serves HTTP/2, over TLS (h2) if TLS is set, or without TLS (h2c) otherwise
+	TLS                *TLSOptions    // serves over TLS if not nil
+	OAuth2             *OAuth2Options // serves a built-in OAuth2/OIDC provider if not nil
 }
 
 func defaultConfig() *Config {
 	return &Config{
 		CORS:               defaultDisabledCORS(),
 		MatchTrailingSlash: false,
+		MatchParams:        ParamsExact,
+		Admin:              defaultDisabledAdmin(),
+		Log:                defaultLogOptions(),
+		HTTP2:              true,
 	}
 }
 
+// TLSOptions holds the certificate to serve TLS with, a self-signed certificate
+// for localhost is generated on start if SelfSigned is true
+type TLSOptions struct {
+	SelfSigned  bool
+	CertFile    string
+	KeyFile     string
+	Certificate *tls.Certificate // loaded from CertFile and KeyFile
+}
+
+// LogOptions holds the logging settings, which could be overridden by command-line arguments
+type LogOptions struct {
+	Level     mylog.Level
+	Format    mylog.Format
+	BodyLimit int
+}
+
+func defaultLogOptions() *LogOptions {
+	return &LogOptions{Level: mylog.LevelInfo, Format: mylog.FormatText, BodyLimit: 0}
+}
+
+// DefaultAdminPath is the path under which the admin endpoints are served by default
+const DefaultAdminPath = "/__mockuma"
+
+type AdminOptions struct {
+	Enabled bool
+	Path    string
+}
+
+func defaultEnabledAdmin() *AdminOptions {
+	return &AdminOptions{Enabled: true, Path: DefaultAdminPath}
+}
+
+func defaultDisabledAdmin() *AdminOptions {
+	return &AdminOptions{Enabled: false, Path: DefaultAdminPath}
+}
+
 type CORSOptions struct {
 	Enabled          bool
 	AllowCredentials bool
@@ -325,6 +373,12 @@ func appendToMappingsOfURI(dst []*Mapping, m *Mapping) []*Mapping {
 	for _, dm := range dst {
 		if dm.URI == m.URI && dm.Method == m.Method {
 			dm.Policies = append(dm.Policies, m.Policies...)
+			if dm.MatchParams == "" { // the first declared mode wins
+				dm.MatchParams = m.MatchParams
+			}
+			if dm.Priority < m.Priority {
+				dm.Priority = m.Priority
+			}
 			merged = true
 		}
 	}
@@ -440,7 +494,57 @@ func (p *mainParser) parseConfig(v interface{}) (c *Config, err error) {
 			mts = false
 		}
 
-		c = &Config{CORS: co, MatchTrailingSlash: mts}
+		p.jsonPath.SetLast(aConfigMatchParams)
+		mp := ParamsExact
+		if vo.Has(aConfigMatchParams) {
+			var ok bool
+			mp, ok = parseParamsMode(vo.Get(aConfigMatchParams))
+			if !ok {
+				err = p.newJSONParseError(p.jsonPath)
+				return
+			}
+		}
+
+		p.jsonPath.SetLast(aConfigAdmin)
+		var ao *AdminOptions
+		ao, err = p.parseAdminOptions(vo)
+		if err != nil {
+			return
+		}
+
+		p.jsonPath.SetLast(aConfigLog)
+		var lo *LogOptions
+		lo, err = p.parseLogOptions(vo)
+		if err != nil {
+			return
+		}
+
+		p.jsonPath.SetLast(aConfigHTTP2)
+		h2 := true
+		if vo.Has(aConfigHTTP2) {
+			_h2, _err := vo.GetBoolean(aConfigHTTP2)
+			if _err != nil {
+				err = p.newJSONParseError(p.jsonPath)
+				return
+			}
+			h2 = bool(_h2)
+		}
+
+		p.jsonPath.SetLast(aConfigTLS)
+		var to *TLSOptions
+		to, err = p.parseTLSOptions(vo)
+		if err != nil {
+			return
+		}
+
+		p.jsonPath.SetLast(aConfigOAuth2)
+		var oo *OAuth2Options
+		oo, err = p.parseOAuth2Options(vo)
+		if err != nil {
+			return
+		}
+
+		c = &Config{CORS: co, MatchTrailingSlash: mts, MatchParams: mp, Admin: ao, Log: lo, HTTP2: h2, TLS: to, OAuth2: oo}
 		p.jsonPath.RemoveLast()
 	default:
 		return nil, p.newJSONParseError(p.jsonPath)
@@ -549,6 +653,168 @@ func (p *mainParser) parseActualCORSOptions(v myjson.Object) (*CORSOptions, erro
 	return defaultDisabledCORS(), nil
 }
 
+func (p *mainParser) parseAdminOptions(v myjson.Object) (ao *AdminOptions, err error) {
+	_ao := v.Get(aConfigAdmin)
+	switch _ao.(type) {
+	case nil:
+		ao = defaultDisabledAdmin()
+	case myjson.Boolean:
+		if _ao.(myjson.Boolean) {
+			ao = defaultEnabledAdmin()
+		} else {
+			ao = defaultDisabledAdmin()
+		}
+	case myjson.Object:
+		_adminV := _ao.(myjson.Object)
+		p.jsonPath.Append("")
+		ao, err = p.parseActualAdminOptions(_adminV)
+		if err != nil {
+			return
+		}
+		p.jsonPath.RemoveLast()
+	default:
+		err = p.newJSONParseError(p.jsonPath)
+		return
+	}
+	return
+}
+
+func (p *mainParser) parseActualAdminOptions(v myjson.Object) (*AdminOptions, error) {
+	p.jsonPath.SetLast(adminEnabled)
+	enabled, err := v.GetBoolean(adminEnabled)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	if enabled {
+		ao := defaultEnabledAdmin()
+
+		if v.Has(adminPath) {
+			p.jsonPath.SetLast(adminPath)
+			path, err := v.GetString(adminPath)
+			if err != nil {
+				return nil, p.newJSONParseError(p.jsonPath)
+			}
+			_path := strings.TrimRight(string(path), "/")
+			if !strings.HasPrefix(_path, "/") {
+				return nil, &parserError{
+					filename: p.filename,
+					jsonPath: p.jsonPath,
+					err:      errors.New("admin path must start with '/'"),
+				}
+			}
+			ao.Path = _path
+		}
+
+		return ao, nil
+	}
+
+	return defaultDisabledAdmin(), nil
+}
+
+func (p *mainParser) parseLogOptions(v myjson.Object) (*LogOptions, error) {
+	lo := defaultLogOptions()
+
+	_lo := v.Get(aConfigLog)
+	switch _lo.(type) {
+	case nil:
+		return lo, nil
+	case myjson.Object:
+		// continues parsing
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	logV := _lo.(myjson.Object)
+	p.jsonPath.Append("")
+
+	if logV.Has(logLevel) {
+		p.jsonPath.SetLast(logLevel)
+		_level, err := logV.GetString(logLevel)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		level, err := mylog.ParseLevel(string(_level))
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+		lo.Level = level
+	}
+
+	if logV.Has(logFormat) {
+		p.jsonPath.SetLast(logFormat)
+		_format, err := logV.GetString(logFormat)
+		if err != nil {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		format, err := mylog.ParseFormat(string(_format))
+		if err != nil {
+			return nil, &parserError{filename: p.filename, jsonPath: p.jsonPath, err: err}
+		}
+		lo.Format = format
+	}
+
+	if logV.Has(logBodyLimit) {
+		p.jsonPath.SetLast(logBodyLimit)
+		bl, err := logV.GetNumber(logBodyLimit)
+		if err != nil || bl < 0 {
+			return nil, p.newJSONParseError(p.jsonPath)
+		}
+		lo.BodyLimit = int(bl)
+	}
+
+	p.jsonPath.RemoveLast()
+	return lo, nil
+}
+
+func (p *mainParser) parseTLSOptions(v myjson.Object) (*TLSOptions, error) {
+	_to := v.Get(aConfigTLS)
+	switch _to.(type) {
+	case nil:
+		return nil, nil
+	case myjson.Boolean:
+		if _to.(myjson.Boolean) {
+			return &TLSOptions{SelfSigned: true}, nil
+		}
+		return nil, nil
+	case myjson.Object:
+		// continues parsing
+	default:
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	tlsV := _to.(myjson.Object)
+	p.jsonPath.Append("")
+
+	p.jsonPath.SetLast(tlsCertFile)
+	certFile, err := tlsV.GetString(tlsCertFile)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+	p.jsonPath.SetLast(tlsKeyFile)
+	keyFile, err := tlsV.GetString(tlsKeyFile)
+	if err != nil {
+		return nil, p.newJSONParseError(p.jsonPath)
+	}
+
+	to := &TLSOptions{CertFile: string(certFile), KeyFile: string(keyFile)}
+	for _, f := range []string{to.CertFile, to.KeyFile} {
+		if err := checkFilepath(f); err != nil {
+			return nil, &loadError{filename: f, err: err}
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(to.CertFile, to.KeyFile)
+	if err != nil {
+		return nil, &loadError{filename: to.CertFile, err: err}
+	}
+	recordLoadedFile(to.CertFile)
+	recordLoadedFile(to.KeyFile)
+	to.Certificate = &cert
+
+	p.jsonPath.RemoveLast()
+	return to, nil
+}
+
 func (p *mainParser) getAsStringSlice(v myjson.Object, name string) ([]string, error) {
 	p.jsonPath.Append("")
 
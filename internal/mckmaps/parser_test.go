@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 	"github.com/kumasuke120/mockuma/internal/myos"
 	"github.com/rs/cors"
 	"github.com/stretchr/testify/assert"
@@ -171,7 +173,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn2, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: true,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS: &CORSOptions{
 				Enabled:          true,
 				AllowCredentials: true,
@@ -203,7 +209,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn3, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS: &CORSOptions{
 				Enabled:          true,
 				AllowCredentials: false,
@@ -231,7 +241,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn4, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS:               defaultEnabledCORS(),
 		},
 	}
@@ -246,7 +260,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn5, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS:               defaultDisabledCORS(),
 		},
 	}
@@ -274,7 +292,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn8, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS:               defaultDisabledCORS(),
 		},
 	}
@@ -297,7 +319,11 @@ func TestParser_Parse(t *testing.T) {
 		Mappings:  expectedMappings,
 		Filenames: []string{fn10, fn1},
 		Config: &Config{
+			HTTP2:              true,
 			MatchTrailingSlash: true,
+			MatchParams:        ParamsExact,
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
 			CORS:               defaultDisabledCORS(),
 		},
 	}
@@ -307,6 +333,172 @@ func TestParser_Parse(t *testing.T) {
 		assert.Equal(expected10, actual10)
 	}
 
+	fn11 := "parser-multi-10.json"
+	expected11 := &MockuMappings{
+		Mappings:  expectedMappings,
+		Filenames: []string{fn11, fn1},
+		Config: &Config{
+			HTTP2:              true,
+			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			CORS:               defaultDisabledCORS(),
+			Admin: &AdminOptions{
+				Enabled: true,
+				Path:    "/admin",
+			},
+			Log: defaultLogOptions(),
+		},
+	}
+	parser11 := NewParser(fn11)
+	actual11, e11 := parser11.Parse()
+	if assert.Nil(e11) {
+		assert.Equal(expected11, actual11)
+	}
+
+	fn12 := "parser-multi-11.json"
+	parser12 := NewParser(fn12)
+	_, e12 := parser12.Parse()
+	if assert.NotNil(e12) {
+		ep12 := e12.(*parserError).jsonPath.String()
+		assert.Equal("$.config.admin.path", ep12)
+	}
+
+	fn13 := "parser-multi-12.json"
+	expected13 := &MockuMappings{
+		Mappings:  expectedMappings,
+		Filenames: []string{fn13, fn1},
+		Config: &Config{
+			HTTP2:              true,
+			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			CORS:               defaultDisabledCORS(),
+			Admin:              defaultEnabledAdmin(),
+			Log:                defaultLogOptions(),
+		},
+	}
+	parser13 := NewParser(fn13)
+	actual13, e13 := parser13.Parse()
+	if assert.Nil(e13) {
+		assert.Equal(expected13, actual13)
+	}
+
+	fn14 := "parser-multi-13.json"
+	expected14 := &MockuMappings{
+		Mappings:  expectedMappings,
+		Filenames: []string{fn14, fn1},
+		Config: &Config{
+			HTTP2:              true,
+			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			CORS:               defaultDisabledCORS(),
+			Admin:              defaultDisabledAdmin(),
+			Log: &LogOptions{
+				Level:     mylog.LevelDebug,
+				Format:    mylog.FormatJSON,
+				BodyLimit: 512,
+			},
+		},
+	}
+	parser14 := NewParser(fn14)
+	actual14, e14 := parser14.Parse()
+	if assert.Nil(e14) {
+		assert.Equal(expected14, actual14)
+	}
+
+	fn15 := "parser-multi-14.json"
+	parser15 := NewParser(fn15)
+	_, e15 := parser15.Parse()
+	if assert.NotNil(e15) {
+		ep15 := e15.(*parserError).jsonPath.String()
+		assert.Equal("$.config.log.level", ep15)
+	}
+
+	fn16 := "parser-multi-15.json"
+	expected16 := &MockuMappings{
+		Mappings:  expectedMappings,
+		Filenames: []string{fn16, fn1},
+		Config: &Config{
+			HTTP2:              false,
+			TLS:                &TLSOptions{SelfSigned: true},
+			MatchTrailingSlash: false,
+			MatchParams:        ParamsExact,
+			CORS:               defaultDisabledCORS(),
+			Admin:              defaultDisabledAdmin(),
+			Log:                defaultLogOptions(),
+		},
+	}
+	parser16 := NewParser(fn16)
+	actual16, e16 := parser16.Parse()
+	if assert.Nil(e16) {
+		assert.Equal(expected16, actual16)
+	}
+
+	fn17 := "parser-multi-16.json"
+	parser17 := NewParser(fn17)
+	actual17, e17 := parser17.Parse()
+	if assert.Nil(e17) {
+		assert.Equal([]string{fn17, fn1, "tls/cert.pem", "tls/key.pem"}, actual17.Filenames)
+		assert.True(actual17.Config.HTTP2)
+		if assert.NotNil(actual17.Config.TLS) {
+			assert.False(actual17.Config.TLS.SelfSigned)
+			assert.Equal("tls/cert.pem", actual17.Config.TLS.CertFile)
+			assert.Equal("tls/key.pem", actual17.Config.TLS.KeyFile)
+			assert.NotNil(actual17.Config.TLS.Certificate)
+		}
+	}
+
+	fn18 := "parser-multi-17.json"
+	parser18 := NewParser(fn18)
+	_, e18 := parser18.Parse()
+	if assert.NotNil(e18) {
+		ep18 := e18.(*parserError).jsonPath.String()
+		assert.Equal("$.config.tls.keyFile", ep18)
+	}
+
+	fn19 := "parser-multi-18.json"
+	parser19 := NewParser(fn19)
+	actual19, e19 := parser19.Parse()
+	if assert.Nil(e19) {
+		assert.Equal(ParamsStrict, actual19.Config.MatchParams)
+	}
+
+	fn20 := "parser-multi-19.json"
+	parser20 := NewParser(fn20)
+	_, e20 := parser20.Parse()
+	if assert.NotNil(e20) {
+		ep20 := e20.(*parserError).jsonPath.String()
+		assert.Equal("$.config.matchParams", ep20)
+	}
+
+	fn21 := "parser-multi-20.json"
+	parser21 := NewParser(fn21)
+	actual21, e21 := parser21.Parse()
+	if assert.Nil(e21) {
+		assert.Equal(&OAuth2Options{
+			Path:      "/idp",
+			Issuer:    "http://localhost:3214/idp",
+			ExpiresIn: 10 * time.Minute,
+			Clients: []*OAuth2Client{
+				{ID: "app", Secret: "s3cret", RedirectURIs: []string{"http://localhost:8080/callback"}},
+			},
+			Users: []*OAuth2User{
+				{
+					Username: "kumasuke120",
+					Password: "pa$$w0rd",
+					Claims:   myjson.Object{"email": myjson.String("kumasuke120@example.com")},
+				},
+			},
+		}, actual21.Config.OAuth2)
+	}
+
+	fn22 := "parser-multi-21.json"
+	parser22 := NewParser(fn22)
+	_, e22 := parser22.Parse()
+	if assert.NotNil(e22) {
+		ep22 := e22.(*parserError).jsonPath.String()
+		assert.Equal("$.config.oauth2.users[0].password", ep22)
+	}
+
 	require.Nil(myos.Chdir(oldWd))
 }
 
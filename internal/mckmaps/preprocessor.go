@@ -237,7 +237,11 @@ func (p *dJSONProcessor) generateObject(v myjson.Object) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		return myjson.MakeExtJSONMatcher(raw), nil
+		matcher := myjson.MakeExtJSONMatcher(raw)
+		if err := matcher.Check(); err != nil {
+			return nil, err
+		}
+		return matcher, nil
 	} else {
 		gV := make(myjson.Object)
 		for name, value := range v {
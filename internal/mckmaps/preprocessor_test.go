@@ -153,4 +153,12 @@ func TestToJSONMatcher(t *testing.T) {
 	if assert.Nil(err) {
 		assert.Equal(expected, ja)
 	}
+
+	j1 := myjson.Object{
+		"@json": myjson.Object{
+			"a": myjson.Object{"@between": myjson.Array{myjson.Number(5), myjson.Number(1)}},
+		},
+	}
+	_, err = types.DoFiltersOnV(j1, &dJSONProcessor{})
+	assert.NotNil(err)
 }
@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default buckets of histograms, in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+type Registry struct {
+	mux        sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+func (r *Registry) register(c collector) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		metricVec: metricVec{name: name, help: help, labelNames: labelNames},
+		values:    make(map[string]float64),
+	}
+	r.register(c)
+	return c
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	_buckets := make([]float64, len(buckets))
+	copy(_buckets, buckets)
+	sort.Float64s(_buckets)
+
+	h := &HistogramVec{
+		metricVec: metricVec{name: name, help: help, labelNames: labelNames},
+		buckets:   _buckets,
+		values:    make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+// WriteText writes all registered metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mux.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mux.Unlock()
+
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type metricVec struct {
+	mux        sync.Mutex
+	name       string
+	help       string
+	labelNames []string
+	labelSets  map[string][]string
+}
+
+// joins label values into a key which identifies a series in the vector
+func (v *metricVec) keyOf(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric '%s' requires %d label values, got %d",
+			v.name, len(v.labelNames), len(labelValues)))
+	}
+
+	return strings.Join(labelValues, "\xff")
+}
+
+// records label values of the given series for later output
+func (v *metricVec) track(labelValues []string) string {
+	key := v.keyOf(labelValues)
+	if v.labelSets == nil {
+		v.labelSets = make(map[string][]string)
+	}
+	if _, ok := v.labelSets[key]; !ok {
+		_labelValues := make([]string, len(labelValues))
+		copy(_labelValues, labelValues)
+		v.labelSets[key] = _labelValues
+	}
+	return key
+}
+
+func (v *metricVec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.labelSets))
+	for key := range v.labelSets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *metricVec) writeHeader(w io.Writer, _type string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, _type)
+	return err
+}
+
+// formats labels of the given series, extra is appended as the last label if not empty
+func (v *metricVec) formatLabels(key string, extra ...string) string {
+	labelValues := v.labelSets[key]
+	if len(labelValues) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteRune('{')
+	for i, name := range v.labelNames {
+		if i != 0 {
+			b.WriteRune(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labelValues[i]))
+		b.WriteRune('"')
+	}
+	if len(extra) == 2 {
+		if len(v.labelNames) != 0 {
+			b.WriteRune(',')
+		}
+		b.WriteString(extra[0])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(extra[1]))
+		b.WriteRune('"')
+	}
+	b.WriteRune('}')
+	return b.String()
+}
+
+type CounterVec struct {
+	metricVec
+	values map[string]float64
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("counter cannot decrease in value")
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.values[c.track(labelValues)] += delta
+}
+
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.values[c.keyOf(labelValues)]
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if err := c.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	for _, key := range c.sortedKeys() {
+		_, err := fmt.Fprintf(w, "%s%s %s\n", c.name, c.formatLabels(key), formatFloat(c.values[key]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type HistogramVec struct {
+	metricVec
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // non-cumulative counts for each bucket
+	count  uint64
+	sum    float64
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	key := h.track(labelValues)
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if hv, ok := h.values[h.keyOf(labelValues)]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if err := h.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	for _, key := range h.sortedKeys() {
+		hv, ok := h.values[key]
+		if !ok {
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			_, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+				h.formatLabels(key, "le", formatFloat(upper)), cumulative)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, h.formatLabels(key, "le", "+Inf"), hv.count,
+			h.name, h.formatLabels(key), formatFloat(hv.sum),
+			h.name, h.formatLabels(key), hv.count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
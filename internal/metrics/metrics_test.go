@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterVec(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "Test counter.", "a", "b")
+	c.Inc("1", "x")
+	c.Inc("1", "x")
+	c.Add(3, "2", "y\"")
+	assert.Equal(float64(2), c.Value("1", "x"))
+	assert.Equal(float64(3), c.Value("2", "y\""))
+	assert.Equal(float64(0), c.Value("3", "z"))
+
+	assert.Panics(func() {
+		c.Inc("1")
+	})
+	assert.Panics(func() {
+		c.Add(-1, "1", "x")
+	})
+
+	var b strings.Builder
+	if assert.Nil(r.WriteText(&b)) {
+		expected := "# HELP test_total Test counter.\n" +
+			"# TYPE test_total counter\n" +
+			"test_total{a=\"1\",b=\"x\"} 2\n" +
+			"test_total{a=\"2\",b=\"y\\\"\"} 3\n"
+		assert.Equal(expected, b.String())
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_seconds", "Test histogram.", []float64{1, 0.5}, "a")
+	h.Observe(0.25, "1")
+	h.Observe(0.75, "1")
+	h.Observe(3, "1")
+	assert.Equal(uint64(3), h.Count("1"))
+	assert.Equal(uint64(0), h.Count("2"))
+
+	var b strings.Builder
+	if assert.Nil(r.WriteText(&b)) {
+		expected := "# HELP test_seconds Test histogram.\n" +
+			"# TYPE test_seconds histogram\n" +
+			"test_seconds_bucket{a=\"1\",le=\"0.5\"} 1\n" +
+			"test_seconds_bucket{a=\"1\",le=\"1\"} 2\n" +
+			"test_seconds_bucket{a=\"1\",le=\"+Inf\"} 3\n" +
+			"test_seconds_sum{a=\"1\"} 4\n" +
+			"test_seconds_count{a=\"1\"} 3\n"
+		assert.Equal(expected, b.String())
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	assert.Equal("0.005", formatFloat(0.005))
+	assert.Equal("10", formatFloat(10))
+}
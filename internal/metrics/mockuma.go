@@ -0,0 +1,29 @@
+package metrics
+
+// the registry which all metrics of MocKuma are registered to
+var Default = NewRegistry()
+
+// predefined metrics
+var (
+	Requests = Default.NewCounterVec("mockuma_requests_total",
+		"Total number of requests served, by mapping uri, method, policy index and status code.",
+		"uri", "method", "policy", "status")
+	RequestDuration = Default.NewHistogramVec("mockuma_request_duration_seconds",
+		"Time spent serving requests, including injected latency.",
+		DefBuckets, "uri", "method")
+	InjectedLatency = Default.NewHistogramVec("mockuma_injected_latency_seconds",
+		"Latency injected by the 'latency' attribute of policies.",
+		DefBuckets, "uri", "method")
+	ForwardErrors = Default.NewCounterVec("mockuma_forward_errors_total",
+		"Total number of failed forwards.",
+		"uri", "method")
+	Reloads = Default.NewCounterVec("mockuma_reloads_total",
+		"Total number of automatic reloads of mockuMappings, by result.",
+		"result")
+)
+
+// results of reloads
+const (
+	ReloadSuccess = "success"
+	ReloadFailure = "failure"
+)
@@ -2,6 +2,8 @@ package myhttp
 
 const (
 	HeaderAccept                      = "Accept"
+	HeaderAuthorization               = "Authorization"
+	HeaderCacheControl                = "Cache-Control"
 	HeaderAccessControlRequestMethod  = "Access-Control-Request-Method"
 	HeaderAccessControlRequestHeaders = "Access-Control-Request-Headers"
 	HeaderOrigin                      = "Origin"
@@ -9,9 +11,15 @@ const (
 	HeaderContentLength               = "Content-Length"
 	HeaderContentType                 = "Content-Type"
 	HeaderLocation                    = "Location"
+	HeaderTrailer                     = "Trailer"
 	HeaderXForwardedFor               = "X-Forwarded-For"
 	HeaderXForwardedServer            = "X-Forwarded-Server"
+	HeaderXRequestID                  = "X-Request-Id"
 	HeaderXRequestWith                = "X-Request-With"
 )
 
-const ContentTypeJSON = "application/json; charset=utf-8"
+const (
+	ContentTypeJSON        = "application/json; charset=utf-8"
+	ContentTypeHTML        = "text/html; charset=utf-8"
+	ContentTypeEventStream = "text/event-stream"
+)
@@ -18,3 +18,13 @@ func IsNumber(v interface{}) bool {
 		return false
 	}
 }
+
+func IsAllString(v Array) bool {
+	for _, _v := range v {
+		if _, ok := _v.(String); !ok {
+			return false
+		}
+	}
+
+	return true
+}
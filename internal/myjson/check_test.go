@@ -27,3 +27,14 @@ func TestIsNumber(t *testing.T) {
 	v2 := String("")
 	assert.False(IsNumber(v2))
 }
+
+func TestIsAllString(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	v1 := []interface{}{String("a"), String("b")}
+	assert.True(IsAllString(v1))
+
+	v2 := []interface{}{String("a"), Number(1)}
+	assert.False(IsAllString(v2))
+}
@@ -2,6 +2,7 @@ package myjson
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/kumasuke120/mockuma/internal/types"
 )
@@ -28,11 +29,26 @@ func (m ExtJSONMatcher) Matches(v interface{}) bool {
 	return m.matches(m.v, v)
 }
 
+// MatchesMissing tells if a missing value matches, which only operators like
+// @exists and @not may match
+func (m ExtJSONMatcher) MatchesMissing() bool {
+	return m.valueMatches(m.v, nil, false)
+}
+
 func (m ExtJSONMatcher) matches(mv interface{}, v interface{}) bool {
+	return m.valueMatches(mv, v, true)
+}
+
+// present tells if the value exists, which is false for missing keys of objects,
+// only operators tell missing values from nulls
+func (m ExtJSONMatcher) valueMatches(mv interface{}, v interface{}, present bool) bool {
 	switch mv.(type) {
 	case nil:
 		return v == nil
 	case Object:
+		if name, arg, ok := asOperator(mv.(Object)); ok {
+			return m.operatorMatches(name, arg, v, present)
+		}
 		return m.objectMatches(mv.(Object), v)
 	case Array:
 		return m.arrayMatches(mv.(Array), v)
@@ -45,7 +61,7 @@ func (m ExtJSONMatcher) matches(mv interface{}, v interface{}) bool {
 	case ExtRegexp:
 		return m.regexpMatches(mv.(ExtRegexp), v)
 	case ExtJSONMatcher:
-		return m.matches(mv.(ExtJSONMatcher).v, v)
+		return m.valueMatches(mv.(ExtJSONMatcher).v, v, present)
 	}
 
 	return false
@@ -56,8 +72,11 @@ func (m ExtJSONMatcher) objectMatches(mv Object, v interface{}) bool {
 	case Object:
 		_v := v.(Object)
 		for key, val := range mv {
-			_val := _v[key]
-			if !m.matches(val, _val) {
+			if strings.HasPrefix(key, "@@") { // escaped keys, e.g. '@@gt' for '@gt'
+				key = key[1:]
+			}
+			_val, ok := _v[key]
+			if !m.valueMatches(val, _val, ok) {
 				return false
 			}
 		}
@@ -76,8 +95,13 @@ func (m ExtJSONMatcher) arrayMatches(mv Array, v interface{}) bool {
 				continue
 			}
 
-			_val := _v[idx]
-			if !m.matches(val, _val) {
+			if idx >= len(_v) { // missing elements, which only operators like @exists match
+				if !m.valueMatches(val, nil, false) {
+					return false
+				}
+				continue
+			}
+			if !m.matches(val, _v[idx]) {
 				return false
 			}
 		}
@@ -199,3 +199,150 @@ func TestExtJSONMatcher_Matches(t *testing.T) {
 	})
 	assert.False(jm.Matches(o6))
 }
+
+func TestExtJSONMatcher_Matches_operators(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	jm1 := MakeExtJSONMatcher(Object{
+		"quantity": Object{"@gt": Number(0)},
+		"price":    Object{"@between": Array{Number(1), Number(5)}},
+		"status":   Object{"@in": Array{String("new"), String("paid")}},
+		"coupon":   Object{"@exists": Boolean(false)},
+		"name":     Object{"@type": String("string")},
+		"tags":     Object{"@size": Number(3)},
+		"note":     Object{"@not": Object{"@type": String("null")}},
+		"id": Object{"@anyOf": Array{
+			ExtRegexp(regexp.MustCompile("^a")),
+			Object{"@lte": Number(10)},
+		}},
+		"@@gt": String("escaped"),
+	})
+
+	o1 := Object{
+		"quantity": Number(2),
+		"price":    String("5"),
+		"status":   String("paid"),
+		"name":     String("book"),
+		"tags":     Array{String("a"), String("b"), String("c")},
+		"note":     String("gift"),
+		"id":       Number(7),
+		"@gt":      String("escaped"),
+	}
+	assert.True(jm1.Matches(o1))
+
+	set := func(key string, value interface{}) Object {
+		o := make(Object, len(o1))
+		for k, v := range o1 {
+			o[k] = v
+		}
+		if value == nil {
+			delete(o, key)
+		} else {
+			o[key] = value
+		}
+		return o
+	}
+	assert.False(jm1.Matches(set("quantity", Number(0))))
+	assert.False(jm1.Matches(set("quantity", String("many"))))
+	assert.False(jm1.Matches(set("quantity", nil)))
+	assert.True(jm1.Matches(set("price", Number(1))))
+	assert.False(jm1.Matches(set("price", Number(5.01))))
+	assert.False(jm1.Matches(set("status", String("cancelled"))))
+	assert.False(jm1.Matches(set("coupon", String("SALE"))))
+	assert.False(jm1.Matches(set("name", Number(1))))
+	assert.False(jm1.Matches(set("tags", Array{String("a")})))
+	assert.True(jm1.Matches(set("tags", String("abc"))))
+	assert.True(jm1.Matches(set("note", nil)))
+	assert.True(jm1.Matches(set("id", String("a1"))))
+	assert.False(jm1.Matches(set("id", Number(11))))
+	assert.False(jm1.Matches(set("@gt", nil)))
+
+	jm2 := MakeExtJSONMatcher(Object{
+		"coupon":  Object{"@exists": Boolean(true)},
+		"comment": nil,
+		"items":   Object{"@size": Object{"@gte": Number(1)}},
+	})
+	assert.True(jm2.Matches(Object{"coupon": nil, "items": Array{Number(1)}}))
+	assert.False(jm2.Matches(Object{"items": Array{Number(1)}}))
+	assert.False(jm2.Matches(Object{"coupon": nil, "items": Array{}}))
+	assert.False(jm2.Matches(Object{"coupon": nil, "comment": String("c"), "items": Array{Number(1)}}))
+
+	jm3 := MakeExtJSONMatcher(Object{"@type": String("array")})
+	assert.True(jm3.Matches(Array{}))
+	assert.False(jm3.Matches(Object{}))
+
+	jm4 := MakeExtJSONMatcher(Object{"@gt": Number(1), "@lt": Number(3)})
+	assert.True(jm4.Matches(Object{"@gt": Number(1), "@lt": Number(3)}))
+	assert.False(jm4.Matches(Number(2)))
+
+	assert.True(MakeExtJSONMatcher(Object{"@exists": Boolean(false)}).MatchesMissing())
+	assert.True(MakeExtJSONMatcher(Object{"@not": String("a")}).MatchesMissing())
+	assert.False(MakeExtJSONMatcher(Object{"@exists": Boolean(true)}).MatchesMissing())
+	assert.False(MakeExtJSONMatcher(String("a")).MatchesMissing())
+}
+
+func TestExtJSONMatcher_Check(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	assert.Nil(MakeExtJSONMatcher(Object{
+		"a": Object{"@between": Array{Number(1), Number(1)}},
+		"b": Array{Object{"@type": String("object")}},
+		"c": Object{"@not": Object{"@in": Array{Number(1), String("1")}}},
+	}).Check())
+
+	assert.NotNil(MakeExtJSONMatcher(Object{"@gt": String("1")}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@between": Array{Number(5), Number(1)}}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@between": Array{Number(1)}}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@in": Number(1)}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@exists": String("yes")}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@type": String("integer")}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Array{Object{"@size": Object{"@lt": nil}}}).Check())
+
+	err := MakeExtJSONMatcher(Object{"a": Object{"@anyOf": Array{Object{"@not": Object{"@gte": Boolean(true)}}}}}).Check()
+	if assert.NotNil(err) {
+		assert.Equal("invalid argument for operator '@gte': true", err.Error())
+	}
+}
+
+func TestExtJSONMatcher_Matches_arrays(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	jm1 := MakeExtJSONMatcher(Array{nil, Number(2), Number(3)})
+	assert.True(jm1.Matches(Array{Number(1), Number(2), Number(3), Number(4)}))
+	assert.False(jm1.Matches(Array{Number(1), Number(2)}))
+	assert.False(jm1.Matches(Array{}))
+
+	jm2 := MakeExtJSONMatcher(Array{Number(1), Object{"@exists": Boolean(false)}})
+	assert.True(jm2.Matches(Array{Number(1)}))
+	assert.False(jm2.Matches(Array{Number(1), Number(2)}))
+
+	jm3 := MakeExtJSONMatcher(Object{"@unordered": Array{
+		Number(1),
+		Number(2),
+		ExtRegexp(regexp.MustCompile("^[12]$")),
+	}})
+	assert.True(jm3.Matches(Array{Number(2), String("1"), Number(1)}))
+	assert.True(jm3.Matches(Array{String("1"), Number(2), Number(1)}))
+	assert.False(jm3.Matches(Array{Number(1), Number(2), Number(3)}))
+	assert.False(jm3.Matches(Array{Number(1), Number(2), Number(1), Number(1)}))
+	assert.False(jm3.Matches(Array{Number(1), Number(2)}))
+	assert.False(jm3.Matches(String("1,2,1")))
+
+	jm4 := MakeExtJSONMatcher(Object{"ids": Object{"@contains": Array{Number(3), Number(3), Number(1)}}})
+	assert.True(jm4.Matches(Object{"ids": Array{Number(3), Number(2), Number(1), Number(3)}}))
+	assert.False(jm4.Matches(Object{"ids": Array{Number(3), Number(2), Number(1)}}))
+	assert.False(jm4.Matches(Object{}))
+
+	jm5 := MakeExtJSONMatcher(Object{"@every": Object{"id": Object{"@gt": Number(0)}}})
+	assert.True(jm5.Matches(Array{Object{"id": Number(1)}, Object{"id": Number(2)}}))
+	assert.True(jm5.Matches(Array{}))
+	assert.False(jm5.Matches(Array{Object{"id": Number(1)}, Object{"id": Number(0)}}))
+	assert.False(jm5.Matches(Object{"id": Number(1)}))
+
+	assert.NotNil(MakeExtJSONMatcher(Object{"@contains": Number(1)}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@unordered": Array{Object{"@gt": nil}}}).Check())
+	assert.NotNil(MakeExtJSONMatcher(Object{"@every": Object{"@type": String("int")}}).Check())
+}
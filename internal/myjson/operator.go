@@ -0,0 +1,274 @@
+package myjson
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// operators of ExtJSONMatcher, each of which is an object with a single key,
+// keys in the form of '@@name' are unescaped to '@name' when matching objects
+const (
+	opGt      = "@gt"
+	opGte     = "@gte"
+	opLt      = "@lt"
+	opLte     = "@lte"
+	opBetween = "@between"
+	opIn      = "@in"
+	opExists  = "@exists"
+	opType    = "@type"
+	opSize    = "@size"
+	opNot     = "@not"
+	opAnyOf   = "@anyOf"
+
+	opUnordered = "@unordered"
+	opContains  = "@contains"
+	opEvery     = "@every"
+)
+
+var operators = map[string]bool{
+	opGt: true, opGte: true, opLt: true, opLte: true, opBetween: true, opIn: true,
+	opExists: true, opType: true, opSize: true, opNot: true, opAnyOf: true,
+	opUnordered: true, opContains: true, opEvery: true,
+}
+
+// types for the operator @type
+const (
+	typeNull    = "null"
+	typeBoolean = "boolean"
+	typeNumber  = "number"
+	typeString  = "string"
+	typeArray   = "array"
+	typeObject  = "object"
+)
+
+var jsonTypes = map[string]bool{
+	typeNull: true, typeBoolean: true, typeNumber: true,
+	typeString: true, typeArray: true, typeObject: true,
+}
+
+// returns the name and the argument of the operator if the object is one
+func asOperator(o Object) (string, interface{}, bool) {
+	if len(o) != 1 {
+		return "", nil, false
+	}
+	for name, arg := range o {
+		if operators[name] {
+			return name, arg, true
+		}
+	}
+	return "", nil, false
+}
+
+// Check checks arguments of all operators in the matcher
+func (m ExtJSONMatcher) Check() error {
+	return checkOperators(m.v)
+}
+
+func checkOperators(v interface{}) error {
+	switch v.(type) {
+	case Object:
+		o := v.(Object)
+		if name, arg, ok := asOperator(o); ok {
+			return checkOperator(name, arg)
+		}
+		for _, val := range o {
+			if err := checkOperators(val); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for _, val := range v.(Array) {
+			if err := checkOperators(val); err != nil {
+				return err
+			}
+		}
+	case ExtJSONMatcher:
+		return checkOperators(v.(ExtJSONMatcher).v)
+	}
+	return nil
+}
+
+func checkOperator(name string, arg interface{}) error {
+	valid := true
+	switch name {
+	case opGt, opGte, opLt, opLte:
+		_, valid = arg.(Number)
+	case opBetween:
+		a, ok := arg.(Array)
+		valid = ok && len(a) == 2 && IsAllNumber(a) && a[0].(Number) <= a[1].(Number)
+	case opIn, opAnyOf, opUnordered, opContains:
+		var a Array
+		a, valid = arg.(Array)
+		if valid {
+			return checkOperators(a)
+		}
+	case opExists:
+		_, valid = arg.(Boolean)
+	case opType:
+		s, ok := arg.(String)
+		valid = ok && jsonTypes[string(s)]
+	case opSize, opNot, opEvery:
+		return checkOperators(arg)
+	}
+
+	if !valid {
+		bytes, _ := Marshal(arg)
+		return fmt.Errorf("invalid argument for operator '%s': %s", name, bytes)
+	}
+	return nil
+}
+
+// present tells if the value exists, which is false for missing keys of objects
+func (m ExtJSONMatcher) operatorMatches(name string, arg interface{}, v interface{}, present bool) bool {
+	switch name {
+	case opExists:
+		return bool(arg.(Boolean)) == present
+	case opNot:
+		return !m.valueMatches(arg, v, present)
+	case opAnyOf, opIn:
+		for _, a := range arg.(Array) {
+			if m.valueMatches(a, v, present) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !present {
+		return false
+	}
+	switch name {
+	case opGt, opGte, opLt, opLte:
+		n, ok := asNumber(v)
+		return ok && compareNumbers(name, n, float64(arg.(Number)))
+	case opBetween:
+		n, ok := asNumber(v)
+		a := arg.(Array)
+		return ok && n >= float64(a[0].(Number)) && n <= float64(a[1].(Number))
+	case opType:
+		return typeOf(v) == string(arg.(String))
+	case opSize:
+		size, ok := sizeOf(v)
+		return ok && m.matches(arg, Number(size))
+	}
+
+	a, ok := v.(Array)
+	if !ok {
+		return false
+	}
+	switch name {
+	case opUnordered:
+		return len(arg.(Array)) == len(a) && m.distinctMatches(arg.(Array), a)
+	case opContains:
+		return m.distinctMatches(arg.(Array), a)
+	case opEvery:
+		for _, e := range a {
+			if !m.matches(arg, e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// tests if each of the matchers is matched by a distinct element of the array,
+// by finding a maximum bipartite matching with augmenting paths
+func (m ExtJSONMatcher) distinctMatches(mvs Array, a Array) bool {
+	if len(mvs) > len(a) {
+		return false
+	}
+
+	results := make([][]bool, len(mvs))
+	for i, mv := range mvs {
+		results[i] = make([]bool, len(a))
+		for j, e := range a {
+			results[i][j] = m.matches(mv, e)
+		}
+	}
+
+	matchedBy := make([]int, len(a)) // the index of the matcher matched with each element
+	for j := range matchedBy {
+		matchedBy[j] = -1
+	}
+	var augment func(i int, visited []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for j := range a {
+			if results[i][j] && !visited[j] {
+				visited[j] = true
+				if matchedBy[j] < 0 || augment(matchedBy[j], visited) {
+					matchedBy[j] = i
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for i := range mvs {
+		if !augment(i, make([]bool, len(a))) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareNumbers(op string, n float64, arg float64) bool {
+	switch op {
+	case opGt:
+		return n > arg
+	case opGte:
+		return n >= arg
+	case opLt:
+		return n < arg
+	default:
+		return n <= arg
+	}
+}
+
+// numbers in strings are compared as well, e.g. values of params or headers
+func asNumber(v interface{}) (float64, bool) {
+	switch v.(type) {
+	case Number:
+		return float64(v.(Number)), true
+	case String:
+		n, err := strconv.ParseFloat(string(v.(String)), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return typeNull
+	case Boolean:
+		return typeBoolean
+	case Number:
+		return typeNumber
+	case String:
+		return typeString
+	case Array:
+		return typeArray
+	case Object:
+		return typeObject
+	default:
+		return ""
+	}
+}
+
+// sizes of strings are counted in characters
+func sizeOf(v interface{}) (int, bool) {
+	switch v.(type) {
+	case String:
+		return utf8.RuneCountInString(string(v.(String))), true
+	case Array:
+		return len(v.(Array)), true
+	case Object:
+		return len(v.(Object)), true
+	default:
+		return 0, false
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
@@ -15,6 +16,12 @@ type Path struct {
 	paths []interface{}
 }
 
+// segments of paths which may select multiple values
+type (
+	pathWildcard  struct{} // '*', selects all members of an Object or elements of an Array
+	pathRecursive struct{} // '..', selects the value and all its descendants
+)
+
 func NewPath(paths ...interface{}) *Path {
 	var _paths []interface{}
 	for _, p := range paths {
@@ -63,12 +70,22 @@ func (p *Path) String() string {
 	var result strings.Builder
 	result.WriteRune('$')
 
+	afterRecursive := false
 	for _, v := range p.paths {
 		switch v.(type) {
+		case pathRecursive:
+			result.WriteString("..")
+		case pathWildcard:
+			if !afterRecursive {
+				result.WriteRune('.')
+			}
+			result.WriteRune('*')
 		case string:
 			_v := v.(string)
 			if validJavascriptIdentifier.MatchString(_v) {
-				result.WriteRune('.')
+				if !afterRecursive {
+					result.WriteRune('.')
+				}
 				result.WriteString(_v)
 			} else {
 				_v = strconv.Quote(_v)
@@ -82,6 +99,7 @@ func (p *Path) String() string {
 		case int:
 			result.WriteString(fmt.Sprintf("[%d]", v.(int)))
 		}
+		_, afterRecursive = v.(pathRecursive)
 	}
 	return result.String()
 }
@@ -121,7 +139,15 @@ func ParsePath(pathStr string) (*Path, error) {
 
 		switch s {
 		case psReady:
-			if r == '.' {
+			if r == '.' && i+1 < len(runes) && runes[i+1] == '.' { // recursive descent
+				paths = append(paths, pathRecursive{})
+				i += 1
+				if i+1 < len(runes) && runes[i+1] == '[' {
+					s = psReady
+				} else {
+					s = psInKey
+				}
+			} else if r == '.' {
 				s = psInKey
 			} else if r == '[' {
 				s = psMaybeQuotedKeyOrIndex
@@ -137,6 +163,11 @@ func ParsePath(pathStr string) (*Path, error) {
 					i -= 1
 				}
 
+				if temp.String() == "*" {
+					temp.Reset()
+					paths = append(paths, pathWildcard{})
+					break
+				}
 				key, err := toKey(&temp, false)
 				if err != nil {
 					return nil, parseError
@@ -151,6 +182,10 @@ func ParsePath(pathStr string) (*Path, error) {
 				i -= 1
 			} else if r == '\'' {
 				s = psInQuotedKey
+			} else if r == '*' && i+1 < len(runes) && runes[i+1] == ']' {
+				s = psReady
+				i += 1
+				paths = append(paths, pathWildcard{})
 			} else {
 				return nil, parseError
 			}
@@ -286,6 +321,8 @@ func (o Object) toAllPathDs(path *Path) ([]interface{}, error) {
 			default:
 				return nil, errors.New("requires a json object")
 			}
+		case pathWildcard, pathRecursive:
+			return nil, errors.New("cannot set by a path selecting multiple values")
 		default:
 			panic("Shouldn't happen")
 		}
@@ -303,3 +340,74 @@ func anySetEx(d interface{}, k interface{}, v interface{}) interface{} {
 
 	panic("Shouldn't happen")
 }
+
+// Select returns all values selected by the path from the given json in the
+// document order, members of Objects are visited in the order of their keys
+func (p *Path) Select(v interface{}) []interface{} {
+	current := []interface{}{v}
+	for _, seg := range p.paths {
+		var next []interface{}
+		for _, c := range current {
+			if _, ok := seg.(pathRecursive); ok {
+				next = appendDescendants(next, c)
+			} else {
+				next = appendChildren(next, c, seg)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func appendChildren(dst []interface{}, v interface{}, seg interface{}) []interface{} {
+	switch v.(type) {
+	case Object:
+		_v := v.(Object)
+		switch seg.(type) {
+		case string:
+			if _v.Has(seg.(string)) {
+				dst = append(dst, _v.Get(seg.(string)))
+			}
+		case pathWildcard:
+			for _, key := range sortedKeys(_v) {
+				dst = append(dst, _v[key])
+			}
+		}
+	case Array:
+		_v := v.(Array)
+		switch seg.(type) {
+		case int:
+			if _v.Has(seg.(int)) {
+				dst = append(dst, _v.Get(seg.(int)))
+			}
+		case pathWildcard:
+			dst = append(dst, _v...)
+		}
+	}
+	return dst
+}
+
+func appendDescendants(dst []interface{}, v interface{}) []interface{} {
+	dst = append(dst, v)
+	switch v.(type) {
+	case Object:
+		_v := v.(Object)
+		for _, key := range sortedKeys(_v) {
+			dst = appendDescendants(dst, _v[key])
+		}
+	case Array:
+		for _, e := range v.(Array) {
+			dst = appendDescendants(dst, e)
+		}
+	}
+	return dst
+}
+
+func sortedKeys(o Object) []string {
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
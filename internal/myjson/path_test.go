@@ -50,6 +50,61 @@ func TestParsePath(t *testing.T) {
 
 	_, e10 := ParsePath("$[1abc]")
 	assert.NotNil(e10)
+
+	for _, ps := range []string{"$..price", "$.items.*.id", "$..*", "$..[0]", "$.*[1]", "$..items..id"} {
+		p, err := ParsePath(ps)
+		if assert.Nil(err) {
+			assert.Equal(ps, p.String())
+		}
+	}
+
+	p11, e11 := ParsePath("$['items'][*]")
+	assert.Nil(e11)
+	assert.Equal("$.items.*", p11.String())
+
+	_, e12 := ParsePath("$..")
+	assert.NotNil(e12)
+
+	_, e13 := ParsePath("$...a")
+	assert.NotNil(e13)
+
+	_, e14 := ParsePath("$[*")
+	assert.NotNil(e14)
+}
+
+func TestPath_Select(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	v := Object{
+		"items": Array{
+			Object{"id": Number(1), "price": Number(10)},
+			Object{"id": Number(2), "price": Number(20), "extra": Object{"price": Number(5)}},
+		},
+		"price": Number(0),
+	}
+
+	selects := func(ps string) []interface{} {
+		p, err := ParsePath(ps)
+		if !assert.Nil(err) {
+			return nil
+		}
+		return p.Select(v)
+	}
+
+	assert.Equal([]interface{}{Number(1)}, selects("$.items[0].id"))
+	assert.Equal([]interface{}{Number(1), Number(2)}, selects("$.items[*].id"))
+	assert.Equal([]interface{}{Number(0), Number(10), Number(20), Number(5)}, selects("$..price"))
+	assert.Equal([]interface{}{v}, selects("$"))
+	assert.Empty(selects("$.items[2].id"))
+	assert.Empty(selects("$.price.id"))
+	assert.Empty(selects("$.items.id"))
+
+	_, err := Object{}.SetByPath(NewPath("a"), String("b"))
+	assert.Nil(err)
+	p, _ := ParsePath("$..a")
+	_, err = Object{}.SetByPath(p, String("b"))
+	assert.NotNil(err)
 }
 
 func TestObject_SetByPath(t *testing.T) {
@@ -0,0 +1,246 @@
+package myjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+// Token is a JSON Web Token in the compact serialization of JWS, which is
+// 'header.claims.signature' with each part encoded in base64url
+type Token struct {
+	Header myjson.Object
+	Claims myjson.Object
+
+	signingInput string
+	signature    []byte
+}
+
+// Parse decodes the token without verifying its signature
+func Parse(s string) (*Token, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt: malformed token")
+	}
+
+	header, err := decodeObject(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("jwt: malformed header: %v", err)
+	}
+	claims, err := decodeObject(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("jwt: malformed claims: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("jwt: malformed signature: %v", err)
+	}
+
+	return &Token{
+		Header:       header,
+		Claims:       claims,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}, nil
+}
+
+func decodeObject(part string) (myjson.Object, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := myjson.Unmarshal(bytes)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := v.(myjson.Object)
+	if !ok {
+		return nil, errors.New("not an object")
+	}
+	return o, nil
+}
+
+// Sign makes a token of the claims signed with RS256, the key id is put in
+// the header as 'kid' if not empty
+func Sign(claims myjson.Object, key *rsa.PrivateKey, keyID string) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	parts := make([]string, 0, 3)
+	for _, v := range []interface{}{header, claims} {
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, base64.RawURLEncoding.EncodeToString(bytes))
+	}
+	signingInput := strings.Join(parts, ".")
+
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// RSAJWK returns the public key as a JSON Web Key for RS256, which is
+// published in JWKS for clients to verify tokens
+func RSAJWK(key *rsa.PublicKey, keyID string) myjson.Object {
+	e := big.NewInt(int64(key.E)).Bytes()
+	return myjson.Object{
+		"kty": myjson.String("RSA"),
+		"use": myjson.String("sig"),
+		"alg": myjson.String("RS256"),
+		"kid": myjson.String(keyID),
+		"n":   myjson.String(base64.RawURLEncoding.EncodeToString(key.N.Bytes())),
+		"e":   myjson.String(base64.RawURLEncoding.EncodeToString(e)),
+	}
+}
+
+// Alg returns the algorithm in the header, e.g. 'HS256', 'RS256' or 'ES256'
+func (t *Token) Alg() string {
+	alg, _ := t.Header.GetString("alg")
+	return string(alg)
+}
+
+// Verify verifies the signature with the key, which is a []byte for HMAC,
+// a *rsa.PublicKey for RSA or a *ecdsa.PublicKey for ECDSA
+func (t *Token) Verify(key interface{}) error {
+	alg := t.Alg()
+	hash, ok := hashOf(alg)
+	if !ok {
+		return fmt.Errorf("jwt: unsupported algorithm '%s'", alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(t.signingInput))
+	digest := h.Sum(nil)
+
+	var valid bool
+	switch key.(type) {
+	case []byte:
+		if alg[:2] != "HS" {
+			return fmt.Errorf("jwt: cannot verify '%s' with a secret", alg)
+		}
+		mac := hmac.New(hash.New, key.([]byte))
+		mac.Write([]byte(t.signingInput))
+		valid = hmac.Equal(mac.Sum(nil), t.signature)
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), hash, digest, t.signature) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(key.(*rsa.PublicKey), hash, digest, t.signature, nil) == nil
+		default:
+			return fmt.Errorf("jwt: cannot verify '%s' with a RSA key", alg)
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			return fmt.Errorf("jwt: cannot verify '%s' with a ECDSA key", alg)
+		}
+		pub := key.(*ecdsa.PublicKey)
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) == 2*size { // r and s in fixed lengths
+			r := new(big.Int).SetBytes(t.signature[:size])
+			s := new(big.Int).SetBytes(t.signature[size:])
+			valid = ecdsa.Verify(pub, digest, r, s)
+		}
+	default:
+		return errors.New("jwt: unsupported key")
+	}
+
+	if !valid {
+		return errors.New("jwt: invalid signature")
+	}
+	return nil
+}
+
+func hashOf(alg string) (crypto.Hash, bool) {
+	if len(alg) != 5 {
+		return 0, false
+	}
+	switch alg[:2] {
+	case "HS", "RS", "PS", "ES":
+	default:
+		return 0, false
+	}
+
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// Validate checks the time-based claims 'exp' and 'nbf' if present
+func (t *Token) Validate(now time.Time) error {
+	unix := float64(now.Unix())
+	if exp, ok := t.Claims.Get("exp").(myjson.Number); ok && unix >= float64(exp) {
+		return errors.New("jwt: token is expired")
+	}
+	if nbf, ok := t.Claims.Get("nbf").(myjson.Number); ok && unix < float64(nbf) {
+		return errors.New("jwt: token is not valid yet")
+	}
+	return nil
+}
+
+// ParsePublicKey parses a RSA or ECDSA public key in PEM, which is either a
+// 'PUBLIC KEY', a 'RSA PUBLIC KEY' or a 'CERTIFICATE'
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: no PEM block found")
+	}
+
+	var key crypto.PublicKey
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported PEM block '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.New("jwt: only RSA and ECDSA public keys are supported")
+	}
+}
@@ -0,0 +1,187 @@
+package myjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+const hs256Token = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9." +
+	"eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ." +
+	"SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c"
+
+func TestParse(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	t1, err1 := Parse(hs256Token)
+	require.Nil(err1)
+	assert.Equal("HS256", t1.Alg())
+	assert.Equal(myjson.Object{
+		"sub":  myjson.String("1234567890"),
+		"name": myjson.String("John Doe"),
+		"iat":  myjson.Number(1516239022),
+	}, t1.Claims)
+
+	_, err2 := Parse("a.b")
+	assert.NotNil(err2)
+
+	_, err3 := Parse("!.e30.")
+	assert.NotNil(err3)
+
+	_, err4 := Parse("e30.W10.") // claims must be an object
+	assert.NotNil(err4)
+}
+
+func TestToken_Verify(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	t1, err := Parse(hs256Token)
+	require.Nil(err)
+	assert.Nil(t1.Verify([]byte("your-256-bit-secret")))
+	assert.NotNil(t1.Verify([]byte("another-secret")))
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(err)
+	t2 := sign(t, "RS256", func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+	})
+	assert.Nil(t2.Verify(&rsaKey.PublicKey))
+	assert.NotNil(t2.Verify([]byte("your-256-bit-secret")))
+
+	t3 := sign(t, "PS384", func(digest []byte) ([]byte, error) {
+		return rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA384, digest, nil)
+	})
+	assert.Nil(t3.Verify(&rsaKey.PublicKey))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(err)
+	t4 := sign(t, "ES256", func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes() // pads r and s to 32 bytes
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+		return signature, nil
+	})
+	assert.Nil(t4.Verify(&ecKey.PublicKey))
+	assert.NotNil(t4.Verify(&rsaKey.PublicKey))
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(err)
+	assert.NotNil(t4.Verify(&otherKey.PublicKey))
+
+	t5 := sign(t, "none", func([]byte) ([]byte, error) { return nil, nil })
+	assert.NotNil(t5.Verify([]byte("your-256-bit-secret")))
+}
+
+// makes a token with the given algorithm, whose signature is made by signing the SHA digest
+func sign(t *testing.T, alg string, signDigest func(digest []byte) ([]byte, error)) *Token {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"kumasuke120"}`))
+	signingInput := header + "." + claims
+
+	var digest []byte
+	if hash, ok := hashOf(alg); ok {
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		digest = h.Sum(nil)
+	}
+	signature, err := signDigest(digest)
+	require.Nil(t, err)
+
+	token, err := Parse(signingInput + "." + base64.RawURLEncoding.EncodeToString(signature))
+	require.Nil(t, err)
+	return token
+}
+
+func TestToken_Validate(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	now := time.Unix(1600000000, 0)
+	newToken := func(claims myjson.Object) *Token {
+		return &Token{Claims: claims}
+	}
+
+	assert.Nil(newToken(myjson.Object{}).Validate(now))
+	assert.Nil(newToken(myjson.Object{"exp": myjson.Number(1600000001), "nbf": myjson.Number(1600000000)}).Validate(now))
+	assert.NotNil(newToken(myjson.Object{"exp": myjson.Number(1600000000)}).Validate(now))
+	assert.NotNil(newToken(myjson.Object{"nbf": myjson.Number(1600000001)}).Validate(now))
+}
+
+func TestParsePublicKey(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.Nil(err)
+
+	key1, err1 := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if assert.Nil(err1) {
+		assert.Equal(&ecKey.PublicKey, key1)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(err)
+	key2, err2 := ParsePublicKey(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+	}))
+	if assert.Nil(err2) {
+		assert.Equal(&rsaKey.PublicKey, key2)
+	}
+
+	_, err3 := ParsePublicKey([]byte("not a pem"))
+	assert.NotNil(err3)
+
+	_, err4 := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NotNil(err4)
+}
+
+func TestSign(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(err)
+
+	s, err := Sign(myjson.Object{"sub": myjson.String("kumasuke120"), "exp": myjson.Number(1600000000)}, key, "k1")
+	require.Nil(err)
+	token, err := Parse(s)
+	require.Nil(err)
+	assert.Equal("RS256", token.Alg())
+	assert.Equal(myjson.String("k1"), token.Header.Get("kid"))
+	assert.Equal(myjson.Object{"sub": myjson.String("kumasuke120"), "exp": myjson.Number(1600000000)}, token.Claims)
+	assert.Nil(token.Verify(&key.PublicKey))
+
+	jwk := RSAJWK(&key.PublicKey, "k1")
+	assert.Equal(myjson.String("AQAB"), jwk.Get("e"))
+	assert.Equal(myjson.String("k1"), jwk.Get("kid"))
+}
@@ -0,0 +1,79 @@
+package mylog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessRecord describes a served request, one record is emitted for each request
+type AccessRecord struct {
+	RequestID     string  `json:"requestId"`
+	Method        string  `json:"method"`
+	URL           string  `json:"url"`
+	RemoteAddr    string  `json:"remoteAddr,omitempty"`
+	Mapping       string  `json:"mapping,omitempty"`
+	Policy        *int    `json:"policy,omitempty"`
+	Command       string  `json:"command,omitempty"`
+	Status        int     `json:"status"`
+	Bytes         int64   `json:"bytes"`
+	Duration      float64 `json:"durationMs"`
+	Latency       float64 `json:"latencyMs"`
+	ForwardTarget string  `json:"forwardTarget,omitempty"`
+	RequestBody   string  `json:"requestBody,omitempty"`
+	ResponseBody  string  `json:"responseBody,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+type jsonAccessRecord struct {
+	Time   string `json:"time"`
+	Level  string `json:"level"`
+	Logger string `json:"logger"`
+	*AccessRecord
+}
+
+var accessLogger = New("executor")
+
+// Access outputs the given record in the configured format at the info level
+func Access(rec *AccessRecord) {
+	if !Enabled(LevelInfo) {
+		return
+	}
+
+	_, f := getConf()
+	switch f {
+	case FormatJSON:
+		writeJSON(&jsonAccessRecord{
+			Time:         now(),
+			Level:        LevelInfo.String(),
+			Logger:       "access",
+			AccessRecord: rec,
+		})
+	default:
+		accessLogger.write(LevelInfo, rec.text())
+	}
+}
+
+func (rec *AccessRecord) text() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-9s: (%d) %s %s", rec.Command, rec.Status, rec.Method, rec.URL))
+	if rec.ForwardTarget != "" {
+		b.WriteString(" => ")
+		b.WriteString(rec.ForwardTarget)
+	}
+	if rec.RequestBody != "" {
+		b.WriteString(fmt.Sprintf(", request body = %q", rec.RequestBody))
+	}
+	if rec.ResponseBody != "" {
+		b.WriteString(fmt.Sprintf(", response body = %q", rec.ResponseBody))
+	}
+	return b.String()
+}
+
+// Truncate cuts the given body to the configured body limit
+func Truncate(body []byte) string {
+	limit := BodyLimit()
+	if len(body) > limit {
+		body = body[:limit]
+	}
+	return string(body)
+}
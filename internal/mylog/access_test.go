@@ -0,0 +1,60 @@
+package mylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccess(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	defer SetOutput(log.Writer())
+	SetOutput(&buf)
+	defer Configure(LevelInfo, FormatText, 0)
+
+	policy := 1
+	rec := &AccessRecord{
+		RequestID:     "abc",
+		Method:        "GET",
+		URL:           "/hello?a=1",
+		Mapping:       "/hello",
+		Policy:        &policy,
+		Command:       "forwards",
+		Status:        200,
+		Bytes:         5,
+		ForwardTarget: "http://localhost:8080/hello?a=1",
+		ResponseBody:  "hello",
+	}
+
+	Configure(LevelInfo, FormatText, 0)
+	Access(rec)
+	assert.Contains(buf.String(),
+		"[executor] forwards : (200) GET /hello?a=1 => http://localhost:8080/hello?a=1, "+
+			"response body = \"hello\"\n")
+
+	buf.Reset()
+	Configure(LevelInfo, FormatJSON, 0)
+	Access(rec)
+	var record map[string]interface{}
+	if assert.Nil(json.Unmarshal(buf.Bytes(), &record)) {
+		assert.Equal("info", record["level"])
+		assert.Equal("access", record["logger"])
+		assert.Equal("abc", record["requestId"])
+		assert.Equal("/hello", record["mapping"])
+		assert.Equal(float64(1), record["policy"])
+		assert.Equal(float64(200), record["status"])
+		assert.Equal("hello", record["responseBody"])
+		assert.NotContains(record, "requestBody")
+	}
+
+	buf.Reset()
+	Configure(LevelWarn, FormatJSON, 0)
+	Access(rec)
+	assert.Empty(buf.String())
+}
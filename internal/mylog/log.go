@@ -0,0 +1,180 @@
+package mylog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(name, s) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, errors.New("unknown log level: " + s)
+}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+type Format string
+
+const (
+	FormatText = Format("text")
+	FormatJSON = Format("json")
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatText, errors.New("unknown log format: " + s)
+}
+
+var (
+	confMux   sync.RWMutex
+	level     = LevelInfo
+	format    = FormatText
+	bodyLimit = 0
+)
+
+// Configure sets the minimum level of logs to output, the output format and the
+// maximum number of bytes of request/response bodies recorded in access logs
+func Configure(l Level, f Format, limit int) {
+	confMux.Lock()
+	defer confMux.Unlock()
+
+	level = l
+	format = f
+	if limit < 0 {
+		limit = 0
+	}
+	bodyLimit = limit
+}
+
+func SetOutput(w io.Writer) {
+	log.SetOutput(w)
+}
+
+func getConf() (Level, Format) {
+	confMux.RLock()
+	defer confMux.RUnlock()
+	return level, format
+}
+
+func Enabled(l Level) bool {
+	minLevel, _ := getConf()
+	return l >= minLevel
+}
+
+// BodyLimit returns the maximum number of bytes of bodies recorded in access logs,
+// 0 means bodies are not recorded
+func BodyLimit() int {
+	confMux.RLock()
+	defer confMux.RUnlock()
+	return bodyLimit
+}
+
+type Logger struct {
+	tag string
+}
+
+// New returns a logger whose text output is prefixed by the given tag, e.g. "[server  ]"
+func New(tag string) *Logger {
+	return &Logger{tag: tag}
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.output(LevelDebug, format, v...)
+}
+
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.output(LevelInfo, format, v...)
+}
+
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.output(LevelWarn, format, v...)
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.output(LevelError, format, v...)
+}
+
+// Fatalf outputs the message regardless of the level and then exits the program
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.write(LevelError, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func (l *Logger) output(lv Level, format string, v ...interface{}) {
+	if Enabled(lv) {
+		l.write(lv, fmt.Sprintf(format, v...))
+	}
+}
+
+type jsonRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Logger  string `json:"logger"`
+	Message string `json:"message"`
+}
+
+func (l *Logger) write(lv Level, msg string) {
+	msg = strings.TrimSuffix(msg, "\n")
+
+	_, f := getConf()
+	switch f {
+	case FormatJSON:
+		writeJSON(&jsonRecord{
+			Time:    now(),
+			Level:   lv.String(),
+			Logger:  l.tag,
+			Message: msg,
+		})
+	default:
+		log.Printf("[%-8s] %s\n", l.tag, msg)
+	}
+}
+
+func now() string {
+	return time.Now().Format(time.RFC3339Nano)
+}
+
+var writeMux sync.Mutex
+
+func writeJSON(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Println("[log     ] cannot marshal log record:", err)
+		return
+	}
+
+	writeMux.Lock()
+	defer writeMux.Unlock()
+	_, _ = log.Writer().Write(append(b, '\n'))
+}
@@ -0,0 +1,88 @@
+package mylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	l1, e1 := ParseLevel("DEBUG")
+	if assert.Nil(e1) {
+		assert.Equal(LevelDebug, l1)
+	}
+	l2, e2 := ParseLevel("warn")
+	if assert.Nil(e2) {
+		assert.Equal(LevelWarn, l2)
+	}
+	_, e3 := ParseLevel("verbose")
+	assert.NotNil(e3)
+
+	assert.Equal("error", LevelError.String())
+	assert.Equal("Level(10)", Level(10).String())
+}
+
+func TestParseFormat(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	f1, e1 := ParseFormat("JSON")
+	if assert.Nil(e1) {
+		assert.Equal(FormatJSON, f1)
+	}
+	f2, e2 := ParseFormat("text")
+	if assert.Nil(e2) {
+		assert.Equal(FormatText, f2)
+	}
+	_, e3 := ParseFormat("xml")
+	assert.NotNil(e3)
+}
+
+func TestLogger(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	defer SetOutput(log.Writer())
+	SetOutput(&buf)
+	defer Configure(LevelInfo, FormatText, 0)
+
+	l := New("test")
+
+	Configure(LevelWarn, FormatText, 0)
+	l.Infof("info %d", 1)
+	assert.Empty(buf.String())
+	l.Warnf("warn %d", 2)
+	assert.Contains(buf.String(), "[test    ] warn 2\n")
+
+	buf.Reset()
+	Configure(LevelDebug, FormatJSON, 0)
+	l.Debugf("debug %d", 3)
+	var record map[string]interface{}
+	if assert.Nil(json.Unmarshal(buf.Bytes(), &record)) {
+		assert.Equal("debug", record["level"])
+		assert.Equal("test", record["logger"])
+		assert.Equal("debug 3", record["message"])
+		assert.NotEmpty(record["time"])
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	defer Configure(LevelInfo, FormatText, 0)
+
+	Configure(LevelInfo, FormatText, -1)
+	assert.Equal(0, BodyLimit())
+	Configure(LevelInfo, FormatText, 4)
+	assert.Equal(4, BodyLimit())
+	assert.Equal("abcd", Truncate([]byte("abcdef")))
+	assert.Equal("ab", Truncate([]byte("ab")))
+}
@@ -0,0 +1,108 @@
+package myxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Element is an element of a XML document, which is compared structurally:
+// namespaces are resolved, the order of attributes, comments, processing
+// instructions and whitespaces around texts are ignored
+type Element struct {
+	Name     xml.Name
+	Attrs    map[xml.Name]string
+	Text     string // texts directly in the element, trimmed and joined
+	Children []*Element
+}
+
+// Parse parses the XML document and returns its root element
+func Parse(data []byte) (*Element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var root *Element
+	var stack []*Element
+	var texts [][]string // texts of elements in the stack
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t.(type) {
+		case xml.StartElement:
+			st := t.(xml.StartElement)
+			e := &Element{Name: st.Name, Attrs: make(map[xml.Name]string)}
+			for _, attr := range st.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue // declarations of namespaces, which are resolved in names
+				}
+				e.Attrs[attr.Name] = attr.Value
+			}
+
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("xml: multiple root elements")
+				}
+				root = e
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			}
+			stack = append(stack, e)
+			texts = append(texts, nil)
+		case xml.EndElement:
+			last := len(stack) - 1
+			stack[last].Text = strings.Join(texts[last], " ")
+			stack, texts = stack[:last], texts[:last]
+		case xml.CharData:
+			text := strings.TrimSpace(string(t.(xml.CharData)))
+			if text == "" {
+				continue
+			}
+			if len(stack) == 0 {
+				return nil, errors.New("xml: text outside of the root element")
+			}
+			texts[len(texts)-1] = append(texts[len(texts)-1], text)
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	return root, nil
+}
+
+// Equal tests if two elements are structurally equal
+func (e *Element) Equal(o *Element) bool {
+	if e == nil || o == nil {
+		return e == o
+	}
+
+	if e.Name != o.Name || e.Text != o.Text {
+		return false
+	}
+
+	if len(e.Attrs) != len(o.Attrs) {
+		return false
+	}
+	for name, value := range e.Attrs {
+		if oValue, ok := o.Attrs[name]; !ok || oValue != value {
+			return false
+		}
+	}
+
+	if len(e.Children) != len(o.Children) {
+		return false
+	}
+	for idx, child := range e.Children {
+		if !child.Equal(o.Children[idx]) {
+			return false
+		}
+	}
+	return true
+}
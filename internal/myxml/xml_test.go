@@ -0,0 +1,79 @@
+package myxml
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	e1, err1 := Parse([]byte(`<?xml version="1.0"?>
+<!-- books -->
+<b:books xmlns:b="urn:books" count="2">
+  <b:book id="1">Go <i>in</i> Action</b:book>
+  <b:book id="2"/>
+</b:books>`))
+	require.Nil(err1)
+	assert.Equal(xml.Name{Space: "urn:books", Local: "books"}, e1.Name)
+	assert.Equal(map[xml.Name]string{{Local: "count"}: "2"}, e1.Attrs)
+	assert.Equal("", e1.Text)
+	if assert.Len(e1.Children, 2) {
+		assert.Equal("Go Action", e1.Children[0].Text)
+		assert.Equal("1", e1.Children[0].Attrs[xml.Name{Local: "id"}])
+		if assert.Len(e1.Children[0].Children, 1) {
+			assert.Equal("in", e1.Children[0].Children[0].Text)
+		}
+		assert.Empty(e1.Children[1].Children)
+	}
+
+	_, err2 := Parse([]byte(`<a></b>`))
+	assert.NotNil(err2)
+
+	_, err3 := Parse([]byte(`<a/><b/>`))
+	assert.NotNil(err3)
+
+	_, err4 := Parse([]byte(`text<a/>`))
+	assert.NotNil(err4)
+
+	_, err5 := Parse([]byte(`  `))
+	assert.NotNil(err5)
+}
+
+func TestElement_Equal(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	parse := func(s string) *Element {
+		e, err := Parse([]byte(s))
+		if err != nil {
+			panic(err)
+		}
+		return e
+	}
+
+	e := parse(`<a x="1" y="2"><b>text</b><c/></a>`)
+	assert.True(e.Equal(parse("<a y=\"2\" x=\"1\">\n  <b> text </b>\n  <c></c>\n</a>")))
+	assert.True(e.Equal(parse(`<a x="1" y="2"><!-- comment --><b>text</b><c/></a>`)))
+	assert.False(e.Equal(parse(`<a x="1"><b>text</b><c/></a>`)))
+	assert.False(e.Equal(parse(`<a x="1" y="3"><b>text</b><c/></a>`)))
+	assert.False(e.Equal(parse(`<a x="1" y="2"><c/><b>text</b></a>`)))
+	assert.False(e.Equal(parse(`<a x="1" y="2"><b>other</b><c/></a>`)))
+	assert.False(e.Equal(parse(`<a x="1" y="2"><b>text</b></a>`)))
+
+	ns := parse(`<n:a xmlns:n="urn:a"/>`)
+	assert.True(ns.Equal(parse(`<m:a xmlns:m="urn:a"/>`)))
+	assert.True(ns.Equal(parse(`<a xmlns="urn:a"/>`)))
+	assert.False(ns.Equal(parse(`<a/>`)))
+
+	var nilElement *Element
+	assert.True(nilElement.Equal(nil))
+	assert.False(nilElement.Equal(e))
+	assert.False(e.Equal(nil))
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/metrics"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+)
+
+// paths of admin endpoints, relative to the admin path
+const (
+	adminPathInspector      = "/"
+	adminPathMetrics        = "/metrics"
+	adminPathMappings       = "/mappings"
+	adminPathRequests       = "/requests"
+	adminPathRequestsStream = "/requests/stream"
+)
+
+// serves admin endpoints under the admin path, passes other requests to the next handler
+type adminHandler struct {
+	path      string
+	mappings  *mckmaps.MockuMappings
+	inspector *inspector
+	next      http.Handler
+}
+
+func newAdminHandler(options *mckmaps.AdminOptions, h *mockHandler, next http.Handler) http.Handler {
+	handlerLog.Infof("enabled  : admin handler, path = %s", options.Path)
+	return &adminHandler{
+		path:      options.Path,
+		mappings:  h.mappings,
+		inspector: h.inspector,
+		next:      next,
+	}
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == h.path {
+		http.Redirect(w, r, h.path+adminPathInspector, http.StatusFound)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, h.path+"/") {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set(myhttp.HeaderServer, HeaderValueServer)
+	switch path := r.URL.Path[len(h.path):]; path {
+	case adminPathInspector:
+		h.serveInspector(w, r)
+	case adminPathMetrics:
+		h.serveMetrics(w, r)
+	case adminPathMappings:
+		h.serveMappings(w, r)
+	case adminPathRequests:
+		h.serveRequests(w, r)
+	case adminPathRequestsStream:
+		h.serveRequestsStream(w, r)
+	default:
+		if strings.HasPrefix(path, adminPathRequests+"/") {
+			h.serveRequest(w, r, path[len(adminPathRequests)+1:])
+		} else {
+			h.writePolicy(w, r, pNotFound)
+		}
+	}
+}
+
+func (h *adminHandler) serveInspector(w http.ResponseWriter, r *http.Request) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	w.Header().Set(myhttp.HeaderContentType, myhttp.ContentTypeHTML)
+	if _, err := w.Write([]byte(inspectorPage)); err != nil {
+		handlerLog.Errorf("error    : fail to write inspector page: %v", err)
+	}
+}
+
+func (h *adminHandler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	w.Header().Set(myhttp.HeaderContentType, metrics.ContentType)
+	if err := metrics.Default.WriteText(w); err != nil {
+		handlerLog.Errorf("error    : fail to write metrics: %v", err)
+	}
+}
+
+func (h *adminHandler) serveMappings(w http.ResponseWriter, r *http.Request) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	h.writeJSON(w, r, listMappedURIs(h.mappings))
+}
+
+func (h *adminHandler) serveRequests(w http.ResponseWriter, r *http.Request) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	h.writeJSON(w, r, h.inspector.recent())
+}
+
+func (h *adminHandler) serveRequest(w http.ResponseWriter, r *http.Request, seq string) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	_seq, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		h.writePolicy(w, r, pNotFound)
+		return
+	}
+	ins := h.inspector.get(_seq)
+	if ins == nil {
+		h.writePolicy(w, r, pNotFound)
+		return
+	}
+
+	h.writeJSON(w, r, ins)
+}
+
+// streams summaries of incoming requests as server-sent events
+func (h *adminHandler) serveRequestsStream(w http.ResponseWriter, r *http.Request) {
+	if !h.methodAllowed(w, r, myhttp.MethodGet) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writePolicy(w, r, pInternalServerError)
+		return
+	}
+
+	s := h.inspector.subscribe()
+	defer h.inspector.unsubscribe(s)
+
+	w.Header().Set(myhttp.HeaderContentType, myhttp.ContentTypeEventStream)
+	w.Header().Set(myhttp.HeaderCacheControl, "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done: // sends the pending requests before disconnecting
+			for len(s.ch) != 0 {
+				if !writeInspectionEvent(w, <-s.ch) {
+					break
+				}
+			}
+			flusher.Flush()
+			return
+		case ins := <-s.ch:
+			if !writeInspectionEvent(w, ins) {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeInspectionEvent(w http.ResponseWriter, ins *inspection) bool {
+	data, err := json.Marshal(ins)
+	if err != nil {
+		handlerLog.Errorf("error    : fail to encode request #%d: %v", ins.Seq, err)
+		return true
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: request\ndata: %s\n\n", ins.Seq, data)
+	return err == nil
+}
+
+func (h *adminHandler) methodAllowed(w http.ResponseWriter, r *http.Request, method myhttp.HTTPMethod) bool {
+	if myhttp.ToHTTPMethod(r.Method) != method {
+		h.writePolicy(w, r, pMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func (h *adminHandler) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		handlerLog.Errorf("error    : %s %s => fail to encode response: %v", r.Method, r.URL, err)
+		h.writePolicy(w, r, pInternalServerError)
+		return
+	}
+
+	w.Header().Set(myhttp.HeaderContentType, myhttp.ContentTypeJSON)
+	if _, err := w.Write(data); err != nil {
+		handlerLog.Errorf("error    : %s %s => fail to write response: %v", r.Method, r.URL, err)
+	}
+}
+
+func (h *adminHandler) writePolicy(w http.ResponseWriter, r *http.Request, policy *mckmaps.Policy) {
+	executor := &policyExecutor{r: r, w: &w, policy: policy, policyIdx: -1}
+	if err := executor.execute(); err != nil {
+		handlerLog.Errorf("error    : %s %s => fail to render response: %v", r.Method, r.URL, err)
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/metrics"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+var mappingsWithAdmin = &mckmaps.MockuMappings{
+	Mappings: mappings.Mappings,
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{
+			Enabled: false,
+		},
+		Admin: &mckmaps.AdminOptions{
+			Enabled: true,
+			Path:    "/__admin",
+		},
+	},
+}
+
+func TestAdminHandler_ServeHTTP(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(mappingsWithAdmin)
+	_, ok := handler.(*adminHandler)
+	assert.True(ok)
+
+	req1 := httptest.NewRequest("POST", "/hello", nil)
+	rr1 := httptest.NewRecorder()
+	handler.ServeHTTP(rr1, req1)
+	assert.Equal(http.StatusOK, rr1.Code)
+
+	req2 := httptest.NewRequest("GET", "/__admin/metrics", nil)
+	rr2 := httptest.NewRecorder()
+	handler.ServeHTTP(rr2, req2)
+	assert.Equal(http.StatusOK, rr2.Code)
+	assert.Equal(metrics.ContentType, rr2.Header().Get("Content-Type"))
+	assert.Contains(rr2.Body.String(), `mockuma_requests_total{uri="/hello",method="POST",policy="0",status="200"}`)
+
+	req3 := httptest.NewRequest("POST", "/__admin/metrics", nil)
+	rr3 := httptest.NewRecorder()
+	handler.ServeHTTP(rr3, req3)
+	assert.Equal(http.StatusMethodNotAllowed, rr3.Code)
+
+	req4 := httptest.NewRequest("GET", "/__admin/notfound", nil)
+	rr4 := httptest.NewRecorder()
+	handler.ServeHTTP(rr4, req4)
+	assert.Equal(http.StatusNotFound, rr4.Code)
+}
+
+func TestAdminHandler_ServeHTTP_injectedLatency(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	mappingsWithLatency := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/TestAdminHandler_ServeHTTP_injectedLatency",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{
+							StatusCode: myhttp.StatusOK,
+							Body:       []byte(""),
+							Latency:    &mckmaps.Interval{Min: 1, Max: 1},
+						},
+					},
+				},
+			},
+		},
+		Config: mappingsWithAdmin.Config,
+	}
+	handler := newMockHandler(mappingsWithLatency)
+
+	req1 := httptest.NewRequest("GET", "/TestAdminHandler_ServeHTTP_injectedLatency", nil)
+	rr1 := httptest.NewRecorder()
+	handler.ServeHTTP(rr1, req1)
+	assert.Equal(http.StatusOK, rr1.Code)
+
+	req2 := httptest.NewRequest("GET", "/__admin/metrics", nil)
+	rr2 := httptest.NewRecorder()
+	handler.ServeHTTP(rr2, req2)
+	assert.Equal(http.StatusOK, rr2.Code)
+	assert.Contains(rr2.Body.String(),
+		`mockuma_injected_latency_seconds_count{uri="/TestAdminHandler_ServeHTTP_injectedLatency",method="GET"} 1`)
+}
+
+func TestAdminHandler_inspector(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(mappingsWithAdmin)
+
+	req1 := httptest.NewRequest("GET", "/__admin", nil)
+	rr1 := httptest.NewRecorder()
+	handler.ServeHTTP(rr1, req1)
+	assert.Equal(http.StatusFound, rr1.Code)
+	assert.Equal("/__admin/", rr1.Header().Get("Location"))
+
+	req2 := httptest.NewRequest("GET", "/__admin/", nil)
+	rr2 := httptest.NewRecorder()
+	handler.ServeHTTP(rr2, req2)
+	assert.Equal(http.StatusOK, rr2.Code)
+	assert.Equal(myhttp.ContentTypeHTML, rr2.Header().Get("Content-Type"))
+	assert.Contains(rr2.Body.String(), "MocKuma Inspector")
+
+	req3 := httptest.NewRequest("GET", "/__admin/mappings", nil)
+	rr3 := httptest.NewRecorder()
+	handler.ServeHTTP(rr3, req3)
+	assert.Equal(http.StatusOK, rr3.Code)
+	var mapped []*mappedURI
+	if assert.Nil(json.Unmarshal(rr3.Body.Bytes(), &mapped)) {
+		assert.Equal(listMappedURIs(mappingsWithAdmin), mapped)
+	}
+
+	req4 := httptest.NewRequest("POST", "/hello", strings.NewReader("TestAdminHandler_inspector"))
+	rr4 := httptest.NewRecorder()
+	handler.ServeHTTP(rr4, req4)
+	assert.Equal(http.StatusOK, rr4.Code)
+
+	req5 := httptest.NewRequest("GET", "/__admin/requests", nil)
+	rr5 := httptest.NewRecorder()
+	handler.ServeHTTP(rr5, req5)
+	assert.Equal(http.StatusOK, rr5.Code)
+	var recent []*inspection
+	var seq uint64
+	if assert.Nil(json.Unmarshal(rr5.Body.Bytes(), &recent)) && assert.NotEmpty(recent) {
+		last := recent[len(recent)-1]
+		assert.Equal("/hello", last.URL)
+		assert.Equal("/hello", last.Mapping)
+		assert.Nil(last.Request)
+		seq = last.Seq
+	}
+
+	req6 := httptest.NewRequest("GET", "/__admin/requests/"+strconv.FormatUint(seq, 10), nil)
+	rr6 := httptest.NewRecorder()
+	handler.ServeHTTP(rr6, req6)
+	assert.Equal(http.StatusOK, rr6.Code)
+	var detail *inspection
+	if assert.Nil(json.Unmarshal(rr6.Body.Bytes(), &detail)) && assert.NotNil(detail.Request) {
+		assert.Equal("TestAdminHandler_inspector", detail.Request.Body)
+		assert.Equal(rr4.Body.String(), detail.Response.Body)
+	}
+
+	req7 := httptest.NewRequest("GET", "/__admin/requests/abc", nil)
+	rr7 := httptest.NewRecorder()
+	handler.ServeHTTP(rr7, req7)
+	assert.Equal(http.StatusNotFound, rr7.Code)
+}
+
+func TestAdminHandler_serveRequestsStream(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	h := &mockHandler{mappings: mappingsWithAdmin, inspector: newInspector(10)}
+	h.pathMatcher = newPathMatcher(mappingsWithAdmin)
+	handler := newAdminHandler(mappingsWithAdmin.Config.Admin, h, h)
+
+	req1 := httptest.NewRequest("GET", "/__admin/requests/stream", nil)
+	rr1 := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rr1, req1)
+		close(done)
+	}()
+
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		h.inspector.mux.Lock()
+		subscribed = len(h.inspector.subscribers) != 0
+		h.inspector.mux.Unlock()
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/hello", nil))
+	h.inspector.disconnectAll()
+	<-done
+
+	assert.Equal(http.StatusOK, rr1.Code)
+	assert.Equal(myhttp.ContentTypeEventStream, rr1.Header().Get("Content-Type"))
+	body := rr1.Body.String()
+	assert.True(strings.HasPrefix(body, "id: 1\nevent: request\ndata: {"))
+	assert.Contains(body, `"url":"/hello"`)
+}
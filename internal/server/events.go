@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+)
+
+var errFlushNotSupported = errors.New("events are not supported by the response writer")
+
+// streams the events as server-sent events, flushing after each event; the
+// stream ends normally when the client disconnects or the server shuts down
+func (e *policyExecutor) executeEvents() error {
+	events := e.policy.Events
+	returns := events.Returns
+
+	if returns.Latency != nil {
+		e.latency += waitBeforeReturns(returns.Latency)
+	}
+
+	flusher, ok := (*e.w).(http.Flusher)
+	if !ok {
+		return errFlushNotSupported
+	}
+
+	e.writeHeaders(returns.Headers)
+	e.writeCookies(returns.Cookies)
+	header := (*e.w).Header()
+	if header.Get(myhttp.HeaderContentType) == "" {
+		header.Set(myhttp.HeaderContentType, myhttp.ContentTypeEventStream)
+	}
+	if header.Get(myhttp.HeaderCacheControl) == "" {
+		header.Set(myhttp.HeaderCacheControl, "no-cache")
+	}
+	(*e.w).WriteHeader(int(returns.StatusCode))
+	flusher.Flush()
+
+	if e.returnHead {
+		return nil
+	}
+
+	ctx := e.r.Context()
+	for round := 0; events.Repeat == 0 || round < events.Repeat; round++ {
+		for _, event := range events.Events {
+			if event.Delay != nil {
+				if err := sleepWithContext(ctx, randomDuration(event.Delay)); err != nil {
+					return nil
+				}
+			}
+
+			if _, err := (*e.w).Write(formatEvent(event)); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// formats the event in the wire format of server-sent events
+func formatEvent(event *mckmaps.Event) []byte {
+	var buf bytes.Buffer
+	if event.ID != "" {
+		buf.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Event != "" {
+		buf.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		buf.WriteString("retry: " + strconv.Itoa(event.Retry) + "\n")
+	}
+	// each line of the data is sent as a separate field
+	for _, line := range strings.Split(event.Data, "\n") {
+		buf.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
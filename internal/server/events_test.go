@@ -0,0 +1,100 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEvent(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	e1 := &mckmaps.Event{ID: "1", Event: "tick", Data: "hello", Retry: 3000}
+	assert.Equal("id: 1\nevent: tick\nretry: 3000\ndata: hello\n\n", string(formatEvent(e1)))
+
+	e2 := &mckmaps.Event{Data: "line1\r\nline2"}
+	assert.Equal("data: line1\ndata: line2\n\n", string(formatEvent(e2)))
+
+	e3 := &mckmaps.Event{}
+	assert.Equal("data: \n\n", string(formatEvent(e3)))
+}
+
+var mappingsWithEvents = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		{
+			URI:    "/events",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeEvents,
+					Events: &mckmaps.Events{
+						Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK},
+						Events: []*mckmaps.Event{
+							{ID: "1", Data: "a"},
+							{ID: "2", Event: "tick", Data: "b", Delay: &mckmaps.Interval{Min: 20, Max: 20}},
+						},
+						Repeat: 2,
+					},
+				},
+			},
+		},
+		{
+			URI:    "/forever",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeEvents,
+					Events: &mckmaps.Events{
+						Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK},
+						Events: []*mckmaps.Event{
+							{Data: "ping", Delay: &mckmaps.Interval{Min: 10, Max: 10}},
+						},
+					},
+				},
+			},
+		},
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestPolicyExecutor_executeEvents(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	s := httptest.NewServer(newMockHandler(mappingsWithEvents))
+	defer s.Close()
+
+	start1 := time.Now()
+	resp1, err1 := http.Get(s.URL + "/events")
+	if assert.Nil(err1) {
+		assert.Equal(http.StatusOK, resp1.StatusCode)
+		assert.Equal(myhttp.ContentTypeEventStream, resp1.Header.Get(myhttp.HeaderContentType))
+		assert.Equal("no-cache", resp1.Header.Get(myhttp.HeaderCacheControl))
+		body1, err1 := ioutil.ReadAll(resp1.Body)
+		if assert.Nil(err1) {
+			expected := "id: 1\ndata: a\n\nid: 2\nevent: tick\ndata: b\n\n"
+			assert.Equal(expected+expected, string(body1))
+		}
+		_ = resp1.Body.Close()
+		assert.True(time.Since(start1) >= 40*time.Millisecond)
+	}
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	resp2, err2 := client.Get(s.URL + "/forever")
+	if assert.Nil(err2) {
+		assert.Equal(http.StatusOK, resp2.StatusCode)
+		body2, err2 := ioutil.ReadAll(resp2.Body)
+		assert.NotNil(err2) // the stream never ends
+		assert.Contains(string(body2), "data: ping\n\n")
+		_ = resp2.Body.Close()
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/mylog"
+)
+
+// a request and its response, recorded after the request has been served
+type exchange struct {
+	id      string
+	start   time.Time
+	elapsed time.Duration
+
+	r        *http.Request
+	reqBody  []byte
+	w        *recordingWriter
+	e        *policyExecutor // nil if served by a built-in endpoint
+	builtin  string          // the built-in handler serving the request, e.g. 'oauth2'
+	endpoint string          // the path of the built-in endpoint
+	err      error
+}
+
+// bodies of the request and the response are kept if bodyLimit is positive
+func newExchange(w http.ResponseWriter, r *http.Request, bodyLimit int) *exchange {
+	x := &exchange{id: newRequestID(r), start: time.Now(), r: r}
+
+	if bodyLimit > 0 && r.Body != nil { // reads the body in advance for logging
+		body, err := ioutil.ReadAll(r.Body)
+		if err == nil {
+			x.reqBody = body
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	x.w = newRecordingWriter(w)
+	x.w.bodyLimit = bodyLimit
+	return x
+}
+
+// uses the request id given by the client if any
+func newRequestID(r *http.Request) string {
+	if id := r.Header.Get(myhttp.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// records metrics and access logs for the exchange
+func (x *exchange) finish() {
+	x.elapsed = time.Since(x.start)
+
+	recordMetrics(x)
+	mylog.Access(x.toAccessRecord())
+}
+
+func (x *exchange) toAccessRecord() *mylog.AccessRecord {
+	rec := &mylog.AccessRecord{
+		RequestID:  x.id,
+		Method:     x.r.Method,
+		URL:        x.r.URL.String(),
+		RemoteAddr: x.r.RemoteAddr,
+		Status:     x.w.status(),
+		Bytes:      x.w.written,
+		Duration:   toMilliseconds(x.elapsed),
+	}
+
+	if e := x.e; e != nil {
+		if e.mapping != nil {
+			rec.Mapping = e.mapping.URI
+		}
+		if e.policyIdx >= 0 {
+			policyIdx := e.policyIdx
+			rec.Policy = &policyIdx
+		}
+		if e.policy != nil {
+			rec.Command = string(e.policy.CmdType)
+		}
+		rec.Latency = toMilliseconds(e.latency)
+		rec.ForwardTarget = e.forwardTarget
+	} else {
+		rec.Mapping = x.endpoint
+		rec.Command = x.builtin
+	}
+
+	if len(x.reqBody) != 0 {
+		rec.RequestBody = mylog.Truncate(x.reqBody)
+	}
+	if len(x.w.body) != 0 {
+		rec.ResponseBody = mylog.Truncate(x.w.body)
+	}
+	if x.err != nil {
+		rec.Error = x.err.Error()
+	}
+	return rec
+}
+
+func toMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
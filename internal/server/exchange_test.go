@@ -0,0 +1,64 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/mylog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequestID(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	r1 := httptest.NewRequest("GET", "/", nil)
+	id1 := newRequestID(r1)
+	assert.Len(id1, 16)
+	assert.NotEqual(id1, newRequestID(r1))
+
+	r2 := httptest.NewRequest("GET", "/", nil)
+	r2.Header.Set(myhttp.HeaderXRequestID, "given-id")
+	assert.Equal("given-id", newRequestID(r2))
+}
+
+func TestExchange_toAccessRecord(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	defer mylog.Configure(mylog.LevelInfo, mylog.FormatText, 0)
+	mylog.Configure(mylog.LevelInfo, mylog.FormatText, 3)
+
+	r := httptest.NewRequest("POST", "/hello", strings.NewReader("name=Mike"))
+	x := newExchange(httptest.NewRecorder(), r, mylog.BodyLimit())
+	body, err := ioutil.ReadAll(r.Body)
+	if assert.Nil(err) {
+		assert.Equal("name=Mike", string(body))
+	}
+
+	x.e = &policyExecutor{
+		r:         r,
+		mapping:   mappings.Mappings[0],
+		policy:    mappings.Mappings[0].Policies[0],
+		policyIdx: 0,
+	}
+	_, _ = x.w.Write([]byte("Hello"))
+	x.finish()
+
+	rec := x.toAccessRecord()
+	assert.Equal(x.id, rec.RequestID)
+	assert.Equal("POST", rec.Method)
+	assert.Equal("/hello", rec.URL)
+	assert.Equal("/hello", rec.Mapping)
+	if assert.NotNil(rec.Policy) {
+		assert.Equal(0, *rec.Policy)
+	}
+	assert.Equal(200, rec.Status)
+	assert.Equal(int64(5), rec.Bytes)
+	assert.Equal("nam", rec.RequestBody)
+	assert.Equal("Hel", rec.ResponseBody)
+	assert.Empty(rec.Error)
+}
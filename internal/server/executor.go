@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"math/rand"
 	"net/http"
 	"net/url"
@@ -17,17 +16,24 @@ import (
 
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
 	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 )
 
+var executorLog = mylog.New("executor")
+
 type policyExecutor struct {
 	h      *mockHandler
 	r      *http.Request
 	w      *http.ResponseWriter
 	policy *mckmaps.Policy
 
-	returnHead   bool
-	fromForwards bool
-	statusCode   int
+	mapping   *mckmaps.Mapping // the mapping which the policy belongs to, nil for predefined policies
+	policyIdx int              // the index of the policy in the mapping, -1 for predefined policies
+	callIdx   int              // the 0-based index of the call to the policy
+
+	returnHead    bool
+	latency       time.Duration // the latency injected by the policy
+	forwardTarget string        // the url which the request is forwarded to
 }
 
 type forwardError struct {
@@ -47,36 +53,53 @@ func (e *policyExecutor) execute() error {
 		return e.executeReturns()
 	case mckmaps.CmdTypeForwards:
 		return e.executeForwards()
+	case mckmaps.CmdTypeSequence:
+		return e.executeSequence()
+	case mckmaps.CmdTypeOneOf:
+		return e.executeOneOf()
+	case mckmaps.CmdTypeFaults:
+		return e.executeFaults()
+	case mckmaps.CmdTypeEvents:
+		return e.executeEvents()
+	case mckmaps.CmdTypeWebSocket:
+		return e.executeWebSocket()
 	}
 
-	log.Printf("[executor] %-9s: unsupported command type\n", cmdType)
+	executorLog.Errorf("%-9s: unsupported command type", cmdType)
 	return errors.New("unsupported command type: " + string(cmdType))
 }
 
 func (e *policyExecutor) executeReturns() error {
-	returns := e.policy.Returns
+	return e.executeReturnsOf(e.policy.Returns)
+}
 
-	if returns.Latency != nil {
-		waitBeforeReturns(returns.Latency)
-	}
+func (e *policyExecutor) executeSequence() error {
+	return e.executeReturnsOf(e.policy.Sequence.At(e.callIdx))
+}
 
-	err := e.writeResponseForReturns(returns)
-	if err != nil {
-		return err
+func (e *policyExecutor) executeOneOf() error {
+	return e.executeReturnsOf(e.policy.OneOf.Pick(rand.Float64()))
+}
+
+func (e *policyExecutor) executeReturnsOf(returns *mckmaps.Returns) error {
+	if returns.Latency != nil {
+		e.latency += waitBeforeReturns(returns.Latency)
 	}
 
-	e.statusCode = int(returns.StatusCode) // records statusCode for forwards
-	if !e.fromForwards {                   // forwards prints its log by itself
-		log.Printf("[executor] %-9s: (%d) %s %s\n", e.policy.CmdType,
-			e.statusCode, e.r.Method, e.r.URL)
+	// predefined policies are written as usual, even for gRPC mappings
+	if e.mapping != nil && e.mapping.GRPC != nil && e.policyIdx >= 0 {
+		return e.writeResponseForGRPC(returns)
 	}
-	return nil
+	return e.writeResponseForReturns(returns)
 }
 
 func (e *policyExecutor) writeResponseForReturns(returns *mckmaps.Returns) error {
 	e.writeHeaders(returns.Headers)
+	e.writeCookies(returns.Cookies)
+	e.pushResources(returns.Push)
 
-	if e.returnHead {
+	// the length is also announced for paced bodies, so that clients could show the progress
+	if e.returnHead || (returns.Pacing != nil && (*e.w).Header().Get(myhttp.HeaderContentLength) == "") {
 		(*e.w).Header().Set(myhttp.HeaderContentLength, strconv.Itoa(len(returns.Body)))
 	}
 
@@ -84,7 +107,12 @@ func (e *policyExecutor) writeResponseForReturns(returns *mckmaps.Returns) error
 	(*e.w).WriteHeader(int(returns.StatusCode))
 
 	if !e.returnHead {
-		err := e.writeBody(returns.Body)
+		var err error
+		if returns.Pacing != nil {
+			err = writePacedBody(e.r.Context(), *e.w, returns.Body, returns.Pacing)
+		} else {
+			err = e.writeBody(returns.Body)
+		}
 		if err != nil {
 			return err
 		}
@@ -108,6 +136,33 @@ func (e *policyExecutor) writeHeaders(headers []*mckmaps.NameValuesPair) {
 	}
 }
 
+func (e *policyExecutor) writeCookies(cookies []*http.Cookie) {
+	for _, cookie := range cookies {
+		http.SetCookie(*e.w, cookie)
+	}
+}
+
+// pushes resources to HTTP/2 clients which accept server pushes, pushed requests
+// are served by mappings as usual; pushes must be initiated before the response
+func (e *policyExecutor) pushResources(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	pusher, ok := (*e.w).(http.Pusher)
+	if !ok {
+		return
+	}
+	for _, p := range paths {
+		if err := pusher.Push(p, nil); err != nil {
+			if err != http.ErrNotSupported {
+				executorLog.Warnf("fail to push %s: %v", p, err)
+			}
+			return
+		}
+	}
+}
+
 func (e *policyExecutor) writeBody(body []byte) error {
 	var err error
 	if body != nil && len(body) != 0 {
@@ -120,7 +175,7 @@ func (e *policyExecutor) executeForwards() error {
 	forwards := e.policy.Forwards
 
 	if forwards.Latency != nil {
-		waitBeforeReturns(forwards.Latency)
+		e.latency += waitBeforeReturns(forwards.Latency)
 	}
 
 	fPath := forwards.Path
@@ -137,6 +192,7 @@ func (e *policyExecutor) forwardsRemote(fPath string) error {
 		return err
 	}
 
+	e.forwardTarget = newRequest.URL.String()
 	httpClient := http.Client{}
 	resp, err := httpClient.Do(newRequest)
 	if err != nil {
@@ -144,19 +200,11 @@ func (e *policyExecutor) forwardsRemote(fPath string) error {
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Println("[executor] error    : error encountered when forwarding: " + err.Error())
+			executorLog.Errorf("error    : error encountered when forwarding: %v", err)
 		}
 	}()
 
-	err = e.writeResponseForForwardsRemote(resp)
-	if err != nil {
-		return err
-	}
-
-	e.statusCode = resp.StatusCode // records statusCode for forwards
-	log.Printf("[executor] %-9s: (%d) %s %s => %s\n", e.policy.CmdType,
-		resp.StatusCode, e.r.Method, e.r.URL, newRequest.URL)
-	return nil
+	return e.writeResponseForForwardsRemote(resp)
 }
 
 func (e *policyExecutor) newForwardRemoteRequest(fPath string) (*http.Request, error) {
@@ -183,6 +231,15 @@ func (e *policyExecutor) writeResponseForForwardsRemote(resp *http.Response) err
 	}
 	(*e.w).Header().Set(myhttp.HeaderXForwardedServer, HeaderValueServer)
 	(*e.w).WriteHeader(resp.StatusCode) // statusCode must be written after headers
+
+	if pacing := e.policy.Forwards.Pacing; pacing != nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return writePacedBody(e.r.Context(), *e.w, body, pacing)
+	}
+
 	_, err := io.Copy(*e.w, resp.Body)
 	if err != nil {
 		return err
@@ -196,15 +253,10 @@ func (e *policyExecutor) forwardsLocal(fPath string) error {
 		return err
 	}
 
+	e.forwardTarget = newRequest.URL.String()
 	fe := e.h.matchNewExecutor(newRequest, *e.w)
-	fe.fromForwards = true
 	err = fe.execute() // executor writes response for forwards
-
-	if err == nil {
-		e.statusCode = fe.statusCode // records statusCode for forwards
-		log.Printf("[executor] %-9s: (%d) %s %s => %s\n", e.policy.CmdType,
-			fe.statusCode, e.r.Method, e.r.URL, newRequest.URL)
-	}
+	e.latency += fe.latency
 	return err
 }
 
@@ -244,12 +296,23 @@ func (e *policyExecutor) newForwardRequest(url string) (*http.Request, error) {
 	return newRequest, nil
 }
 
-func waitBeforeReturns(latency *mckmaps.Interval) {
-	diff := latency.Max - latency.Min
+// sleeps for a random duration in the given interval, returns the duration slept
+func waitBeforeReturns(latency *mckmaps.Interval) time.Duration {
+	d := randomDuration(latency)
+	if d > 0 {
+		time.Sleep(d)
+	}
+	return d
+}
+
+// returns a random duration in the given interval of milliseconds
+func randomDuration(interval *mckmaps.Interval) time.Duration {
+	var d time.Duration
+	diff := interval.Max - interval.Min
 	if diff > 0 {
-		d := rand.Int63n(diff) + latency.Min
-		time.Sleep(time.Duration(d * int64(time.Millisecond)))
-	} else if latency.Min > 0 {
-		time.Sleep(time.Duration(latency.Min * int64(time.Millisecond)))
+		d = time.Duration((rand.Int63n(diff) + interval.Min) * int64(time.Millisecond))
+	} else if interval.Min > 0 {
+		d = time.Duration(interval.Min * int64(time.Millisecond))
 	}
+	return d
 }
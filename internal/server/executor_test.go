@@ -39,6 +39,33 @@ func TestWaitBeforeReturns(t *testing.T) {
 	assert.True(i2Elapsed.Milliseconds() >= 100 && i2Elapsed.Milliseconds() <= 350)
 }
 
+func TestPolicyExecutor_writeCookies(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	rr := httptest.NewRecorder()
+	var rw http.ResponseWriter = rr
+	exe := &policyExecutor{
+		r: httptest.NewRequest("GET", "/TestPolicyExecutor_writeCookies", nil),
+		w: &rw,
+		policy: &mckmaps.Policy{
+			CmdType: mckmaps.CmdTypeReturns,
+			Returns: &mckmaps.Returns{
+				StatusCode: myhttp.StatusOK,
+				Headers:    []*mckmaps.NameValuesPair{{Name: "Set-Cookie", Values: []string{"a=1"}}},
+				Cookies: []*http.Cookie{
+					{Name: "session", Value: "abc", Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode},
+					{Name: "lang", MaxAge: -1},
+				},
+			},
+		},
+	}
+	if assert.Nil(exe.execute()) {
+		assert.Equal([]string{"a=1", "session=abc; Path=/; HttpOnly; SameSite=Lax", "lang=; Max-Age=0"},
+			rr.Header()["Set-Cookie"])
+	}
+}
+
 func TestPolicyExecutor_executor(t *testing.T) {
 	//noinspection GoImportUsedAsName
 	assert := assert.New(t)
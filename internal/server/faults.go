@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+)
+
+var errHijackNotSupported = errors.New("faults are not supported by the connection")
+
+// errors after the connection is hijacked are only logged, since no response
+// could be written any more
+func (e *policyExecutor) executeFaults() error {
+	faults := e.policy.Faults
+	returns := faults.Returns
+
+	if returns.Latency != nil {
+		e.latency += waitBeforeReturns(returns.Latency)
+	}
+
+	hijacker, ok := (*e.w).(http.Hijacker)
+	if !ok {
+		return errHijackNotSupported
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return errHijackNotSupported
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if err := e.injectFault(conn, brw, faults); err != nil {
+		executorLog.Debugf("faults   : %s %s => %v", e.r.Method, e.r.URL, err)
+	}
+	return nil
+}
+
+func (e *policyExecutor) injectFault(conn net.Conn, brw *bufio.ReadWriter, faults *mckmaps.Faults) error {
+	returns := faults.Returns
+	switch faults.Type {
+	case mckmaps.FaultReset:
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			// discards unsent data and sends RST instead of FIN on close
+			return tcpConn.SetLinger(0)
+		}
+		return nil
+	case mckmaps.FaultCloseAfterHeaders:
+		e.recordStatus(returns.StatusCode)
+		_, err := conn.Write(e.rawHead(returns))
+		return err
+	case mckmaps.FaultGarbage:
+		garbage := make([]byte, faults.Size)
+		rand.Read(garbage)
+		_, err := conn.Write(garbage)
+		return err
+	case mckmaps.FaultStall:
+		<-e.waitForClose(brw)
+		return nil
+	case mckmaps.FaultDrip:
+		e.recordStatus(returns.StatusCode)
+		return e.drip(conn, brw, faults)
+	}
+
+	return errors.New("unsupported fault type: " + string(faults.Type))
+}
+
+// returns the status line and headers in the wire format, announcing the length of the body
+func (e *policyExecutor) rawHead(returns *mckmaps.Returns) []byte {
+	e.writeHeaders(returns.Headers)
+	e.writeCookies(returns.Cookies)
+	header := (*e.w).Header()
+	header.Set(myhttp.HeaderContentLength, strconv.Itoa(len(returns.Body)))
+
+	var buf bytes.Buffer
+	statusCode := int(returns.StatusCode)
+	buf.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode)))
+	_ = header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// records the status code written to the hijacked connection, for metrics and logs
+func (e *policyExecutor) recordStatus(statusCode myhttp.StatusCode) {
+	if rw, ok := (*e.w).(*recordingWriter); ok && rw.statusCode == 0 {
+		rw.statusCode = int(statusCode)
+	}
+}
+
+// the returned channel is closed when the client closes the connection or the
+// server is shutting down
+func (e *policyExecutor) waitForClose(brw *bufio.ReadWriter) <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, brw)
+		close(closed)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-closed:
+		case <-e.r.Context().Done():
+		}
+		close(done)
+	}()
+	return done
+}
+
+// writes the response a few bytes at a time
+func (e *policyExecutor) drip(conn net.Conn, brw *bufio.ReadWriter, faults *mckmaps.Faults) error {
+	done := e.waitForClose(brw)
+	data := append(e.rawHead(faults.Returns), faults.Returns.Body...)
+	for len(data) != 0 {
+		n := faults.Bytes
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := conn.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+
+		if len(data) != 0 {
+			select {
+			case <-done:
+				return nil
+			case <-time.After(randomDuration(faults.Interval)):
+			}
+		}
+	}
+	return nil
+}
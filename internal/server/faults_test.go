@@ -0,0 +1,98 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFaultsMapping(uri string, faults *mckmaps.Faults) *mckmaps.Mapping {
+	if faults.Returns == nil {
+		faults.Returns = &mckmaps.Returns{StatusCode: myhttp.StatusOK}
+	}
+	return &mckmaps.Mapping{
+		URI:    uri,
+		Method: myhttp.MethodGet,
+		Policies: []*mckmaps.Policy{
+			{CmdType: mckmaps.CmdTypeFaults, Faults: faults},
+		},
+	}
+}
+
+var mappingsWithFaults = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		newFaultsMapping("/reset", &mckmaps.Faults{Type: mckmaps.FaultReset}),
+		newFaultsMapping("/closeAfterHeaders", &mckmaps.Faults{
+			Type:    mckmaps.FaultCloseAfterHeaders,
+			Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK, Body: []byte("hello")},
+		}),
+		newFaultsMapping("/garbage", &mckmaps.Faults{Type: mckmaps.FaultGarbage, Size: 64}),
+		newFaultsMapping("/stall", &mckmaps.Faults{Type: mckmaps.FaultStall}),
+		newFaultsMapping("/drip", &mckmaps.Faults{
+			Type:     mckmaps.FaultDrip,
+			Returns:  &mckmaps.Returns{StatusCode: myhttp.StatusCode(201), Body: []byte("hello")},
+			Interval: &mckmaps.Interval{Min: 10, Max: 10},
+			Bytes:    16,
+		}),
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestPolicyExecutor_executeFaults(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	s := httptest.NewServer(newMockHandler(mappingsWithFaults))
+	defer s.Close()
+	client := &http.Client{Timeout: time.Second}
+
+	_, err1 := client.Get(s.URL + "/reset")
+	assert.NotNil(err1)
+
+	resp2, err2 := client.Get(s.URL + "/closeAfterHeaders")
+	if assert.Nil(err2) {
+		assert.Equal(http.StatusOK, resp2.StatusCode)
+		assert.Equal("5", resp2.Header.Get("Content-Length"))
+		_, err2 = ioutil.ReadAll(resp2.Body)
+		assert.NotNil(err2)
+		_ = resp2.Body.Close()
+	}
+
+	_, err3 := client.Get(s.URL + "/garbage")
+	assert.NotNil(err3)
+
+	stallClient := &http.Client{Timeout: 100 * time.Millisecond}
+	start4 := time.Now()
+	_, err4 := stallClient.Get(s.URL + "/stall")
+	assert.NotNil(err4)
+	assert.True(time.Since(start4) >= 100*time.Millisecond)
+
+	start5 := time.Now()
+	resp5, err5 := client.Get(s.URL + "/drip")
+	if assert.Nil(err5) {
+		assert.Equal(201, resp5.StatusCode)
+		body5, err5 := ioutil.ReadAll(resp5.Body)
+		if assert.Nil(err5) {
+			assert.Equal("hello", string(body5))
+		}
+		_ = resp5.Body.Close()
+		assert.True(time.Since(start5) >= 50*time.Millisecond)
+	}
+
+	// faults are not supported without hijacking
+	executor := &policyExecutor{
+		r:      httptest.NewRequest("GET", "/reset", nil),
+		w:      new(http.ResponseWriter),
+		policy: mappingsWithFaults.Mappings[0].Policies[0],
+	}
+	*executor.w = newRecordingWriter(httptest.NewRecorder())
+	assert.Equal(errHijackNotSupported, executor.execute())
+}
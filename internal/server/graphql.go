@@ -0,0 +1,435 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+const contentTypeGraphQL = "application/graphql"
+
+// names of parameters or attributes of the JSON body of GraphQL requests
+const (
+	graphQLQuery         = "query"
+	graphQLOperationName = "operationName"
+	graphQLVariables     = "variables"
+)
+
+var errMissingGraphQLQuery = errors.New("missing GraphQL query")
+
+// a GraphQL request, whose query is parsed to the operation to execute and the
+// root fields the operation selects
+type graphQLRequest struct {
+	valid         bool
+	operationName string
+	fields        []string
+	variables     interface{}
+}
+
+// parses a GraphQL request, which is sent as JSON in the body of POST requests,
+// as parameters of GET requests or as the body with the type 'application/graphql'
+func parseGraphQLRequest(r *http.Request, body []byte) (*graphQLRequest, error) {
+	var query, operationName string
+	var variables interface{}
+
+	switch {
+	case strings.HasPrefix(r.Header.Get(myhttp.HeaderContentType), contentTypeGraphQL):
+		query = string(body)
+		operationName = r.Form.Get(graphQLOperationName)
+	case r.Method == http.MethodGet || len(body) == 0:
+		query = r.Form.Get(graphQLQuery)
+		operationName = r.Form.Get(graphQLOperationName)
+		if rawVariables := r.Form.Get(graphQLVariables); rawVariables != "" {
+			v, err := myjson.Unmarshal([]byte(rawVariables))
+			if err != nil {
+				return nil, fmt.Errorf("malformed GraphQL variables: %v", err)
+			}
+			variables = v
+		}
+	default:
+		json, err := myjson.Unmarshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("malformed GraphQL request: %v", err)
+		}
+		obj, ok := json.(myjson.Object)
+		if !ok {
+			return nil, errors.New("malformed GraphQL request: not a JSON object")
+		}
+		if obj.Get(graphQLQuery) != nil {
+			rawQuery, err := obj.GetString(graphQLQuery)
+			if err != nil {
+				return nil, err
+			}
+			query = string(rawQuery)
+		}
+		if obj.Get(graphQLOperationName) != nil {
+			rawName, err := obj.GetString(graphQLOperationName)
+			if err != nil {
+				return nil, err
+			}
+			operationName = string(rawName)
+		}
+		variables = obj.Get(graphQLVariables)
+	}
+
+	if query == "" {
+		return nil, errMissingGraphQLQuery
+	}
+	if variables == nil { // absent variables are matched as an empty object
+		variables = myjson.Object{}
+	}
+
+	doc, err := parseGraphQLDocument(query)
+	if err != nil {
+		return nil, err
+	}
+	op, err := doc.operation(operationName)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := doc.rootFields(op.selection, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	return &graphQLRequest{
+		valid:         true,
+		operationName: op.name,
+		fields:        fields,
+		variables:     variables,
+	}, nil
+}
+
+func (r *graphQLRequest) matches(when *mckmaps.GraphQLWhen) bool {
+	if !r.valid {
+		return false
+	}
+
+	if when.OperationName != "" && when.OperationName != r.operationName {
+		return false
+	}
+	if when.OperationNameRegexp != nil && !when.OperationNameRegexp.MatchString(r.operationName) {
+		return false
+	}
+
+	for _, f := range when.Fields {
+		if !r.selects(f) {
+			return false
+		}
+	}
+
+	if when.Variables != nil && !when.Variables.Matches(r.variables) {
+		return false
+	}
+	return true
+}
+
+func (r *graphQLRequest) selects(field string) bool {
+	for _, f := range r.fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+type gqlTokenKind int
+
+const (
+	gqlEOF gqlTokenKind = iota
+	gqlPunct
+	gqlName
+	gqlValue
+)
+
+type gqlToken struct {
+	kind gqlTokenKind
+	text string
+}
+
+func (t gqlToken) is(punct string) bool {
+	return t.kind == gqlPunct && t.text == punct
+}
+
+// splits a GraphQL document into tokens, ignoring whitespaces, commas and comments
+func tokenizeGraphQL(s string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' && s[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: gqlPunct, text: "..."})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{|}", c) >= 0:
+			tokens = append(tokens, gqlToken{kind: gqlPunct, text: s[i : i+1]})
+			i++
+		case isGraphQLNameStart(c):
+			j := i + 1
+			for j < len(s) && (isGraphQLNameStart(s[j]) || isDigit(s[j])) {
+				j++
+			}
+			tokens = append(tokens, gqlToken{kind: gqlName, text: s[i:j]})
+			i = j
+		case c == '-' || isDigit(c):
+			j := i + 1
+			for j < len(s) && (isDigit(s[j]) || strings.IndexByte(".eE+-", s[j]) >= 0) {
+				j++
+			}
+			tokens = append(tokens, gqlToken{kind: gqlValue, text: s[i:j]})
+			i = j
+		case strings.HasPrefix(s[i:], `"""`):
+			j := i + 3
+			for {
+				k := strings.Index(s[j:], `"""`)
+				if k < 0 {
+					return nil, errors.New("unterminated block string in GraphQL query")
+				}
+				escaped := k > 0 && s[j+k-1] == '\\'
+				j += k + 3
+				if !escaped {
+					break
+				}
+			}
+			tokens = append(tokens, gqlToken{kind: gqlValue, text: s[i:j]})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' && s[j] != '\n' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) || s[j] != '"' {
+				return nil, errors.New("unterminated string in GraphQL query")
+			}
+			tokens = append(tokens, gqlToken{kind: gqlValue, text: s[i : j+1]})
+			i = j + 1
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' in GraphQL query", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+type gqlOperation struct {
+	name      string
+	selection int // the index of the token starting the selection set
+}
+
+// an executable GraphQL document, only operations and fragments are located,
+// selections are parsed on demand
+type gqlDocument struct {
+	tokens     []gqlToken
+	operations []gqlOperation
+	fragments  map[string]int // indices of selection sets of fragments
+}
+
+func parseGraphQLDocument(query string) (*gqlDocument, error) {
+	tokens, err := tokenizeGraphQL(query)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &gqlDocument{tokens: tokens, fragments: make(map[string]int)}
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+		switch {
+		case t.is("{"): // query shorthand
+			d.operations = append(d.operations, gqlOperation{selection: i})
+		case t.kind == gqlName && (t.text == "query" || t.text == "mutation" || t.text == "subscription"):
+			op := gqlOperation{}
+			if next := d.at(i + 1); next.kind == gqlName {
+				op.name = next.text
+			}
+			if i, err = d.findSelectionSet(i + 1); err != nil {
+				return nil, err
+			}
+			op.selection = i
+			d.operations = append(d.operations, op)
+		case t.kind == gqlName && t.text == "fragment":
+			name := d.at(i + 1)
+			if name.kind != gqlName {
+				return nil, errors.New("missing fragment name in GraphQL query")
+			}
+			if i, err = d.findSelectionSet(i + 2); err != nil {
+				return nil, err
+			}
+			d.fragments[name.text] = i
+		default:
+			return nil, fmt.Errorf("unexpected '%s' in GraphQL query", t.text)
+		}
+
+		if i, err = d.skipBlock(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(d.operations) == 0 {
+		return nil, errors.New("no operation in GraphQL query")
+	}
+	return d, nil
+}
+
+// returns the operation of the given name, the name may be omitted if there is
+// only one operation
+func (d *gqlDocument) operation(name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(d.operations) != 1 {
+			return nil, errors.New("operationName is required for GraphQL queries with multiple operations")
+		}
+		return &d.operations[0], nil
+	}
+
+	for i := range d.operations {
+		if d.operations[i].name == name {
+			return &d.operations[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find the GraphQL operation '%s'", name)
+}
+
+// returns names of fields the selection set starting at the given index selects,
+// fragments are expanded; aliases are ignored
+func (d *gqlDocument) rootFields(selection int, visited map[string]bool) ([]string, error) {
+	var fields []string
+	var err error
+	for i := selection + 1; ; {
+		t := d.at(i)
+		switch {
+		case t.is("}"):
+			return fields, nil
+		case t.is("..."):
+			i++
+			if next := d.at(i); next.kind == gqlName && next.text != "on" { // fragment spread
+				i = d.skipDirectives(i + 1)
+				if visited[next.text] {
+					continue
+				}
+				visited[next.text] = true
+				start, ok := d.fragments[next.text]
+				if !ok {
+					return nil, fmt.Errorf("cannot find the GraphQL fragment '%s'", next.text)
+				}
+				sub, err := d.rootFields(start, visited)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, sub...)
+			} else { // inline fragment
+				if next.kind == gqlName {
+					i += 2 // skips the type condition
+				}
+				i = d.skipDirectives(i)
+				if !d.at(i).is("{") {
+					return nil, errors.New("missing selection set of inline fragment in GraphQL query")
+				}
+				sub, err := d.rootFields(i, visited)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, sub...)
+				if i, err = d.skipBlock(i); err != nil {
+					return nil, err
+				}
+			}
+		case t.kind == gqlName:
+			field := t.text
+			i++
+			if d.at(i).is(":") { // the alias is followed by the name
+				field = d.at(i + 1).text
+				i += 2
+			}
+			fields = append(fields, field)
+			if d.at(i).is("(") {
+				if i, err = d.skipBlock(i); err != nil {
+					return nil, err
+				}
+			}
+			i = d.skipDirectives(i)
+			if d.at(i).is("{") {
+				if i, err = d.skipBlock(i); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, errors.New("malformed selection set in GraphQL query")
+		}
+	}
+}
+
+func (d *gqlDocument) at(i int) gqlToken {
+	if i < len(d.tokens) {
+		return d.tokens[i]
+	}
+	return gqlToken{kind: gqlEOF}
+}
+
+// finds the first selection set from the given index, skipping variable
+// definitions and directives
+func (d *gqlDocument) findSelectionSet(i int) (int, error) {
+	depth := 0
+	for ; i < len(d.tokens); i++ {
+		t := d.tokens[i]
+		switch {
+		case t.is("(") || t.is("["):
+			depth++
+		case t.is(")") || t.is("]"):
+			depth--
+		case t.is("{") && depth == 0:
+			return i, nil
+		}
+	}
+	return 0, errors.New("missing selection set in GraphQL query")
+}
+
+// skips the block starting at the given index, returns the index after the block
+func (d *gqlDocument) skipBlock(i int) (int, error) {
+	depth := 0
+	for ; i < len(d.tokens); i++ {
+		t := d.tokens[i]
+		switch {
+		case t.is("{") || t.is("(") || t.is("["):
+			depth++
+		case t.is("}") || t.is(")") || t.is("]"):
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, errors.New("unbalanced brackets in GraphQL query")
+}
+
+func (d *gqlDocument) skipDirectives(i int) int {
+	for d.at(i).is("@") {
+		i += 2
+		if d.at(i).is("(") {
+			if next, err := d.skipBlock(i); err == nil {
+				i = next
+			} else {
+				return len(d.tokens)
+			}
+		}
+	}
+	return i
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQLDocument(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	d1, e1 := parseGraphQLDocument(`{ a b: c }`)
+	if assert.Nil(e1) {
+		op, err := d1.operation("")
+		if assert.Nil(err) {
+			assert.Equal("", op.name)
+			fields, err := d1.rootFields(op.selection, make(map[string]bool))
+			if assert.Nil(err) {
+				assert.Equal([]string{"a", "c"}, fields)
+			}
+		}
+	}
+
+	d2, e2 := parseGraphQLDocument(`
+		# fetches a user
+		query GetUser($id: ID!, $filter: Filter = {name: "}"}) @cached(ttl: 60) {
+			me: user(id: $id) @include(if: true) { id, name, friends { id } }
+			...Extra
+			... on Query { posts(first: 10) { title } }
+			... @skip(if: false) { tags }
+		}
+		mutation AddUser { addUser(name: """a "}" b""") { id } }
+		fragment Extra on Query { viewer ...Extra }
+	`)
+	if assert.Nil(e2) {
+		_, err := d2.operation("")
+		assert.NotNil(err)
+		_, err = d2.operation("Unknown")
+		assert.NotNil(err)
+
+		op, err := d2.operation("GetUser")
+		if assert.Nil(err) {
+			fields, err := d2.rootFields(op.selection, make(map[string]bool))
+			if assert.Nil(err) {
+				assert.Equal([]string{"user", "viewer", "posts", "tags"}, fields)
+			}
+		}
+		op, err = d2.operation("AddUser")
+		if assert.Nil(err) {
+			fields, err := d2.rootFields(op.selection, make(map[string]bool))
+			if assert.Nil(err) {
+				assert.Equal([]string{"addUser"}, fields)
+			}
+		}
+	}
+
+	_, e3 := parseGraphQLDocument(`query { a `)
+	assert.NotNil(e3)
+	_, e4 := parseGraphQLDocument(`type Query { a: String }`)
+	assert.NotNil(e4)
+	_, e5 := parseGraphQLDocument(`{ a(s: "unterminated) }`)
+	assert.NotNil(e5)
+}
+
+func TestParseGraphQLRequest(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	body1 := `{"query": "query Q($id: ID) { user(id: $id) { id } }", "variables": {"id": "1"}}`
+	r1 := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body1))
+	r1.Header.Set(myhttp.HeaderContentType, "application/json")
+	require.Nil(r1.ParseForm())
+	g1, e1 := parseGraphQLRequest(r1, []byte(body1))
+	if assert.Nil(e1) {
+		assert.Equal("Q", g1.operationName)
+		assert.Equal([]string{"user"}, g1.fields)
+		assert.Equal(myjson.Object{"id": myjson.String("1")}, g1.variables)
+	}
+
+	q2 := url.Values{"query": {"{ hello }"}, "variables": {`{"a": 1}`}}
+	r2 := httptest.NewRequest(http.MethodGet, "/graphql?"+q2.Encode(), nil)
+	require.Nil(r2.ParseForm())
+	g2, e2 := parseGraphQLRequest(r2, nil)
+	if assert.Nil(e2) {
+		assert.Equal("", g2.operationName)
+		assert.Equal([]string{"hello"}, g2.fields)
+		assert.Equal(myjson.Object{"a": myjson.Number(1)}, g2.variables)
+	}
+
+	r3 := httptest.NewRequest(http.MethodPost, "/graphql?operationName=B", nil)
+	r3.Header.Set(myhttp.HeaderContentType, "application/graphql")
+	require.Nil(r3.ParseForm())
+	g3, e3 := parseGraphQLRequest(r3, []byte(`query A { a } query B { b }`))
+	if assert.Nil(e3) {
+		assert.Equal("B", g3.operationName)
+		assert.Equal([]string{"b"}, g3.fields)
+		assert.Equal(myjson.Object{}, g3.variables)
+	}
+
+	r4 := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	require.Nil(r4.ParseForm())
+	_, e4 := parseGraphQLRequest(r4, []byte(`{"operationName": "A"}`))
+	assert.Equal(errMissingGraphQLQuery, e4)
+}
+
+func TestBoundMatcher_graphQLRequest(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	mappings := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:     "/graphql",
+				Method:  myhttp.MethodAny,
+				GraphQL: true,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							GraphQL: &mckmaps.GraphQLWhen{
+								Fields:    []string{"user"},
+								Variables: myjson.NewExtJSONMatcher(myjson.Object{"id": myjson.String("1")}),
+							},
+						},
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK, Body: []byte(`{"data":{"user":{}}}`)},
+					},
+					{
+						When: &mckmaps.When{
+							GraphQL: &mckmaps.GraphQLWhen{OperationNameRegexp: regexp.MustCompile("^Add")},
+						},
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK, Body: []byte(`{"data":{"added":true}}`)},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+
+	s := httptest.NewServer(newMockHandler(mappings))
+	defer s.Close()
+
+	post := func(body string) (int, string) {
+		resp, err := http.Post(s.URL+"/graphql", "application/json", strings.NewReader(body))
+		require.Nil(err)
+		defer func() { _ = resp.Body.Close() }()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.Nil(err)
+		return resp.StatusCode, string(b)
+	}
+
+	c1, b1 := post(`{"query": "query($id: ID) { user(id: $id) { id } }", "variables": {"id": "1"}}`)
+	assert.Equal(http.StatusOK, c1)
+	assert.Equal(`{"data":{"user":{}}}`, b1)
+
+	c2, b2 := post(`{"query": "mutation AddUser { addUser { id } }"}`)
+	assert.Equal(http.StatusOK, c2)
+	assert.Equal(`{"data":{"added":true}}`, b2)
+
+	c3, _ := post(`{"query": "query($id: ID) { user(id: $id) { id } }", "variables": {"id": "2"}}`)
+	assert.Equal(http.StatusBadRequest, c3)
+
+	c4, _ := post(`not a json`)
+	assert.Equal(http.StatusBadRequest, c4)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+)
+
+// content types of gRPC protocols, which may be followed by '+proto'
+const (
+	contentTypeGRPC        = "application/grpc"
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+)
+
+// names of gRPC headers, which are sent as trailers unless the response has no message
+const (
+	headerGRPCStatus  = "Grpc-Status"
+	headerGRPCMessage = "Grpc-Message"
+)
+
+const (
+	grpcFrameHeaderLen = 5
+	grpcFlagCompressed = 0x01
+	grpcFlagTrailer    = 0x80
+)
+
+var errGRPCCompressed = errors.New("compressed gRPC messages are not supported")
+
+type grpcProtocol int
+
+const (
+	grpcNative grpcProtocol = iota
+	grpcWeb
+	grpcWebText
+)
+
+func grpcProtocolOf(r *http.Request) grpcProtocol {
+	contentType := r.Header.Get(myhttp.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, contentTypeGRPCWebText):
+		return grpcWebText
+	case strings.HasPrefix(contentType, contentTypeGRPCWeb):
+		return grpcWeb
+	default:
+		return grpcNative
+	}
+}
+
+func (p grpcProtocol) contentType() string {
+	switch p {
+	case grpcWebText:
+		return contentTypeGRPCWebText + "+proto"
+	case grpcWeb:
+		return contentTypeGRPCWeb + "+proto"
+	default:
+		return contentTypeGRPC + "+proto"
+	}
+}
+
+// reads the request message of a unary call from the body
+func readGRPCMessage(body []byte, protocol grpcProtocol) ([]byte, error) {
+	if protocol == grpcWebText {
+		decoded, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+
+	if len(body) < grpcFrameHeaderLen {
+		return nil, errors.New("incomplete gRPC message")
+	}
+	if body[0]&grpcFlagCompressed != 0 {
+		return nil, errGRPCCompressed
+	}
+	l := binary.BigEndian.Uint32(body[1:grpcFrameHeaderLen])
+	if uint32(len(body)-grpcFrameHeaderLen) < l {
+		return nil, errors.New("incomplete gRPC message")
+	}
+	return body[grpcFrameHeaderLen : grpcFrameHeaderLen+int(l)], nil
+}
+
+func writeGRPCFrame(buf *bytes.Buffer, flag byte, data []byte) {
+	var header [grpcFrameHeaderLen]byte
+	header[0] = flag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	buf.Write(header[:])
+	buf.Write(data)
+}
+
+// writes the response of a unary call, of which the body is the response message
+// in the wire format; the gRPC status is 0 (OK) unless set in headers
+func (e *policyExecutor) writeResponseForGRPC(returns *mckmaps.Returns) error {
+	protocol := grpcProtocolOf(e.r)
+
+	var headers []*mckmaps.NameValuesPair
+	status, message := "0", ""
+	for _, pair := range returns.Headers {
+		switch http.CanonicalHeaderKey(pair.Name) {
+		case headerGRPCStatus:
+			status = pair.Values[0]
+		case headerGRPCMessage:
+			message = pair.Values[0]
+		default:
+			headers = append(headers, pair)
+		}
+	}
+
+	e.writeHeaders(headers)
+	e.writeCookies(returns.Cookies)
+	header := (*e.w).Header()
+	header.Set(myhttp.HeaderContentType, protocol.contentType())
+
+	// an error without a message is sent as a trailers-only response
+	if status != "0" && returns.Body == nil {
+		header.Set(headerGRPCStatus, status)
+		if message != "" {
+			header.Set(headerGRPCMessage, message)
+		}
+		(*e.w).WriteHeader(int(returns.StatusCode))
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writeGRPCFrame(&buf, 0, returns.Body)
+	if protocol == grpcNative {
+		header.Set(myhttp.HeaderTrailer, headerGRPCStatus+", "+headerGRPCMessage)
+	} else { // trailers of gRPC-Web are sent in the body
+		trailers := fmt.Sprintf("grpc-status: %s\r\n", status)
+		if message != "" {
+			trailers += fmt.Sprintf("grpc-message: %s\r\n", message)
+		}
+		writeGRPCFrame(&buf, grpcFlagTrailer, []byte(trailers))
+	}
+
+	(*e.w).WriteHeader(int(returns.StatusCode))
+	var err error
+	if protocol == grpcWebText {
+		_, err = (*e.w).Write([]byte(base64.StdEncoding.EncodeToString(buf.Bytes())))
+	} else {
+		_, err = (*e.w).Write(buf.Bytes())
+	}
+	if err != nil {
+		return err
+	}
+
+	if protocol == grpcNative {
+		header.Set(headerGRPCStatus, status)
+		if message != "" {
+			header.Set(headerGRPCMessage, message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func loadGreeterMethod(t *testing.T) *mckmaps.GRPCMethod {
+	data, err := ioutil.ReadFile(filepath.Join("..", "mckmaps", "testdata", "grpc", "greeter.protoset"))
+	require.Nil(t, err)
+	set := new(descriptorpb.FileDescriptorSet)
+	require.Nil(t, proto.Unmarshal(data, set))
+	files, err := protodesc.NewFiles(set)
+	require.Nil(t, err)
+	d, err := files.FindDescriptorByName("helloworld.Greeter")
+	require.Nil(t, err)
+	md := d.(protoreflect.ServiceDescriptor).Methods().ByName("SayHello")
+	return &mckmaps.GRPCMethod{Descriptor: md}
+}
+
+func newGRPCMappings(t *testing.T) *mckmaps.MockuMappings {
+	method := loadGreeterMethod(t)
+	reply, err := method.EncodeResponse([]byte(`{"message": "Hello, kuma!"}`))
+	require.Nil(t, err)
+
+	return &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    method.Path(),
+				Method: myhttp.MethodPost,
+				GRPC:   method,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							BodyJSON: myjson.NewExtJSONMatcher(myjson.Object{"name": myjson.String("kuma")}),
+						},
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK, Body: reply},
+					},
+					{
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{
+							StatusCode: myhttp.StatusOK,
+							Headers: []*mckmaps.NameValuesPair{
+								{Name: "grpc-status", Values: []string{"5"}},
+								{Name: "grpc-message", Values: []string{"not found"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+}
+
+func grpcFrame(flag byte, data []byte) []byte {
+	var buf bytes.Buffer
+	writeGRPCFrame(&buf, flag, data)
+	return buf.Bytes()
+}
+
+func TestReadGRPCMessage(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	m1, err1 := readGRPCMessage(grpcFrame(0, []byte("hello")), grpcNative)
+	if assert.Nil(err1) {
+		assert.Equal("hello", string(m1))
+	}
+
+	text := base64.StdEncoding.EncodeToString(grpcFrame(0, []byte("hello")))
+	m2, err2 := readGRPCMessage([]byte(text), grpcWebText)
+	if assert.Nil(err2) {
+		assert.Equal("hello", string(m2))
+	}
+
+	_, err3 := readGRPCMessage(grpcFrame(grpcFlagCompressed, []byte("hello")), grpcNative)
+	assert.Equal(errGRPCCompressed, err3)
+
+	_, err4 := readGRPCMessage(grpcFrame(0, []byte("hello"))[:7], grpcNative)
+	assert.NotNil(err4)
+}
+
+func TestPolicyExecutor_writeResponseForGRPC(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	s := httptest.NewServer(h2c.NewHandler(newMockHandler(newGRPCMappings(t)), &http2.Server{}))
+	defer s.Close()
+
+	// HelloRequest{name: "kuma"}
+	kuma := []byte{0x0a, 0x04, 'k', 'u', 'm', 'a'}
+	expectedReply := grpcFrame(0, []byte{0x0a, 0x0c, 'H', 'e', 'l', 'l', 'o', ',', ' ', 'k', 'u', 'm', 'a', '!'})
+
+	// gRPC over h2c
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	req1, err1 := http.NewRequest("POST", s.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(grpcFrame(0, kuma)))
+	require.Nil(err1)
+	req1.Header.Set("Content-Type", "application/grpc")
+	resp1, err1 := client.Do(req1)
+	if assert.Nil(err1) {
+		assert.Equal(2, resp1.ProtoMajor)
+		assert.Equal("application/grpc+proto", resp1.Header.Get("Content-Type"))
+		body1, err1 := ioutil.ReadAll(resp1.Body)
+		if assert.Nil(err1) {
+			assert.Equal(expectedReply, body1)
+		}
+		assert.Equal("0", resp1.Trailer.Get("Grpc-Status"))
+		_ = resp1.Body.Close()
+	}
+
+	req2, err2 := http.NewRequest("POST", s.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(grpcFrame(0, nil)))
+	require.Nil(err2)
+	req2.Header.Set("Content-Type", "application/grpc")
+	resp2, err2 := client.Do(req2)
+	if assert.Nil(err2) {
+		assert.Equal("5", resp2.Header.Get("Grpc-Status"))
+		assert.Equal("not found", resp2.Header.Get("Grpc-Message"))
+		_ = resp2.Body.Close()
+	}
+
+	// gRPC-Web
+	resp3, err3 := http.Post(s.URL+"/helloworld.Greeter/SayHello", "application/grpc-web+proto",
+		bytes.NewReader(grpcFrame(0, kuma)))
+	if assert.Nil(err3) {
+		assert.Equal("application/grpc-web+proto", resp3.Header.Get("Content-Type"))
+		body3, err3 := ioutil.ReadAll(resp3.Body)
+		if assert.Nil(err3) {
+			expected := append(expectedReply, grpcFrame(grpcFlagTrailer, []byte("grpc-status: 0\r\n"))...)
+			assert.Equal(expected, body3)
+		}
+		_ = resp3.Body.Close()
+	}
+
+	// gRPC-Web in base64
+	text := base64.StdEncoding.EncodeToString(grpcFrame(0, kuma))
+	resp4, err4 := http.Post(s.URL+"/helloworld.Greeter/SayHello", "application/grpc-web-text",
+		bytes.NewReader([]byte(text)))
+	if assert.Nil(err4) {
+		assert.Equal("application/grpc-web-text+proto", resp4.Header.Get("Content-Type"))
+		body4, err4 := ioutil.ReadAll(resp4.Body)
+		if assert.Nil(err4) {
+			decoded, err := base64.StdEncoding.DecodeString(string(body4))
+			if assert.Nil(err) {
+				assert.Equal(expectedReply, decoded[:len(expectedReply)])
+			}
+		}
+		_ = resp4.Body.Close()
+	}
+}
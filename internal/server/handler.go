@@ -2,20 +2,24 @@ package server
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"sort"
 
 	"github.com/kumasuke120/mockuma/internal"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
 	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/mylog"
 )
 
+var handlerLog = mylog.New("handler")
+
 var HeaderValueServer = fmt.Sprintf("%s/%s", internal.AppName, internal.VersionNumber)
 
 type mockHandler struct {
 	mappings    *mckmaps.MockuMappings
 	pathMatcher *pathMatcher
+	inspector   *inspector     // nil if the admin handler is disabled
+	oauth2      *oauth2Handler // nil if the oauth2 provider is disabled
 }
 
 func newMockHandler(mappings *mckmaps.MockuMappings) http.Handler {
@@ -25,31 +29,63 @@ func newMockHandler(mappings *mckmaps.MockuMappings) http.Handler {
 
 	h.listAllMappings()
 
+	oauth2Option := mappings.Config.OAuth2
+	if oauth2Option != nil {
+		h.oauth2 = newOAuth2Handler(oauth2Option)
+	}
+
+	var handler http.Handler = h
+
 	corsOption := mappings.Config.CORS
 	if corsOption.Enabled {
-		log.Println("[handler ] enabled  : cors handler")
-		return corsOption.ToCors().Handler(h)
-	} else {
-		return h
+		handlerLog.Infof("enabled  : cors handler")
+		handler = corsOption.ToCors().Handler(handler)
 	}
+
+	adminOption := mappings.Config.Admin
+	if adminOption != nil && adminOption.Enabled {
+		h.inspector = defaultInspector
+		handler = newAdminHandler(adminOption, h, handler)
+	}
+
+	return handler
 }
 
 func (h *mockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set(myhttp.HeaderServer, HeaderValueServer)
+	bodyLimit := mylog.BodyLimit()
+	if h.inspector != nil && bodyLimit < inspectorBodyLimit {
+		bodyLimit = inspectorBodyLimit
+	}
+
+	x := newExchange(w, r, bodyLimit)
+	x.w.Header().Set(myhttp.HeaderServer, HeaderValueServer)
+	x.w.Header().Set(myhttp.HeaderXRequestID, x.id)
 
-	executor := h.matchNewExecutor(r, w)
-	if err := executor.execute(); err != nil {
-		h.handleExecuteError(w, r, err)
+	if h.oauth2 != nil && h.oauth2.serve(x.w, r) {
+		x.builtin, x.endpoint = "oauth2", r.URL.Path
+	} else {
+		x.e = h.matchNewExecutor(r, x.w)
+		if x.err = x.e.execute(); x.err != nil {
+			h.handleExecuteError(x.w, r, x.err)
+		}
+	}
+
+	x.finish()
+	if h.inspector != nil {
+		h.inspector.record(x)
 	}
 }
 
 func (h *mockHandler) matchNewExecutor(r *http.Request, w http.ResponseWriter) *policyExecutor {
-	executor := &policyExecutor{h: h, r: r, w: &w}
+	executor := &policyExecutor{h: h, r: r, w: &w, policyIdx: -1}
 
 	matcher := h.pathMatcher.bind(r)
 	if matcher.matches() {
 		executor.returnHead = matcher.headMatches()
 		executor.policy = matcher.matchPolicy()
+		executor.mapping = matcher.matchedMapping
+		executor.policyIdx = matcher.matchedPolicyIdx
+		executor.callIdx = matcher.matchedCallIdx
 	} else {
 		executor.policy = pNotFound
 	}
@@ -58,7 +94,7 @@ func (h *mockHandler) matchNewExecutor(r *http.Request, w http.ResponseWriter) *
 }
 
 func (h *mockHandler) handleExecuteError(w http.ResponseWriter, r *http.Request, err error) {
-	log.Printf("[handler ] error    : %s %s => %v\n", r.Method, r.URL, err)
+	handlerLog.Errorf("error    : %s %s => %v", r.Method, r.URL, err)
 
 	switch err.(type) {
 	case *forwardError:
@@ -70,21 +106,38 @@ func (h *mockHandler) handleExecuteError(w http.ResponseWriter, r *http.Request,
 	}
 
 	if err != nil {
-		log.Printf("[handler ] error    : %s %s => fail to render response: %v\n", r.Method, r.URL, err)
+		handlerLog.Errorf("error    : %s %s => fail to render response: %v", r.Method, r.URL, err)
 	}
 }
 
 func (h *mockHandler) listAllMappings() {
-	uri2Methods := h.mappings.GroupMethodsByURI()
+	for _, m := range listMappedURIs(h.mappings) {
+		handlerLog.Infof("mapped   : %s, methods = %v", m.URI, m.Methods)
+	}
+	for _, c := range findURIPatternConflicts(h.pathMatcher.patternPath) {
+		handlerLog.Warnf("conflict : %s and %s may match the same uri, the former is matched first "+
+			"unless 'priority' is specified", c[0].uri, c[1].uri)
+	}
+}
+
+type mappedURI struct {
+	URI     string              `json:"uri"`
+	Methods []myhttp.HTTPMethod `json:"methods"`
+}
+
+// lists all mapped uris in alphabetical order, along with their methods
+func listMappedURIs(mappings *mckmaps.MockuMappings) []*mappedURI {
+	uri2Methods := mappings.GroupMethodsByURI()
 
-	var uris []string
+	uris := make([]string, 0, len(uri2Methods))
 	for uri := range uri2Methods {
 		uris = append(uris, uri)
 	}
 	sort.Strings(uris)
 
-	for _, uri := range uris {
-		methods := uri2Methods[uri]
-		log.Printf("[handler ] mapped   : %s, methods = %v\n", uri, methods)
+	result := make([]*mappedURI, len(uris))
+	for idx, uri := range uris {
+		result[idx] = &mappedURI{URI: uri, Methods: uri2Methods[uri]}
 	}
+	return result
 }
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -81,3 +83,160 @@ func TestMockHandler_listAllMappings(t *testing.T) {
 	handler := newMockHandler(mappings).(*mockHandler)
 	handler.listAllMappings()
 }
+
+var mappingsWithCalls = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		{
+			URI:    "/retry",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					Times:   1,
+					CmdType: mckmaps.CmdTypeReturns,
+					Returns: &mckmaps.Returns{StatusCode: myhttp.StatusCode(503)},
+				},
+				{
+					CmdType: mckmaps.CmdTypeReturns,
+					Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK},
+				},
+			},
+		},
+		{
+			URI:    "/sequence",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeSequence,
+					Sequence: &mckmaps.Sequence{
+						Returns: []*mckmaps.Returns{
+							{StatusCode: myhttp.StatusCode(500)},
+							{StatusCode: myhttp.StatusCode(502)},
+							{StatusCode: myhttp.StatusOK},
+						},
+					},
+				},
+			},
+		},
+		{
+			URI:    "/cycle",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeSequence,
+					Sequence: &mckmaps.Sequence{
+						Returns: []*mckmaps.Returns{
+							{StatusCode: myhttp.StatusOK},
+							{StatusCode: myhttp.StatusCode(201)},
+						},
+						Cycle: true,
+					},
+				},
+			},
+		},
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestMockHandler_ServeHTTP_calls(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(mappingsWithCalls)
+	serve := func(uri string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", uri, nil))
+		return rr.Code
+	}
+
+	assert.Equal(http.StatusServiceUnavailable, serve("/retry"))
+	assert.Equal(http.StatusOK, serve("/retry"))
+	assert.Equal(http.StatusOK, serve("/retry"))
+
+	assert.Equal(500, serve("/sequence"))
+	assert.Equal(502, serve("/sequence"))
+	assert.Equal(200, serve("/sequence"))
+	assert.Equal(200, serve("/sequence"))
+
+	assert.Equal(200, serve("/cycle"))
+	assert.Equal(201, serve("/cycle"))
+	assert.Equal(200, serve("/cycle"))
+
+	// counts are reset when mappings are reloaded
+	handler = newMockHandler(mappingsWithCalls)
+	assert.Equal(http.StatusServiceUnavailable, serve("/retry"))
+}
+
+var mappingsWithRandom = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		{
+			URI:    "/probability",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					When: &mckmaps.When{
+						Params: []*mckmaps.NameValuesPair{{Name: "p", Values: []string{"0"}}},
+					},
+					Probability: new(float64),
+					CmdType:     mckmaps.CmdTypeReturns,
+					Returns:     &mckmaps.Returns{StatusCode: myhttp.StatusCode(503)},
+				},
+				{
+					When: &mckmaps.When{
+						Params: []*mckmaps.NameValuesPair{{Name: "p", Values: []string{"1"}}},
+					},
+					Probability: func() *float64 { p := 1.0; return &p }(),
+					CmdType:     mckmaps.CmdTypeReturns,
+					Returns:     &mckmaps.Returns{StatusCode: myhttp.StatusCode(503)},
+				},
+				{
+					CmdType: mckmaps.CmdTypeReturns,
+					Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK},
+				},
+			},
+		},
+		{
+			URI:    "/oneOf",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeOneOf,
+					OneOf: &mckmaps.OneOf{
+						Choices: []*mckmaps.Choice{
+							{Weight: 1, Returns: &mckmaps.Returns{StatusCode: myhttp.StatusCode(503)}},
+							{Weight: 1, Returns: &mckmaps.Returns{StatusCode: myhttp.StatusOK}},
+						},
+					},
+				},
+			},
+		},
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestMockHandler_ServeHTTP_random(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(mappingsWithRandom)
+	serve := func(uri string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", uri, nil))
+		return rr.Code
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(http.StatusOK, serve("/probability?p=0"))
+		assert.Equal(503, serve("/probability?p=1"))
+	}
+
+	codes := make(map[int]int)
+	for i := 0; i < 100; i++ {
+		codes[serve("/oneOf")]++
+	}
+	assert.Len(codes, 2)
+	assert.Equal(100, codes[200]+codes[503])
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mylog"
+)
+
+const (
+	inspectorCapacity  = 200       // the number of recent requests kept by the inspector
+	inspectorBodyLimit = 64 * 1024 // the maximum number of bytes of each body kept by the inspector
+	inspectorBuffer    = 64        // the number of requests buffered for each subscriber
+)
+
+// the inspector outlives handlers, so that the recent requests survive reloads
+var defaultInspector = newInspector(inspectorCapacity)
+
+// an inspected request with its response, used by the inspector web ui
+type inspection struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	*mylog.AccessRecord
+	Request  *inspectedMessage `json:"request,omitempty"`
+	Response *inspectedMessage `json:"response,omitempty"`
+}
+
+type inspectedMessage struct {
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body"`
+	Truncated bool        `json:"truncated"`
+}
+
+func newInspection(x *exchange) *inspection {
+	rec := x.toAccessRecord()
+	rec.RequestBody = ""
+	rec.ResponseBody = ""
+
+	reqBody, reqTruncated := x.reqBody, false
+	if len(reqBody) > inspectorBodyLimit {
+		reqBody, reqTruncated = reqBody[:inspectorBodyLimit], true
+	}
+	respBody := x.w.body
+	if len(respBody) > inspectorBodyLimit {
+		respBody = respBody[:inspectorBodyLimit]
+	}
+
+	return &inspection{
+		Time:         x.start,
+		AccessRecord: rec,
+		Request: &inspectedMessage{
+			Header:    x.r.Header.Clone(),
+			Body:      string(reqBody),
+			Truncated: reqTruncated,
+		},
+		Response: &inspectedMessage{
+			Header:    x.w.Header().Clone(),
+			Body:      string(respBody),
+			Truncated: x.w.written > int64(len(respBody)),
+		},
+	}
+}
+
+// returns a copy without request and response details
+func (i *inspection) summary() *inspection {
+	return &inspection{Seq: i.Seq, Time: i.Time, AccessRecord: i.AccessRecord}
+}
+
+type subscriber struct {
+	ch   chan *inspection
+	done chan struct{}
+}
+
+// keeps recent requests and broadcasts new ones to its subscribers
+type inspector struct {
+	mux         sync.Mutex
+	capacity    int
+	seq         uint64
+	history     []*inspection
+	subscribers map[*subscriber]bool
+}
+
+func newInspector(capacity int) *inspector {
+	return &inspector{
+		capacity:    capacity,
+		subscribers: make(map[*subscriber]bool),
+	}
+}
+
+func (i *inspector) record(x *exchange) {
+	ins := newInspection(x)
+
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	i.seq++
+	ins.Seq = i.seq
+	if len(i.history) >= i.capacity {
+		n := copy(i.history, i.history[len(i.history)-i.capacity+1:])
+		i.history = i.history[:n]
+	}
+	i.history = append(i.history, ins)
+
+	summary := ins.summary()
+	for s := range i.subscribers {
+		select {
+		case s.ch <- summary:
+		default: // drops the request for slow subscribers
+		}
+	}
+}
+
+// returns summaries of recent requests, the oldest first
+func (i *inspector) recent() []*inspection {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	result := make([]*inspection, len(i.history))
+	for idx, ins := range i.history {
+		result[idx] = ins.summary()
+	}
+	return result
+}
+
+func (i *inspector) get(seq uint64) *inspection {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	for _, ins := range i.history {
+		if ins.Seq == seq {
+			return ins
+		}
+	}
+	return nil
+}
+
+func (i *inspector) subscribe() *subscriber {
+	s := &subscriber{
+		ch:   make(chan *inspection, inspectorBuffer),
+		done: make(chan struct{}),
+	}
+
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	i.subscribers[s] = true
+	return s
+}
+
+func (i *inspector) unsubscribe(s *subscriber) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+	delete(i.subscribers, s)
+}
+
+// ends all current subscriptions, so that the server could be shut down without
+// waiting for the streams
+func (i *inspector) disconnectAll() {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	for s := range i.subscribers {
+		close(s.done)
+		delete(i.subscribers, s)
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+// the single-page web ui of the inspector, served at the admin path; all urls
+// in the page are relative to the admin path
+const inspectorPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>MocKuma Inspector</title>
+<style>
+  * { box-sizing: border-box; }
+  body { margin: 0; font: 13px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292e; }
+  header { padding: 8px 16px; background: #24292e; color: #fff; display: flex; align-items: center; }
+  header h1 { font-size: 16px; margin: 0; flex: 1; }
+  header .status { font-size: 12px; }
+  header .status.on::before { content: "\25CF "; color: #28a745; }
+  header .status.off::before { content: "\25CF "; color: #d73a49; }
+  header button { margin-left: 12px; }
+  main { display: flex; height: calc(100vh - 40px); }
+  section { overflow: auto; border-right: 1px solid #e1e4e8; }
+  section h2 { font-size: 13px; margin: 0; padding: 6px 12px; background: #f6f8fa; border-bottom: 1px solid #e1e4e8;
+    position: sticky; top: 0; }
+  #mappings { width: 22%; }
+  #requests { width: 38%; }
+  #detail { flex: 1; border-right: none; padding-bottom: 16px; }
+  ul { list-style: none; margin: 0; padding: 0; }
+  #mappings li { padding: 4px 12px; border-bottom: 1px solid #f1f1f1; word-break: break-all; }
+  .method { display: inline-block; font-size: 11px; padding: 0 4px; margin-right: 4px; border-radius: 3px;
+    background: #e1e4e8; font-family: monospace; }
+  table { width: 100%; border-collapse: collapse; }
+  #requests td { padding: 4px 8px; border-bottom: 1px solid #f1f1f1; white-space: nowrap; font-family: monospace; }
+  #requests td.url { max-width: 240px; overflow: hidden; text-overflow: ellipsis; }
+  #requests tr { cursor: pointer; }
+  #requests tr:hover { background: #f6f8fa; }
+  #requests tr.selected { background: #fff5b1; }
+  .s2 { color: #28a745; } .s3 { color: #0366d6; } .s4 { color: #e36209; } .s5 { color: #d73a49; }
+  #detail .content { padding: 0 12px; }
+  #detail h3 { font-size: 13px; margin: 16px 0 4px; }
+  #detail dl { display: grid; grid-template-columns: max-content auto; gap: 2px 12px; margin: 0; font-family: monospace; }
+  #detail dt { color: #586069; }
+  #detail dd { margin: 0; word-break: break-all; }
+  pre { margin: 0; padding: 8px; background: #f6f8fa; border-radius: 3px; white-space: pre-wrap; word-break: break-all; }
+  .empty { padding: 8px 12px; color: #586069; }
+</style>
+</head>
+<body>
+<header>
+  <h1>MocKuma Inspector</h1>
+  <span id="status" class="status off">disconnected</span>
+  <button id="clear">Clear</button>
+</header>
+<main>
+  <section id="mappings"><h2>Mappings</h2><ul id="mapping-list"></ul></section>
+  <section id="requests"><h2>Requests</h2><table><tbody id="request-list"></tbody></table></section>
+  <section id="detail"><h2>Detail</h2><div class="content" id="detail-content">
+    <p class="empty">Select a request to see its detail.</p></div></section>
+</main>
+<script>
+(function () {
+  "use strict";
+
+  var requestList = document.getElementById("request-list");
+  var detail = document.getElementById("detail-content");
+  var status = document.getElementById("status");
+  var selected = null;
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) { e.setAttribute(k, attrs[k]); });
+    (children || []).forEach(function (c) {
+      e.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+    });
+    return e;
+  }
+
+  function getJSON(url) {
+    return fetch(url).then(function (resp) {
+      if (!resp.ok) { throw new Error(resp.status + " " + resp.statusText); }
+      return resp.json();
+    });
+  }
+
+  function loadMappings() {
+    var list = document.getElementById("mapping-list");
+    getJSON("mappings").then(function (mappings) {
+      list.innerHTML = "";
+      mappings.forEach(function (m) {
+        var methods = m.methods.map(function (method) { return el("span", {"class": "method"}, [method]); });
+        list.appendChild(el("li", {}, methods.concat([m.uri])));
+      });
+      if (mappings.length === 0) { list.appendChild(el("li", {"class": "empty"}, ["no mappings"])); }
+    });
+  }
+
+  function addRequest(req) {
+    var policy = req.policy === undefined ? "-" : "#" + req.policy;
+    var row = el("tr", {"data-seq": req.seq}, [
+      el("td", {}, [new Date(req.time).toLocaleTimeString()]),
+      el("td", {"class": "s" + String(req.status).charAt(0)}, [String(req.status)]),
+      el("td", {}, [req.method]),
+      el("td", {"class": "url", "title": req.url}, [req.url]),
+      el("td", {}, [(req.command || "-") + " " + policy]),
+      el("td", {}, [req.durationMs.toFixed(1) + "ms"])
+    ]);
+    row.addEventListener("click", function () { showDetail(req.seq, row); });
+    requestList.insertBefore(row, requestList.firstChild);
+  }
+
+  function definitions(pairs) {
+    var dl = el("dl");
+    pairs.forEach(function (p) {
+      if (p[1] === undefined || p[1] === null || p[1] === "") { return; }
+      dl.appendChild(el("dt", {}, [p[0]]));
+      dl.appendChild(el("dd", {}, [String(p[1])]));
+    });
+    return dl;
+  }
+
+  function message(title, msg) {
+    var headers = Object.keys(msg.header || {}).sort().map(function (name) {
+      return [name, msg.header[name].join(", ")];
+    });
+    var body = msg.body + (msg.truncated ? "\n... (truncated)" : "");
+    return [el("h3", {}, [title + " Headers"]), definitions(headers),
+      el("h3", {}, [title + " Body"]), el("pre", {}, [body || "(empty)"])];
+  }
+
+  function showDetail(seq, row) {
+    if (selected) { selected.classList.remove("selected"); }
+    selected = row;
+    row.classList.add("selected");
+
+    getJSON("requests/" + seq).then(function (req) {
+      detail.innerHTML = "";
+      detail.appendChild(el("h3", {}, ["General"]));
+      detail.appendChild(definitions([
+        ["Request ID", req.requestId], ["Time", req.time], ["Method", req.method], ["URL", req.url],
+        ["Remote Address", req.remoteAddr], ["Mapping", req.mapping], ["Policy", req.policy],
+        ["Command", req.command], ["Status", req.status], ["Bytes", req.bytes],
+        ["Duration", req.durationMs.toFixed(3) + "ms"], ["Latency", req.latencyMs.toFixed(3) + "ms"],
+        ["Forward Target", req.forwardTarget], ["Error", req.error]
+      ]));
+      message("Request", req.request).forEach(function (e) { detail.appendChild(e); });
+      message("Response", req.response).forEach(function (e) { detail.appendChild(e); });
+    }).catch(function (err) {
+      detail.innerHTML = "";
+      detail.appendChild(el("p", {"class": "empty"}, ["Request #" + seq + " is no longer available: " + err.message]));
+    });
+  }
+
+  function connect() {
+    var lastSeq = 0;
+    getJSON("requests").then(function (reqs) {
+      requestList.innerHTML = "";
+      reqs.forEach(function (req) { addRequest(req); lastSeq = req.seq; });
+
+      var source = new EventSource("requests/stream");
+      source.onopen = function () {
+        status.className = "status on";
+        status.textContent = "live";
+        loadMappings();
+      };
+      source.addEventListener("request", function (e) {
+        var req = JSON.parse(e.data);
+        if (req.seq > lastSeq) {
+          addRequest(req);
+          lastSeq = req.seq;
+        }
+      });
+      source.onerror = function () {
+        status.className = "status off";
+        status.textContent = "reconnecting";
+      };
+    });
+  }
+
+  document.getElementById("clear").addEventListener("click", function () {
+    requestList.innerHTML = "";
+    selected = null;
+  });
+
+  loadMappings();
+  connect();
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestExchange(method, target, reqBody, respBody string) *exchange {
+	r := httptest.NewRequest(method, target, strings.NewReader(reqBody))
+	x := newExchange(httptest.NewRecorder(), r, inspectorBodyLimit)
+	x.e = &policyExecutor{r: r, policyIdx: -1}
+	x.w.Header().Set("Content-Type", "text/plain")
+	_, _ = x.w.Write([]byte(respBody))
+	return x
+}
+
+func TestInspector_record(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	i := newInspector(2)
+	i.record(newTestExchange("GET", "/a", "", "a"))
+	i.record(newTestExchange("POST", "/b", "name=b", "b"))
+	i.record(newTestExchange("PUT", "/c", "", "c"))
+
+	recent := i.recent()
+	if assert.Len(recent, 2) {
+		assert.Equal(uint64(2), recent[0].Seq)
+		assert.Equal("/b", recent[0].URL)
+		assert.Nil(recent[0].Request)
+		assert.Nil(recent[0].Response)
+		assert.Equal(uint64(3), recent[1].Seq)
+	}
+
+	assert.Nil(i.get(1))
+	ins := i.get(2)
+	if assert.NotNil(ins) {
+		assert.Equal("POST", ins.Method)
+		assert.Equal("name=b", ins.Request.Body)
+		assert.False(ins.Request.Truncated)
+		assert.Equal("b", ins.Response.Body)
+		assert.Equal("text/plain", ins.Response.Header.Get("Content-Type"))
+	}
+}
+
+func TestInspector_subscribe(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	i := newInspector(10)
+	s1 := i.subscribe()
+	s2 := i.subscribe()
+	i.unsubscribe(s2)
+
+	i.record(newTestExchange("GET", "/a", "", "a"))
+	select {
+	case ins := <-s1.ch:
+		assert.Equal(uint64(1), ins.Seq)
+		assert.Nil(ins.Request)
+	default:
+		assert.Fail("request not broadcast")
+	}
+	assert.Len(s2.ch, 0)
+
+	i.disconnectAll()
+	_, ok := <-s1.done
+	assert.False(ok)
+	assert.Empty(i.subscribers)
+}
@@ -3,48 +3,89 @@ package server
 import (
 	"bytes"
 	"io/ioutil"
-	"log"
+	"math/rand"
+	"mime/multipart"
 	"net/http"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myjwt"
+	"github.com/kumasuke120/mockuma/internal/myxml"
 )
 
 type pathMatcher struct {
 	mappings    *mckmaps.MockuMappings
 	directPath  map[string][]*mckmaps.Mapping
-	patternPath map[*regexp.Regexp][]*mckmaps.Mapping
+	patternPath []*uriPattern // sorted by precedence
+	calls       *callCounter
 }
 
-var pathVarRegexp = regexp.MustCompile(`{(\d+)}`)
+// the maximum bytes of a multipart form stored in memory, the rest of which
+// are stored in temporary files
+const multipartMaxMemory = 32 << 20
 
 func newPathMatcher(mappings *mckmaps.MockuMappings) *pathMatcher {
 	directPath := make(map[string][]*mckmaps.Mapping)
-	patternPath := make(map[*regexp.Regexp][]*mckmaps.Mapping)
+	var patternPath []*uriPattern
+	patternOfURI := make(map[string]*uriPattern)
 	for _, m := range mappings.Mappings {
-		if theURI := pathVarRegexp.ReplaceAllString(m.URI, "(?P<v$1>.*?)"); theURI == m.URI {
-			mappingsOfURI := directPath[theURI]
+		if pattern := mckmaps.URIPattern(m.URI); pattern == "" {
+			mappingsOfURI := directPath[m.URI]
 			mappingsOfURI = append(mappingsOfURI, m)
-			directPath[theURI] = mappingsOfURI
+			directPath[m.URI] = mappingsOfURI
 		} else {
-			regexpURI := regexp.MustCompile("^" + theURI + "$")
-			mappingsOfURI := patternPath[regexpURI]
-			mappingsOfURI = append(mappingsOfURI, m)
-			patternPath[regexpURI] = mappingsOfURI
+			up, ok := patternOfURI[m.URI]
+			if !ok {
+				up = newURIPattern(m.URI, pattern)
+				patternOfURI[m.URI] = up
+				patternPath = append(patternPath, up)
+			}
+			up.add(m)
 		}
 	}
+	sortURIPatterns(patternPath)
 
 	return &pathMatcher{
 		mappings:    mappings,
 		directPath:  directPath,
 		patternPath: patternPath,
+		calls:       newCallCounter(),
+	}
+}
+
+// counts the calls of each policy, the counts are reset when mappings are reloaded
+type callCounter struct {
+	mux    sync.Mutex
+	counts map[*mckmaps.Policy]int
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{counts: make(map[*mckmaps.Policy]int)}
+}
+
+// takes a call of the given policy, returns the 0-based index of the call, or
+// false if the policy has been called as many times as its limit
+func (c *callCounter) take(p *mckmaps.Policy) (int, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	n := c.counts[p]
+	if p.Times > 0 && n >= p.Times {
+		return n, false
 	}
+	c.counts[p] = n + 1
+	return n, true
 }
 
 func (m *pathMatcher) bind(r *http.Request) *boundMatcher {
-	return &boundMatcher{m: m, r: r, conf: m.mappings.Config}
+	return &boundMatcher{m: m, r: r, conf: m.mappings.Config, matchedPolicyIdx: -1, matchedCallIdx: -1}
 }
 
 type boundMatcher struct {
@@ -55,9 +96,21 @@ type boundMatcher struct {
 	uri        string
 	uriPattern *regexp.Regexp
 
-	matchedMapping *mckmaps.Mapping
-	matchState     matchState
-	bodyCache      []byte
+	matchedMapping   *mckmaps.Mapping
+	matchedPolicyIdx int
+	matchedCallIdx   int // the 0-based index of the call to the matched policy
+	matchState       matchState
+	bodyCache        []byte
+	bodyJSONCache    interface{} // the body parsed as JSON once, see bodyJSON()
+	bodyJSONErr      error
+	bodyJSONParsed   bool
+	bodyXMLCache     *xmlquery.Node // the body parsed as XML once, see bodyXML()
+	bodyXMLErr       error
+	bodyXMLParsed    bool
+	multipartErr     error // the error of parsing multipart form once, see multipartForm()
+	multipartParsed  bool
+	grpcJSONCache    []byte          // the request message in JSON, for gRPC mappings
+	graphQLCache     *graphQLRequest // the parsed request, for GraphQL mappings
 }
 
 type matchState int
@@ -117,17 +170,28 @@ func (bm *boundMatcher) matchURIDirect() (pm []*mckmaps.Mapping) {
 	return
 }
 
+// finds the first pattern matching the uri whose mappings accept the method,
+// or the first matching one if none accepts, as patterns are sorted by precedence
 func (bm *boundMatcher) matchURIPattern() (pm []*mckmaps.Mapping, pp *regexp.Regexp) {
-	for pattern, mappingsOfURI := range bm.m.patternPath { // matching for pattern path
-		if pattern.MatchString(bm.uri) {
-			pm = mappingsOfURI
-			pp = pattern
-		} else if bm.conf.MatchTrailingSlash && pattern.MatchString(bm.uri+"/") {
-			bm.uri += "/"
-			pm = mappingsOfURI
-			pp = pattern
+	uri := bm.uri
+	for _, up := range bm.m.patternPath {
+		theURI := bm.uri
+		if !up.regexp.MatchString(theURI) {
+			if !bm.conf.MatchTrailingSlash || !up.regexp.MatchString(theURI+"/") { // matches /path to /path/
+				continue
+			}
+			theURI += "/"
+		}
+
+		if bm.matchByMethod(up.mappings) != nil || bm.matchHead(up.mappings) != nil {
+			bm.uri = theURI
+			return up.mappings, up.regexp
+		}
+		if pm == nil {
+			uri, pm, pp = theURI, up.mappings, up.regexp
 		}
 	}
+	bm.uri = uri
 	return
 }
 
@@ -184,33 +248,33 @@ func (bm *boundMatcher) matchExactPolicy() *mckmaps.Policy {
 
 	err := bm.r.ParseForm()
 	if err != nil {
-		log.Println("[server  ] fail to parse form:", err)
+		serverLog.Warnf("fail to parse form: %v", err)
 		return nil
 	}
 
+	strict := bm.paramsMode() == mckmaps.ParamsStrict
 	var policy *mckmaps.Policy
-	for _, p := range bm.matchedMapping.Policies {
-		when := p.When
-
-		if when != nil {
-			if bm.uriPattern != nil && !bm.pathVarsMatch(when) {
-				continue
-			}
+	for idx, p := range bm.matchedMapping.Policies {
+		if p.When != nil && !bm.whenMatches(p.When) {
+			continue
+		}
 
-			if !bm.paramsMatch(when) {
-				continue
-			}
+		if strict && !bm.onlyDeclaredParams(p.When) {
+			continue
+		}
 
-			if !bm.headersMatch(when) {
-				continue
-			}
+		if !probabilityMatches(p) {
+			continue
+		}
 
-			if !bm.bodyMatches(when) {
-				continue
-			}
+		callIdx, ok := bm.m.calls.take(p)
+		if !ok { // the policy has reached its call limit
+			continue
 		}
 
 		policy = p
+		bm.matchedPolicyIdx = idx
+		bm.matchedCallIdx = callIdx
 		break
 	}
 
@@ -220,6 +284,82 @@ func (bm *boundMatcher) matchExactPolicy() *mckmaps.Policy {
 	return policy
 }
 
+// tests all conditions of the when, including the nested ones of combinators
+func (bm *boundMatcher) whenMatches(when *mckmaps.When) bool {
+	if bm.uriPattern != nil && !bm.pathVarsMatch(when) {
+		return false
+	}
+
+	if !bm.paramsMatch(when) {
+		return false
+	}
+
+	if !bm.headersMatch(when) {
+		return false
+	}
+
+	if !bm.cookiesMatch(when) {
+		return false
+	}
+
+	if !bm.bodyMatches(when) {
+		return false
+	}
+
+	if !bm.bodyPathsMatch(when) {
+		return false
+	}
+
+	if !bm.bodyXPathMatch(when) {
+		return false
+	}
+
+	if !bm.filesMatch(when) {
+		return false
+	}
+
+	if when.Auth != nil && !bm.authMatches(when.Auth) {
+		return false
+	}
+
+	if when.GraphQL != nil && !bm.graphQLRequest().matches(when.GraphQL) {
+		return false
+	}
+
+	for _, w := range when.AllOf {
+		if !bm.whenMatches(w) {
+			return false
+		}
+	}
+
+	if len(when.AnyOf) != 0 && !bm.anyWhenMatches(when.AnyOf) {
+		return false
+	}
+
+	if when.Not != nil && bm.whenMatches(when.Not) {
+		return false
+	}
+
+	return true
+}
+
+func (bm *boundMatcher) anyWhenMatches(whens []*mckmaps.When) bool {
+	for _, w := range whens {
+		if bm.whenMatches(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// rolls the dice for policies with a probability
+func probabilityMatches(p *mckmaps.Policy) bool {
+	if p.Probability == nil {
+		return true
+	}
+	return rand.Float64() < *p.Probability
+}
+
 func (bm *boundMatcher) cacheBody() {
 	body, err := ioutil.ReadAll(bm.r.Body)
 	if err == nil {
@@ -228,6 +368,34 @@ func (bm *boundMatcher) cacheBody() {
 	}
 }
 
+// decodes the request message as JSON, returns nil if the message is malformed
+func (bm *boundMatcher) grpcRequestJSON() []byte {
+	if bm.grpcJSONCache == nil {
+		message, err := readGRPCMessage(bm.bodyCache, grpcProtocolOf(bm.r))
+		if err == nil {
+			bm.grpcJSONCache, err = bm.matchedMapping.GRPC.DecodeRequest(message)
+		}
+		if err != nil {
+			serverLog.Warnf("fail to decode gRPC message: %v", err)
+			bm.grpcJSONCache = []byte{}
+		}
+	}
+	return bm.grpcJSONCache
+}
+
+// parses the request as a GraphQL request, which never matches if malformed
+func (bm *boundMatcher) graphQLRequest() *graphQLRequest {
+	if bm.graphQLCache == nil {
+		req, err := parseGraphQLRequest(bm.r, bm.bodyCache)
+		if err != nil {
+			serverLog.Warnf("fail to parse GraphQL request: %v", err)
+			req = new(graphQLRequest)
+		}
+		bm.graphQLCache = req
+	}
+	return bm.graphQLCache
+}
+
 func (bm *boundMatcher) resetBodyFromCache() {
 	if bm.bodyCache != nil {
 		bm.r.Body = ioutil.NopCloser(bytes.NewReader(bm.bodyCache))
@@ -260,8 +428,27 @@ func (bm *boundMatcher) extractPathVars() map[string][]string {
 	return pathVars
 }
 
+// the mode of the matched mapping if declared, or the one in the config
+func (bm *boundMatcher) paramsMode() mckmaps.ParamsMode {
+	if bm.matchedMapping.MatchParams != "" {
+		return bm.matchedMapping.MatchParams
+	}
+	if bm.conf.MatchParams != "" {
+		return bm.conf.MatchParams
+	}
+	return mckmaps.ParamsExact
+}
+
 func (bm *boundMatcher) paramsMatch(when *mckmaps.When) bool {
-	if !valuesMatch(when.Params, bm.r.Form) {
+	if len(when.Params) != 0 || len(when.ParamRegexps) != 0 || len(when.ParamJSONs) != 0 {
+		_, _ = bm.multipartForm() // adds text parts to the form
+	}
+
+	if bm.paramsMode() == mckmaps.ParamsPartial {
+		if !valuesContained(when.Params, bm.r.Form) {
+			return false
+		}
+	} else if !valuesMatch(when.Params, bm.r.Form) {
 		return false
 	}
 	if !regexpsMatch(when.ParamRegexps, bm.r.Form) {
@@ -274,6 +461,45 @@ func (bm *boundMatcher) paramsMatch(when *mckmaps.When) bool {
 	return true
 }
 
+// tests if all params of the request are declared in the when or its nested ones,
+// a nil when declares no params
+func (bm *boundMatcher) onlyDeclaredParams(when *mckmaps.When) bool {
+	declared := make(map[string]bool)
+	addParamNames(declared, when)
+
+	_, _ = bm.multipartForm() // adds text parts to the form
+	for name := range bm.r.Form {
+		if !declared[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func addParamNames(names map[string]bool, when *mckmaps.When) {
+	if when == nil {
+		return
+	}
+
+	for _, p := range when.Params {
+		names[p.Name] = true
+	}
+	for _, p := range when.ParamRegexps {
+		names[p.Name] = true
+	}
+	for _, p := range when.ParamJSONs {
+		names[p.Name] = true
+	}
+
+	for _, w := range when.AllOf {
+		addParamNames(names, w)
+	}
+	for _, w := range when.AnyOf {
+		addParamNames(names, w)
+	}
+	addParamNames(names, when.Not)
+}
+
 func (bm *boundMatcher) headersMatch(when *mckmaps.When) bool {
 	if !valuesMatch(when.Headers, bm.r.Header) {
 		return false
@@ -284,27 +510,303 @@ func (bm *boundMatcher) headersMatch(when *mckmaps.When) bool {
 	if !asJSONsMatch(when.HeaderJSONs, bm.r.Header) {
 		return false
 	}
+	if !modesMatch(when.HeaderModes, bm.r.Header) {
+		return false
+	}
+
+	return true
+}
+
+func (bm *boundMatcher) cookiesMatch(when *mckmaps.When) bool {
+	if len(when.Cookies) == 0 && len(when.CookieRegexps) == 0 && len(when.CookieJSONs) == 0 {
+		return true
+	}
+
+	cookies := make(map[string][]string)
+	for _, c := range bm.r.Cookies() {
+		cookies[c.Name] = append(cookies[c.Name], c.Value)
+	}
+
+	if !valuesMatch(when.Cookies, cookies) {
+		return false
+	}
+	if !regexpsMatch(when.CookieRegexps, cookies) {
+		return false
+	}
+	if !asJSONsMatch(when.CookieJSONs, cookies) {
+		return false
+	}
 
 	return true
 }
 
+func (bm *boundMatcher) authMatches(auth *mckmaps.Auth) bool {
+	if auth.Basic != nil {
+		username, password, ok := bm.r.BasicAuth()
+		if !ok {
+			return false
+		}
+		credentials := myjson.Object{"username": myjson.String(username), "password": myjson.String(password)}
+		if !auth.Basic.Matches(credentials) {
+			return false
+		}
+	}
+
+	if auth.Bearer == nil && auth.JWT == nil {
+		return true
+	}
+	token, ok := bearerToken(bm.r)
+	if !ok {
+		return false
+	}
+	if auth.Bearer != nil && !auth.Bearer.Matches(myjson.String(token)) {
+		return false
+	}
+	if auth.JWT != nil && !jwtMatches(auth.JWT, token) {
+		return false
+	}
+
+	return true
+}
+
+// extracts the token from the header 'Authorization: Bearer <token>'
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	authorization := r.Header.Get(myhttp.HeaderAuthorization)
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(authorization[len(prefix):]), true
+}
+
+// the signature and time-based claims are verified only if a key is given
+func jwtMatches(m *mckmaps.JWTMatcher, rawToken string) bool {
+	token, err := myjwt.Parse(rawToken)
+	if err != nil {
+		return false
+	}
+
+	var key interface{}
+	if m.Secret != nil {
+		key = m.Secret
+	} else if m.PublicKey != nil {
+		key = m.PublicKey
+	}
+	if key != nil {
+		if token.Verify(key) != nil || token.Validate(time.Now()) != nil {
+			return false
+		}
+	}
+
+	return m.Claims == nil || m.Claims.Matches(token.Claims)
+}
+
 func (bm *boundMatcher) bodyMatches(when *mckmaps.When) bool {
 	body := bm.bodyCache
+	if bm.matchedMapping.GRPC != nil {
+		body = bm.grpcRequestJSON()
+	}
 	if when.Body != nil {
 		return bytes.Equal(when.Body, body)
 	} else if when.BodyRegexp != nil {
 		return when.BodyRegexp.Match(body)
 	} else if when.BodyJSON != nil {
-		json, err := myjson.Unmarshal(body)
+		json, err := bm.bodyJSON()
 		if err != nil {
 			return false
 		}
 		return when.BodyJSON.Matches(json)
+	} else if when.BodyXML != nil {
+		element, err := myxml.Parse(body)
+		if err != nil {
+			return false
+		}
+		return when.BodyXML.Equal(element)
 	} else {
 		return true
 	}
 }
 
+func (bm *boundMatcher) bodyPathsMatch(when *mckmaps.When) bool {
+	if len(when.BodyPaths) == 0 {
+		return true
+	}
+
+	json, err := bm.bodyJSON()
+	if err != nil {
+		return false
+	}
+	for _, bp := range when.BodyPaths {
+		if !jsonAnyMatches(bp.Matcher, bp.Path.Select(json)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parses the body as JSON, the request message is parsed instead for gRPC mappings
+func (bm *boundMatcher) bodyJSON() (interface{}, error) {
+	if !bm.bodyJSONParsed {
+		body := bm.bodyCache
+		if bm.matchedMapping.GRPC != nil {
+			body = bm.grpcRequestJSON()
+		}
+		bm.bodyJSONCache, bm.bodyJSONErr = myjson.Unmarshal(body)
+		bm.bodyJSONParsed = true
+	}
+	return bm.bodyJSONCache, bm.bodyJSONErr
+}
+
+func (bm *boundMatcher) bodyXPathMatch(when *mckmaps.When) bool {
+	if len(when.BodyXPath) == 0 {
+		return true
+	}
+
+	doc, err := bm.bodyXML()
+	if err != nil {
+		return false
+	}
+	for _, xm := range when.BodyXPath {
+		if !xpathMatches(xm, doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (bm *boundMatcher) bodyXML() (*xmlquery.Node, error) {
+	if !bm.bodyXMLParsed {
+		bm.bodyXMLCache, bm.bodyXMLErr = xmlquery.Parse(bytes.NewReader(bm.bodyCache))
+		bm.bodyXMLParsed = true
+	}
+	return bm.bodyXMLCache, bm.bodyXMLErr
+}
+
+// evaluates the expression on the document, a node-set matches if any of
+// its nodes matches, other results are matched in their string forms
+func xpathMatches(xm *mckmaps.XPathMatcher, doc *xmlquery.Node) bool {
+	switch result := xm.Expr.Evaluate(xmlquery.CreateXPathNavigator(doc)); result.(type) {
+	case *xpath.NodeIterator:
+		iter := result.(*xpath.NodeIterator)
+		for iter.MoveNext() {
+			if xpathValueMatches(xm, iter.Current().Value()) {
+				return true
+			}
+		}
+		return false
+	case float64:
+		return xpathValueMatches(xm, myjson.Number(result.(float64)).String())
+	case bool:
+		return xpathValueMatches(xm, myjson.Boolean(result.(bool)).String())
+	case string:
+		return xpathValueMatches(xm, result.(string))
+	default:
+		return false
+	}
+}
+
+func xpathValueMatches(xm *mckmaps.XPathMatcher, value string) bool {
+	if xm.Regexp != nil {
+		return xm.Regexp.MatchString(value)
+	}
+	return xm.Value == value
+}
+
+func (bm *boundMatcher) filesMatch(when *mckmaps.When) bool {
+	if len(when.Files) == 0 {
+		return true
+	}
+
+	form, err := bm.multipartForm()
+	if err != nil {
+		return false
+	}
+	for _, fm := range when.Files {
+		if !fileAnyMatches(fm, form.File[fm.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parses the multipart form once, whose text parts are added to the form of
+// the request; only files are matched against it, a malformed one matches no files
+func (bm *boundMatcher) multipartForm() (*multipart.Form, error) {
+	if !bm.multipartParsed {
+		// the body has been cached and will be reset after matching
+		bm.multipartErr = bm.r.ParseMultipartForm(multipartMaxMemory)
+		if bm.multipartErr != nil && bm.multipartErr != http.ErrNotMultipart {
+			serverLog.Warnf("fail to parse multipart form: %v", bm.multipartErr)
+		}
+		bm.multipartParsed = true
+	}
+	return bm.r.MultipartForm, bm.multipartErr
+}
+
+func fileAnyMatches(fm *mckmaps.FileMatcher, headers []*multipart.FileHeader) bool {
+	for _, fh := range headers {
+		if fileMatches(fm, fh) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileMatches(fm *mckmaps.FileMatcher, fh *multipart.FileHeader) bool {
+	if !stringMatches(fm.Filename, fm.FilenameRegexp, fh.Filename) {
+		return false
+	}
+	if !stringMatches(fm.ContentType, fm.ContentTypeRegexp, fh.Header.Get(myhttp.HeaderContentType)) {
+		return false
+	}
+	if fm.Size != nil && (fh.Size < fm.Size.Min || fh.Size > fm.Size.Max) {
+		return false
+	}
+
+	if fm.Content != "" || fm.ContentRegexp != nil {
+		content, err := readFileContent(fh)
+		if err != nil {
+			serverLog.Warnf("fail to read uploaded file '%s': %v", fh.Filename, err)
+			return false
+		}
+		if !stringMatches(fm.Content, fm.ContentRegexp, string(content)) {
+			return false
+		}
+	}
+	return true
+}
+
+func readFileContent(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// matches the value by the regexp if set, or by the expected one if not empty
+func stringMatches(expected string, r *regexp.Regexp, value string) bool {
+	if r != nil {
+		return r.MatchString(value)
+	}
+	return expected == "" || expected == value
+}
+
+// tests if any of the values matches, or if a missing value matches when
+// there are no values
+func jsonAnyMatches(m myjson.ExtJSONMatcher, values []interface{}) bool {
+	if len(values) == 0 {
+		return m.MatchesMissing()
+	}
+	for _, v := range values {
+		if m.Matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
 func valuesMatch(expected []*mckmaps.NameValuesPair, actual map[string][]string) bool {
 	for _, e := range expected {
 		formValues := actual[e.Name]
@@ -317,6 +819,36 @@ func valuesMatch(expected []*mckmaps.NameValuesPair, actual map[string][]string)
 	return true
 }
 
+// tests if actual values contain all expected ones, extra values are allowed
+func valuesContained(expected []*mckmaps.NameValuesPair, actual map[string][]string) bool {
+	for _, e := range expected {
+		formValues := actual[e.Name]
+
+		if !stringSliceContains(formValues, e.Values) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tests if l contains all elements of r, counting duplicates
+func stringSliceContains(l, r []string) bool {
+	count := make(map[string]int, len(l))
+	for _, _x := range l {
+		count[_x]++
+	}
+
+	for _, _y := range r {
+		if count[_y] == 0 {
+			return false
+		}
+		count[_y] -= 1
+	}
+
+	return true
+}
+
 // tests if two []string share same elements, ignoring the order
 func stringSlicesEqualIgnoreOrder(l, r []string) bool {
 	if len(l) != len(r) {
@@ -363,6 +895,57 @@ func regexpAnyMatches(r *regexp.Regexp, values []string) bool {
 	return false
 }
 
+func modesMatch(expected []*mckmaps.NameModePair, actual map[string][]string) bool {
+	for _, e := range expected {
+		values := actual[e.Name]
+
+		var ok bool
+		switch e.Mode {
+		case mckmaps.ValueContains:
+			ok = anyValueMatches(values, func(v string) bool { return strings.Contains(v, e.Values[0]) })
+		case mckmaps.ValuePrefix:
+			ok = anyValueMatches(values, func(v string) bool { return strings.HasPrefix(v, e.Values[0]) })
+		case mckmaps.ValueTokens:
+			ok = tokensMatch(e.Values, values)
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func anyValueMatches(values []string, matches func(string) bool) bool {
+	for _, v := range values {
+		if matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// tests if all expected tokens are found in comma-separated values, e.g.
+// 'application/json' in 'text/html, application/json;q=0.9'
+func tokensMatch(expected []string, values []string) bool {
+	tokens := make(map[string]bool)
+	for _, v := range values {
+		for _, token := range strings.Split(v, ",") {
+			if idx := strings.IndexByte(token, ';'); idx >= 0 {
+				token = token[:idx]
+			}
+			tokens[strings.ToLower(strings.TrimSpace(token))] = true
+		}
+	}
+
+	for _, e := range expected {
+		if !tokens[strings.ToLower(strings.TrimSpace(e))] {
+			return false
+		}
+	}
+	return true
+}
+
 func asJSONsMatch(expected []*mckmaps.NameJSONPair, actual map[string][]string) bool {
 	for _, e := range expected {
 		formValues := actual[e.Name]
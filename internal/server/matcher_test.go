@@ -1,14 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/antchfx/xpath"
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
 	"github.com/kumasuke120/mockuma/internal/myhttp"
 	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myxml"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -207,20 +218,10 @@ func TestNewPathMatcher(t *testing.T) {
 		},
 	}
 	assert.Equal(t, expectedDirectPath, matcher.directPath)
-	expectedPatternPath := map[*regexp.Regexp][]*mckmaps.Mapping{
-		regexp.MustCompile("^/p/(?P<v0>.*?)/m(?P<v1>.*?)$"): {
-			mappings.Mappings[3],
-		},
-	}
-	assert.Equal(t, formatRegexpKeyMap(expectedPatternPath), formatRegexpKeyMap(matcher.patternPath))
-}
-
-func formatRegexpKeyMap(m map[*regexp.Regexp][]*mckmaps.Mapping) map[string][]*mckmaps.Mapping {
-	result := make(map[string][]*mckmaps.Mapping, len(m))
-	for r, ms := range m {
-		result[r.String()] = ms
+	if assert.Len(t, matcher.patternPath, 1) {
+		assert.Equal(t, "^/p/(?P<v0>[^/]+)/m(?P<v1>[^/]+)$", matcher.patternPath[0].regexp.String())
+		assert.Equal(t, []*mckmaps.Mapping{mappings.Mappings[3]}, matcher.patternPath[0].mappings)
 	}
-	return result
 }
 
 func TestPathMatcher_matches(t *testing.T) {
@@ -234,6 +235,61 @@ func TestPathMatcher_matches(t *testing.T) {
 	assert.Equal(mappings.Mappings[0], bound.matchedMapping)
 }
 
+func TestPathMatcher_matches_typedPathVars(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	typedMappings := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{URI: "/books/{0:int}", Method: myhttp.MethodGet},
+			{URI: "/books/{0:[a-z-]+}", Method: myhttp.MethodGet},
+			{URI: "/users/{0:uuid}", Method: myhttp.MethodGet},
+			{URI: "/files/{0:*}", Method: myhttp.MethodGet},
+			{URI: "/tags/{0}", Method: myhttp.MethodGet},
+			{URI: "/codes/{0:[A-Z]{3}}.json", Method: myhttp.MethodGet},
+		},
+		Config: &mckmaps.Config{CORS: &mckmaps.CORSOptions{Enabled: false}},
+	}
+	matcher := newPathMatcher(typedMappings)
+
+	matched := func(target string) (*mckmaps.Mapping, map[string][]string) {
+		bound := matcher.bind(httptest.NewRequest("GET", target, nil))
+		if !bound.matches() || bound.matchState != matchExact {
+			return nil, nil
+		}
+		return bound.matchedMapping, bound.extractPathVars()
+	}
+
+	m, vars := matched("/books/-42")
+	assert.Equal(typedMappings.Mappings[0], m)
+	assert.Equal(map[string][]string{"0": {"-42"}}, vars)
+	m, vars = matched("/books/go-in-action")
+	assert.Equal(typedMappings.Mappings[1], m)
+	assert.Equal(map[string][]string{"0": {"go-in-action"}}, vars)
+	m, _ = matched("/books/Go1")
+	assert.Nil(m)
+
+	m, _ = matched("/users/123e4567-e89b-12d3-a456-426614174000")
+	assert.Equal(typedMappings.Mappings[2], m)
+	m, _ = matched("/users/123e4567")
+	assert.Nil(m)
+
+	m, vars = matched("/files/a/b/c.txt")
+	assert.Equal(typedMappings.Mappings[3], m)
+	assert.Equal(map[string][]string{"0": {"a/b/c.txt"}}, vars)
+
+	m, _ = matched("/tags/go")
+	assert.Equal(typedMappings.Mappings[4], m)
+	m, _ = matched("/tags/go/java")
+	assert.Nil(m)
+
+	m, vars = matched("/codes/USD.json")
+	assert.Equal(typedMappings.Mappings[5], m)
+	assert.Equal(map[string][]string{"0": {"USD"}}, vars)
+	m, _ = matched("/codes/USDxjson")
+	assert.Nil(m)
+}
+
 func TestPathMatcher_matchPolicy(t *testing.T) {
 	//noinspection GoImportUsedAsName
 	assert := assert.New(t)
@@ -345,3 +401,599 @@ func TestPathMatcher_matchPolicy(t *testing.T) {
 	assert.Equal(matchState(matchHead), bound15.matchState)
 	assert.Equal(mappings.Mappings[1].Policies[1], bound15.matchPolicy())
 }
+
+func TestCallCounter_take(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	c := newCallCounter()
+	p1 := &mckmaps.Policy{Times: 2}
+	p2 := &mckmaps.Policy{}
+
+	for i := 0; i < 2; i++ {
+		n, ok := c.take(p1)
+		assert.True(ok)
+		assert.Equal(i, n)
+	}
+	_, ok := c.take(p1)
+	assert.False(ok)
+
+	for i := 0; i < 3; i++ {
+		n, ok := c.take(p2)
+		assert.True(ok)
+		assert.Equal(i, n)
+	}
+}
+
+func TestBoundMatcher_bodyPathsMatch(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	parsePath := func(s string) *myjson.Path {
+		p, err := myjson.ParsePath(s)
+		if err != nil {
+			panic(err)
+		}
+		return p
+	}
+	mappingsWithBodyPaths := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/orders",
+				Method: myhttp.MethodPost,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							BodyPaths: []*mckmaps.BodyPathMatcher{
+								{
+									Path:    parsePath("$..price"),
+									Matcher: myjson.MakeExtJSONMatcher(myjson.Number(0)),
+								},
+								{
+									Path:    parsePath("$.items[0].id"),
+									Matcher: myjson.MakeExtJSONMatcher(myjson.ExtRegexp(regexp.MustCompile("^a"))),
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				URI:    "/carts",
+				Method: myhttp.MethodPost,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							BodyPaths: []*mckmaps.BodyPathMatcher{
+								{
+									Path:    parsePath("$.coupon"),
+									Matcher: myjson.MakeExtJSONMatcher(myjson.Object{"@exists": myjson.Boolean(false)}),
+								},
+								{
+									Path:    parsePath("$.note"),
+									Matcher: myjson.MakeExtJSONMatcher(myjson.Object{"@not": myjson.String("x")}),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithBodyPaths)
+
+	body1 := `{"items": [{"id": "a1", "price": 10}, {"id": "b2", "detail": {"price": 0}}]}`
+	bound1 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body1)))
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithBodyPaths.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	body2 := `{"items": [{"id": "b1", "price": 0}]}`
+	bound2 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body2)))
+	assert.True(bound2.matches())
+	assert.Equal(pNoPolicyMatched, bound2.matchPolicy())
+
+	body3 := `{"items": [{"id": "a1", "price": 10}]}`
+	bound3 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body3)))
+	assert.True(bound3.matches())
+	assert.Equal(pNoPolicyMatched, bound3.matchPolicy())
+
+	bound4 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader("not a json")))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+
+	// paths selecting nothing are matched as missing values
+	bound5 := matcher.bind(httptest.NewRequest("POST", "/carts", strings.NewReader(`{}`)))
+	assert.True(bound5.matches())
+	assert.Equal(mappingsWithBodyPaths.Mappings[1].Policies[0], bound5.matchPolicy())
+
+	bound6 := matcher.bind(httptest.NewRequest("POST", "/carts", strings.NewReader(`{"coupon": "c1"}`)))
+	assert.True(bound6.matches())
+	assert.Equal(pNoPolicyMatched, bound6.matchPolicy())
+
+	bound7 := matcher.bind(httptest.NewRequest("POST", "/carts", strings.NewReader(`{"note": "x"}`)))
+	assert.True(bound7.matches())
+	assert.Equal(pNoPolicyMatched, bound7.matchPolicy())
+}
+
+func TestBoundMatcher_bodyXMLMatch(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	parseXML := func(s string) *myxml.Element {
+		e, err := myxml.Parse([]byte(s))
+		if err != nil {
+			panic(err)
+		}
+		return e
+	}
+	mappingsWithXML := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/orders",
+				Method: myhttp.MethodPost,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							BodyXPath: []*mckmaps.XPathMatcher{
+								{Expr: xpath.MustCompile("//item/price"), Value: "0"},
+								{Expr: xpath.MustCompile("/order/@id"), Regexp: regexp.MustCompile("^a")},
+								{Expr: xpath.MustCompile("count(//item) > 1"), Value: "true"},
+							},
+						},
+					},
+					{
+						When: &mckmaps.When{
+							BodyXML: parseXML(`<order id="a1" status="new"><item>book</item></order>`),
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithXML)
+
+	body1 := `<order id="a1"><item><price>10</price></item><item><price>0</price></item></order>`
+	bound1 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body1)))
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithXML.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	body2 := `<order id="b1"><item><price>10</price></item><item><price>0</price></item></order>`
+	bound2 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body2)))
+	assert.True(bound2.matches())
+	assert.Equal(pNoPolicyMatched, bound2.matchPolicy())
+
+	body3 := "<?xml version=\"1.0\"?>\n<order status=\"new\"  id=\"a1\">\n  <!-- one item -->\n  <item> book </item>\n</order>"
+	bound3 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body3)))
+	assert.True(bound3.matches())
+	assert.Equal(mappingsWithXML.Mappings[0].Policies[1], bound3.matchPolicy())
+
+	body4 := `<order id="a1" status="paid"><item>book</item></order>`
+	bound4 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader(body4)))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+
+	bound5 := matcher.bind(httptest.NewRequest("POST", "/orders", strings.NewReader("not a xml")))
+	assert.True(bound5.matches())
+	assert.Equal(pNoPolicyMatched, bound5.matchPolicy())
+}
+
+func TestBoundMatcher_filesMatch(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	mappingsWithFiles := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/avatars",
+				Method: myhttp.MethodPost,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							Params: []*mckmaps.NameValuesPair{
+								{Name: "user", Values: []string{"kumasuke"}},
+							},
+							Files: []*mckmaps.FileMatcher{
+								{
+									Name:           "avatar",
+									FilenameRegexp: regexp.MustCompile(`\.png$`),
+									ContentType:    "image/png",
+									Size:           &mckmaps.Interval{Min: 1, Max: 8},
+									ContentRegexp:  regexp.MustCompile("^PNG"),
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				URI:    "/uploads",
+				Method: myhttp.MethodPost,
+				Policies: []*mckmaps.Policy{
+					{When: &mckmaps.When{Files: []*mckmaps.FileMatcher{{Name: "avatar"}}}},
+					{When: &mckmaps.When{Params: []*mckmaps.NameValuesPair{{Name: "user", Values: []string{"kumasuke"}}}}},
+					{},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithFiles)
+
+	newUpload := func(user, filename, contentType, content string) *http.Request {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		_ = w.WriteField("user", user)
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="avatar"; filename="%s"`, filename))
+		h.Set("Content-Type", contentType)
+		part, _ := w.CreatePart(h)
+		_, _ = part.Write([]byte(content))
+		_ = w.Close()
+
+		r := httptest.NewRequest("POST", "/avatars", body)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+		return r
+	}
+
+	bound1 := matcher.bind(newUpload("kumasuke", "me.png", "image/png", "PNG..."))
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithFiles.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	bound2 := matcher.bind(newUpload("someone", "me.png", "image/png", "PNG..."))
+	assert.True(bound2.matches())
+	assert.Equal(pNoPolicyMatched, bound2.matchPolicy())
+
+	bound3 := matcher.bind(newUpload("kumasuke", "me.jpg", "image/png", "PNG..."))
+	assert.True(bound3.matches())
+	assert.Equal(pNoPolicyMatched, bound3.matchPolicy())
+
+	bound4 := matcher.bind(newUpload("kumasuke", "me.png", "image/jpeg", "PNG..."))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+
+	bound5 := matcher.bind(newUpload("kumasuke", "me.png", "image/png", "PNG......."))
+	assert.True(bound5.matches())
+	assert.Equal(pNoPolicyMatched, bound5.matchPolicy())
+
+	bound6 := matcher.bind(newUpload("kumasuke", "me.png", "image/png", "GIF..."))
+	assert.True(bound6.matches())
+	assert.Equal(pNoPolicyMatched, bound6.matchPolicy())
+
+	bound7 := matcher.bind(httptest.NewRequest("POST", "/avatars?user=kumasuke", nil))
+	assert.True(bound7.matches())
+	assert.Equal(pNoPolicyMatched, bound7.matchPolicy())
+
+	// a malformed multipart form fails files only
+	newMalformed := func(target string) *http.Request {
+		r := httptest.NewRequest("POST", target, strings.NewReader("--x\r\n"))
+		r.Header.Set("Content-Type", "multipart/form-data")
+		return r
+	}
+	bound8 := matcher.bind(newMalformed("/uploads?user=kumasuke"))
+	assert.True(bound8.matches())
+	assert.Equal(mappingsWithFiles.Mappings[1].Policies[1], bound8.matchPolicy())
+	bound9 := matcher.bind(newMalformed("/uploads"))
+	assert.True(bound9.matches())
+	assert.Equal(mappingsWithFiles.Mappings[1].Policies[2], bound9.matchPolicy())
+
+	// multipart forms are parsed only if needed
+	noFiles := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{URI: "/avatars", Method: myhttp.MethodPost, Policies: []*mckmaps.Policy{{}}},
+		},
+		Config: &mckmaps.Config{CORS: &mckmaps.CORSOptions{Enabled: false}},
+	}
+	bound10 := newPathMatcher(noFiles).bind(newUpload("kumasuke", "me.png", "image/png", "PNG..."))
+	assert.True(bound10.matches())
+	assert.Equal(noFiles.Mappings[0].Policies[0], bound10.matchPolicy())
+	assert.False(bound10.multipartParsed)
+	assert.Nil(bound10.r.MultipartForm)
+}
+
+func TestBoundMatcher_whenMatches(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	mappingsWithCombinators := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/users/{0}",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							AnyOf: []*mckmaps.When{
+								{HeaderRegexps: []*mckmaps.NameRegexpPair{{Name: "X-Debug", Regexp: regexp.MustCompile(".+")}}},
+								{Params: []*mckmaps.NameValuesPair{{Name: "debug", Values: []string{"1"}}}},
+							},
+							Not: &mckmaps.When{
+								PathVarRegexps: []*mckmaps.NameRegexpPair{{Name: "0", Regexp: regexp.MustCompile("^admin")}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithCombinators)
+
+	r1 := httptest.NewRequest("GET", "/users/kumasuke", nil)
+	r1.Header.Set("X-Debug", "on")
+	bound1 := matcher.bind(r1)
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithCombinators.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	bound2 := matcher.bind(httptest.NewRequest("GET", "/users/kumasuke?debug=1", nil))
+	assert.True(bound2.matches())
+	assert.Equal(mappingsWithCombinators.Mappings[0].Policies[0], bound2.matchPolicy())
+
+	bound3 := matcher.bind(httptest.NewRequest("GET", "/users/kumasuke?debug=0", nil))
+	assert.True(bound3.matches())
+	assert.Equal(pNoPolicyMatched, bound3.matchPolicy())
+
+	bound4 := matcher.bind(httptest.NewRequest("GET", "/users/administrator?debug=1", nil))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+}
+
+func TestBoundMatcher_cookiesMatch(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	mappingsWithCookies := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/profile",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							Cookies:       []*mckmaps.NameValuesPair{{Name: "lang", Values: []string{"cn"}}},
+							CookieRegexps: []*mckmaps.NameRegexpPair{{Name: "session", Regexp: regexp.MustCompile("^[0-9a-f]+$")}},
+							CookieJSONs: []*mckmaps.NameJSONPair{
+								{Name: "visits", JSON: myjson.MakeExtJSONMatcher(myjson.Object{"@gte": myjson.Number(3)})},
+							},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithCookies)
+
+	newRequest := func(cookie string) *http.Request {
+		r := httptest.NewRequest("GET", "/profile", nil)
+		r.Header.Set("Cookie", cookie)
+		return r
+	}
+
+	bound1 := matcher.bind(newRequest(`lang=cn; session=abc123; visits=3`))
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithCookies.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	bound2 := matcher.bind(newRequest(`lang=en; session=abc123; visits=3`))
+	assert.True(bound2.matches())
+	assert.Equal(pNoPolicyMatched, bound2.matchPolicy())
+
+	bound3 := matcher.bind(newRequest(`lang=cn; session=xyz; visits=3`))
+	assert.True(bound3.matches())
+	assert.Equal(pNoPolicyMatched, bound3.matchPolicy())
+
+	bound4 := matcher.bind(newRequest(`lang=cn; session=abc123; visits=2`))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+
+	bound5 := matcher.bind(httptest.NewRequest("GET", "/profile", nil))
+	assert.True(bound5.matches())
+	assert.Equal(pNoPolicyMatched, bound5.matchPolicy())
+}
+
+func TestBoundMatcher_headersMatch_modes(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	mappingsWithModes := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/reports",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{
+						When: &mckmaps.When{
+							HeaderModes: []*mckmaps.NameModePair{
+								{Name: "Accept", Mode: mckmaps.ValueTokens, Values: []string{"application/json", "text/csv"}},
+								{Name: "Authorization", Mode: mckmaps.ValuePrefix, Values: []string{"Bearer "}},
+								{Name: "User-Agent", Mode: mckmaps.ValueContains, Values: []string{"Mobile"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithModes)
+
+	newRequest := func(accept, authorization, userAgent string) *http.Request {
+		r := httptest.NewRequest("GET", "/reports", nil)
+		r.Header.Add("Accept", accept)
+		r.Header.Set("Authorization", authorization)
+		r.Header.Set("User-Agent", userAgent)
+		return r
+	}
+
+	r1 := newRequest("text/html, Application/JSON;q=0.9", "Bearer abc", "Browser/1.0 (Mobile)")
+	r1.Header.Add("Accept", "text/csv;q=0.8")
+	bound1 := matcher.bind(r1)
+	assert.True(bound1.matches())
+	assert.Equal(mappingsWithModes.Mappings[0].Policies[0], bound1.matchPolicy())
+
+	bound2 := matcher.bind(newRequest("application/json, text/csv", "Bearer abc", "Browser/1.0 (Mobile)"))
+	assert.True(bound2.matches())
+	assert.Equal(mappingsWithModes.Mappings[0].Policies[0], bound2.matchPolicy())
+
+	bound3 := matcher.bind(newRequest("application/json", "Bearer abc", "Browser/1.0 (Mobile)"))
+	assert.True(bound3.matches())
+	assert.Equal(pNoPolicyMatched, bound3.matchPolicy())
+
+	bound4 := matcher.bind(newRequest("application/json, text/csv", "Basic abc", "Browser/1.0 (Mobile)"))
+	assert.True(bound4.matches())
+	assert.Equal(pNoPolicyMatched, bound4.matchPolicy())
+
+	bound5 := matcher.bind(newRequest("application/json, text/csv", "Bearer abc", "Browser/1.0"))
+	assert.True(bound5.matches())
+	assert.Equal(pNoPolicyMatched, bound5.matchPolicy())
+}
+
+func TestBoundMatcher_paramsMatch_modes(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	newMappings := func(configMode, mappingMode mckmaps.ParamsMode) *mckmaps.MockuMappings {
+		return &mckmaps.MockuMappings{
+			Mappings: []*mckmaps.Mapping{
+				{
+					URI:         "/articles",
+					Method:      myhttp.MethodGet,
+					MatchParams: mappingMode,
+					Policies: []*mckmaps.Policy{
+						{
+							When: &mckmaps.When{
+								Params: []*mckmaps.NameValuesPair{
+									{Name: "tag", Values: []string{"go"}},
+								},
+								AnyOf: []*mckmaps.When{
+									{ParamRegexps: []*mckmaps.NameRegexpPair{{Name: "page", Regexp: regexp.MustCompile(`^\d+$`)}}},
+									{},
+								},
+							},
+						},
+					},
+				},
+			},
+			Config: &mckmaps.Config{
+				CORS:        &mckmaps.CORSOptions{Enabled: false},
+				MatchParams: configMode,
+			},
+		}
+	}
+
+	policyMatches := func(m *mckmaps.MockuMappings, target string) bool {
+		bound := newPathMatcher(m).bind(httptest.NewRequest("GET", target, nil))
+		return assert.True(bound.matches()) && bound.matchPolicy() == m.Mappings[0].Policies[0]
+	}
+
+	exact := newMappings(mckmaps.ParamsExact, "")
+	assert.True(policyMatches(exact, "/articles?tag=go&page=1"))
+	assert.True(policyMatches(exact, "/articles?tag=go&sort=asc"))
+	assert.False(policyMatches(exact, "/articles?tag=go&tag=java"))
+
+	partial := newMappings(mckmaps.ParamsExact, mckmaps.ParamsPartial)
+	assert.True(policyMatches(partial, "/articles?tag=go&tag=java"))
+	assert.True(policyMatches(partial, "/articles?tag=java&tag=go&sort=asc"))
+	assert.False(policyMatches(partial, "/articles?tag=java"))
+
+	strict := newMappings(mckmaps.ParamsStrict, "")
+	assert.True(policyMatches(strict, "/articles?tag=go"))
+	assert.True(policyMatches(strict, "/articles?tag=go&page=1"))
+	assert.False(policyMatches(strict, "/articles?tag=go&sort=asc"))
+	assert.False(policyMatches(strict, "/articles?tag=go&tag=java"))
+
+	overridden := newMappings(mckmaps.ParamsStrict, mckmaps.ParamsPartial)
+	assert.True(policyMatches(overridden, "/articles?tag=go&tag=java&sort=asc"))
+
+	unset := newMappings("", "")
+	assert.False(policyMatches(unset, "/articles?tag=go&tag=java"))
+
+	whenless := newMappings(mckmaps.ParamsStrict, "")
+	whenless.Mappings[0].Policies[0].When = nil
+	assert.True(policyMatches(whenless, "/articles"))
+	assert.False(policyMatches(whenless, "/articles?x=1"))
+}
+
+func TestBoundMatcher_authMatches(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	secret := []byte("your-256-bit-secret")
+	basic := myjson.MakeExtJSONMatcher(myjson.Object{
+		"username": myjson.String("kumasuke120"),
+		"password": myjson.String("pa$$w0rd"),
+	})
+	claims := myjson.MakeExtJSONMatcher(myjson.Object{"sub": myjson.String("kumasuke120")})
+	bearer := myjson.MakeExtJSONMatcher(myjson.String("opaque-token"))
+	mappingsWithAuth := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/profile",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{When: &mckmaps.When{Auth: &mckmaps.Auth{Basic: &basic}}},
+					{When: &mckmaps.When{Auth: &mckmaps.Auth{JWT: &mckmaps.JWTMatcher{Secret: secret, Claims: &claims}}}},
+					{When: &mckmaps.When{Auth: &mckmaps.Auth{Bearer: &bearer}}},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+	matcher := newPathMatcher(mappingsWithAuth)
+
+	signHS256 := func(claims string) string {
+		signingInput := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+			base64.RawURLEncoding.EncodeToString([]byte(claims))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	matchPolicy := func(authorization string) *mckmaps.Policy {
+		r := httptest.NewRequest("GET", "/profile", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		bound := matcher.bind(r)
+		assert.True(bound.matches())
+		return bound.matchPolicy()
+	}
+	policies := mappingsWithAuth.Mappings[0].Policies
+
+	basicAuth := func(username, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+	assert.Equal(policies[0], matchPolicy(basicAuth("kumasuke120", "pa$$w0rd")))
+	assert.Equal(pNoPolicyMatched, matchPolicy(basicAuth("kumasuke120", "password")))
+	assert.Equal(pNoPolicyMatched, matchPolicy(""))
+
+	token := signHS256(`{"sub":"kumasuke120"}`)
+	assert.Equal(policies[1], matchPolicy("Bearer "+token))
+	assert.Equal(policies[1], matchPolicy("bearer "+token))
+	assert.Equal(pNoPolicyMatched, matchPolicy("Bearer "+signHS256(`{"sub":"jane.doe"}`)))
+	assert.Equal(pNoPolicyMatched, matchPolicy("Bearer "+token[:len(token)-2]+"AA")) // tampered signature
+
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	assert.Equal(policies[1], matchPolicy("Bearer "+signHS256(fmt.Sprintf(`{"sub":"kumasuke120","exp":%d}`, future))))
+	assert.Equal(pNoPolicyMatched, matchPolicy("Bearer "+signHS256(fmt.Sprintf(`{"sub":"kumasuke120","exp":%d}`, past))))
+	assert.Equal(pNoPolicyMatched, matchPolicy("Bearer "+signHS256(fmt.Sprintf(`{"sub":"kumasuke120","nbf":%d}`, future))))
+
+	assert.Equal(policies[2], matchPolicy("Bearer opaque-token"))
+	assert.Equal(pNoPolicyMatched, matchPolicy("Bearer another-token"))
+	assert.Equal(pNoPolicyMatched, matchPolicy(basicAuth("opaque-token", "")))
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/kumasuke120/mockuma/internal/metrics"
+)
+
+func recordMetrics(x *exchange) {
+	e := x.e
+	if e == nil { // served by a built-in endpoint
+		metrics.Requests.Inc(x.endpoint, x.r.Method, "", strconv.Itoa(x.w.status()))
+		metrics.RequestDuration.Observe(x.elapsed.Seconds(), x.endpoint, x.r.Method)
+		return
+	}
+
+	var uri, policy string
+	if e.mapping != nil {
+		uri = e.mapping.URI
+	}
+	if e.policyIdx >= 0 {
+		policy = strconv.Itoa(e.policyIdx)
+	}
+	method := e.r.Method
+
+	metrics.Requests.Inc(uri, method, policy, strconv.Itoa(x.w.status()))
+	metrics.RequestDuration.Observe(x.elapsed.Seconds(), uri, method)
+	if e.latency > 0 {
+		metrics.InjectedLatency.Observe(e.latency.Seconds(), uri, method)
+	}
+	if _, ok := x.err.(*forwardError); ok {
+		metrics.ForwardErrors.Inc(uri, method)
+	}
+}
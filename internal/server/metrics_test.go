@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	e1 := &policyExecutor{
+		r:         httptest.NewRequest("GET", "/TestRecordMetrics", nil),
+		mapping:   mappings.Mappings[0],
+		policyIdx: 0,
+		latency:   100 * time.Millisecond,
+	}
+	w1 := newRecordingWriter(httptest.NewRecorder())
+	w1.WriteHeader(201)
+	requests := metrics.Requests.Value("/hello", "GET", "0", "201")
+	latencies := metrics.InjectedLatency.Count("/hello", "GET")
+	forwardErrors := metrics.ForwardErrors.Value("/hello", "GET")
+	recordMetrics(&exchange{elapsed: time.Second, w: w1, e: e1, err: &forwardError{err: errors.New("test")}})
+	assert.Equal(requests+1, metrics.Requests.Value("/hello", "GET", "0", "201"))
+	assert.Equal(latencies+1, metrics.InjectedLatency.Count("/hello", "GET"))
+	assert.Equal(forwardErrors+1, metrics.ForwardErrors.Value("/hello", "GET"))
+
+	e2 := &policyExecutor{
+		r:         httptest.NewRequest("GET", "/TestRecordMetrics", nil),
+		policyIdx: -1,
+	}
+	w2 := newRecordingWriter(httptest.NewRecorder())
+	requests = metrics.Requests.Value("", "GET", "", "200")
+	recordMetrics(&exchange{elapsed: time.Second, w: w2, e: e2})
+	assert.Equal(requests+1, metrics.Requests.Value("", "GET", "", "200"))
+}
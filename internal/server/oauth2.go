@@ -0,0 +1,576 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myjwt"
+)
+
+// paths of OAuth2/OIDC endpoints, relative to the oauth2 path
+const (
+	oauth2PathDiscovery = "/.well-known/openid-configuration"
+	oauth2PathAuthorize = "/authorize"
+	oauth2PathToken     = "/token"
+	oauth2PathUserInfo  = "/userinfo"
+	oauth2PathJWKS      = "/jwks"
+)
+
+const (
+	oauth2CodeExpiresIn         = 10 * time.Minute
+	oauth2RefreshTokenExpiresIn = 24 * time.Hour
+	oauth2DefaultSubject        = "mockuma" // authorized if no users are configured and no login hint is given
+)
+
+// claims set by the provider, which are not returned by the userinfo endpoint
+var oauth2RegisteredClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti", "scope", "client_id", "auth_time", "nonce"}
+
+// the key signing all tokens and the grants of codes and refresh tokens, which
+// are kept in the process so that they are still valid after mappings are reloaded
+var (
+	oauth2KeyOnce sync.Once
+	oauth2Key     *rsa.PrivateKey
+	oauth2KeyID   string
+
+	oauth2Grants = newOAuth2GrantStore()
+)
+
+func loadOAuth2Key() (*rsa.PrivateKey, string) {
+	oauth2KeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		sum := sha256.Sum256(key.N.Bytes())
+		oauth2Key, oauth2KeyID = key, hex.EncodeToString(sum[:8])
+	})
+	return oauth2Key, oauth2KeyID
+}
+
+// what is granted by an authorization code or a refresh token
+type oauth2Grant struct {
+	clientID        string
+	redirectURI     string
+	subject         string
+	claims          myjson.Object // claims of the user, nil for clients themselves
+	scope           string
+	nonce           string
+	challenge       string // the PKCE code challenge
+	challengeMethod string
+	expiresAt       time.Time
+}
+
+// stores grants by single-use codes and rotated refresh tokens
+type oauth2GrantStore struct {
+	mu            sync.Mutex
+	codes         map[string]*oauth2Grant
+	refreshTokens map[string]*oauth2Grant
+}
+
+func newOAuth2GrantStore() *oauth2GrantStore {
+	return &oauth2GrantStore{
+		codes:         make(map[string]*oauth2Grant),
+		refreshTokens: make(map[string]*oauth2Grant),
+	}
+}
+
+// puts the grant of a new code, sweeping expired codes which are never exchanged
+func (s *oauth2GrantStore) putCode(code string, grant *oauth2Grant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for c, g := range s.codes {
+		if now.After(g.expiresAt) {
+			delete(s.codes, c)
+		}
+	}
+	s.codes[code] = grant
+}
+
+func (s *oauth2GrantStore) takeCode(code string) (*oauth2Grant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grant, ok := s.codes[code]
+	delete(s.codes, code) // codes are single-use
+	return grant, ok
+}
+
+// puts the grant of a new refresh token, sweeping expired ones which are never redeemed
+func (s *oauth2GrantStore) putRefreshToken(token string, grant *oauth2Grant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for t, g := range s.refreshTokens {
+		if now.After(g.expiresAt) {
+			delete(s.refreshTokens, t)
+		}
+	}
+	s.refreshTokens[token] = grant
+}
+
+func (s *oauth2GrantStore) takeRefreshToken(token string) (*oauth2Grant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grant, ok := s.refreshTokens[token]
+	delete(s.refreshTokens, token) // refresh tokens are rotated
+	return grant, ok
+}
+
+// serves a stand-in OAuth2/OIDC provider under the oauth2 path
+type oauth2Handler struct {
+	options *mckmaps.OAuth2Options
+	key     *rsa.PrivateKey
+	keyID   string
+	grants  *oauth2GrantStore
+}
+
+func newOAuth2Handler(options *mckmaps.OAuth2Options) *oauth2Handler {
+	handlerLog.Infof("enabled  : oauth2 handler, path = %s", options.Path)
+	key, keyID := loadOAuth2Key()
+	return &oauth2Handler{
+		options: options,
+		key:     key,
+		keyID:   keyID,
+		grants:  oauth2Grants,
+	}
+}
+
+// serves the request if it is for one of the endpoints, returns false otherwise
+func (h *oauth2Handler) serve(w http.ResponseWriter, r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, h.options.Path+"/") {
+		return false
+	}
+
+	switch r.URL.Path[len(h.options.Path):] {
+	case oauth2PathDiscovery:
+		h.serveDiscovery(w, r)
+	case oauth2PathAuthorize:
+		h.serveAuthorize(w, r)
+	case oauth2PathToken:
+		h.serveToken(w, r)
+	case oauth2PathUserInfo:
+		h.serveUserInfo(w, r)
+	case oauth2PathJWKS:
+		h.serveJWKS(w, r)
+	default:
+		return false
+	}
+	return true
+}
+
+// the issuer configured, or the one derived from the request
+func (h *oauth2Handler) issuer(r *http.Request) string {
+	if h.options.Issuer != "" {
+		return h.options.Issuer
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + h.options.Path
+}
+
+func (h *oauth2Handler) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := h.issuer(r)
+	writeOAuth2JSON(w, r, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + oauth2PathAuthorize,
+		"token_endpoint":                        issuer + oauth2PathToken,
+		"userinfo_endpoint":                     issuer + oauth2PathUserInfo,
+		"jwks_uri":                              issuer + oauth2PathJWKS,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "password", "client_credentials", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"plain", "S256"},
+	})
+}
+
+func (h *oauth2Handler) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	writeOAuth2JSON(w, r, http.StatusOK, map[string]interface{}{
+		"keys": []myjson.Object{myjwt.RSAJWK(&h.key.PublicKey, h.keyID)},
+	})
+}
+
+// approves authorization requests of the code flow without prompting, the user
+// is the one named by 'login_hint', or the first user if not given
+func (h *oauth2Handler) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	clientID := q.Get("client_id")
+	var client *mckmaps.OAuth2Client
+	if len(h.options.Clients) != 0 {
+		if client = h.options.FindClient(clientID); client == nil {
+			writeOAuth2Error(w, r, http.StatusBadRequest, "invalid_client", "unknown client")
+			return
+		}
+	}
+
+	redirectURI := q.Get("redirect_uri")
+	if client != nil && len(client.RedirectURIs) != 0 {
+		if redirectURI == "" && len(client.RedirectURIs) == 1 {
+			redirectURI = client.RedirectURIs[0]
+		} else if !stringSliceContains(client.RedirectURIs, []string{redirectURI}) {
+			writeOAuth2Error(w, r, http.StatusBadRequest, "invalid_request", "unregistered redirect_uri")
+			return
+		}
+	}
+	if _, err := url.ParseRequestURI(redirectURI); err != nil {
+		writeOAuth2Error(w, r, http.StatusBadRequest, "invalid_request", "invalid redirect_uri")
+		return
+	}
+
+	redirect := func(params url.Values) {
+		if state := q.Get("state"); state != "" {
+			params.Set("state", state)
+		}
+		sep := "?"
+		if strings.Contains(redirectURI, "?") {
+			sep = "&"
+		}
+		http.Redirect(w, r, redirectURI+sep+params.Encode(), http.StatusFound)
+	}
+	redirectError := func(code string) {
+		redirect(url.Values{"error": {code}})
+	}
+
+	if q.Get("response_type") != "code" {
+		redirectError("unsupported_response_type")
+		return
+	}
+	switch q.Get("code_challenge_method") {
+	case "", "plain", "S256":
+	default:
+		redirectError("invalid_request")
+		return
+	}
+
+	subject, claims, ok := h.authorizedUser(q.Get("login_hint"))
+	if !ok {
+		redirectError("access_denied")
+		return
+	}
+
+	code := randomOAuth2Token()
+	h.grants.putCode(code, &oauth2Grant{
+		clientID:        clientID,
+		redirectURI:     redirectURI,
+		subject:         subject,
+		claims:          claims,
+		scope:           q.Get("scope"),
+		nonce:           q.Get("nonce"),
+		challenge:       q.Get("code_challenge"),
+		challengeMethod: q.Get("code_challenge_method"),
+		expiresAt:       time.Now().Add(oauth2CodeExpiresIn),
+	})
+
+	redirect(url.Values{"code": {code}})
+}
+
+func (h *oauth2Handler) authorizedUser(loginHint string) (string, myjson.Object, bool) {
+	if len(h.options.Users) == 0 {
+		if loginHint == "" {
+			loginHint = oauth2DefaultSubject
+		}
+		return loginHint, myjson.Object{}, true
+	}
+
+	user := h.options.Users[0]
+	if loginHint != "" {
+		if user = h.options.FindUser(loginHint); user == nil {
+			return "", nil, false
+		}
+	}
+	return user.Username, user.Claims, true
+}
+
+func (h *oauth2Handler) serveToken(w http.ResponseWriter, r *http.Request) {
+	if myhttp.ToHTTPMethod(r.Method) != myhttp.MethodPost {
+		writeOAuth2Error(w, r, http.StatusMethodNotAllowed, "invalid_request", "token requests must be POST")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuth2Error(w, r, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, ok := h.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+		writeOAuth2Error(w, r, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	var grant *oauth2Grant
+	var desc string
+	refreshable := true
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "authorization_code":
+		grant, desc = h.grantByCode(r, clientID)
+	case "password":
+		grant, desc = h.grantByPassword(r, clientID)
+	case "client_credentials":
+		if clientID == "" {
+			desc = "client_id is required"
+		} else {
+			grant = &oauth2Grant{clientID: clientID, subject: clientID, scope: r.PostForm.Get("scope")}
+			refreshable = false
+		}
+	case "refresh_token":
+		grant, desc = h.grantByRefreshToken(r, clientID)
+	default:
+		writeOAuth2Error(w, r, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type: "+grantType)
+		return
+	}
+	if grant == nil {
+		writeOAuth2Error(w, r, http.StatusBadRequest, "invalid_grant", desc)
+		return
+	}
+
+	h.issueTokens(w, r, grant, refreshable)
+}
+
+// authenticates the client with 'client_secret_basic' or 'client_secret_post',
+// any client is accepted if none is configured
+func (h *oauth2Handler) authenticateClient(r *http.Request) (string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if len(h.options.Clients) == 0 {
+		return id, true
+	}
+	client := h.options.FindClient(id)
+	if client == nil {
+		return "", false
+	}
+	if client.Secret != "" && subtle.ConstantTimeCompare([]byte(client.Secret), []byte(secret)) != 1 {
+		return "", false
+	}
+	return id, true
+}
+
+func (h *oauth2Handler) grantByCode(r *http.Request, clientID string) (*oauth2Grant, string) {
+	grant, ok := h.grants.takeCode(r.PostForm.Get("code"))
+
+	switch {
+	case !ok || time.Now().After(grant.expiresAt):
+		return nil, "invalid or expired code"
+	case grant.clientID != clientID:
+		return nil, "code was issued to another client"
+	case grant.redirectURI != r.PostForm.Get("redirect_uri"):
+		return nil, "redirect_uri mismatches"
+	case !pkceVerifies(grant, r.PostForm.Get("code_verifier")):
+		return nil, "invalid code_verifier"
+	}
+	return grant, ""
+}
+
+func pkceVerifies(grant *oauth2Grant, verifier string) bool {
+	if grant.challenge == "" {
+		return true
+	}
+
+	expected := verifier
+	if grant.challengeMethod == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return verifier != "" && expected == grant.challenge
+}
+
+func (h *oauth2Handler) grantByPassword(r *http.Request, clientID string) (*oauth2Grant, string) {
+	username, password := r.PostForm.Get("username"), r.PostForm.Get("password")
+	if username == "" {
+		return nil, "username is required"
+	}
+
+	claims := myjson.Object{}
+	if len(h.options.Users) != 0 {
+		user := h.options.FindUser(username)
+		if user == nil || subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) != 1 {
+			return nil, "invalid username or password"
+		}
+		claims = user.Claims
+	}
+	return &oauth2Grant{clientID: clientID, subject: username, claims: claims, scope: r.PostForm.Get("scope")}, ""
+}
+
+func (h *oauth2Handler) grantByRefreshToken(r *http.Request, clientID string) (*oauth2Grant, string) {
+	grant, ok := h.grants.takeRefreshToken(r.PostForm.Get("refresh_token"))
+
+	switch {
+	case !ok || time.Now().After(grant.expiresAt):
+		return nil, "invalid or expired refresh_token"
+	case grant.clientID != clientID:
+		return nil, "refresh_token was issued to another client"
+	}
+	return grant, ""
+}
+
+// issues an access token, an ID token if the scope 'openid' is granted to a
+// user, and a refresh token if refreshable
+func (h *oauth2Handler) issueTokens(w http.ResponseWriter, r *http.Request, grant *oauth2Grant, refreshable bool) {
+	now := time.Now()
+	expiresAt := now.Add(h.options.ExpiresIn)
+
+	accessClaims := h.newClaims(r, grant, now, expiresAt)
+	if grant.scope != "" {
+		accessClaims["scope"] = myjson.String(grant.scope)
+	}
+	if grant.clientID != "" {
+		accessClaims["client_id"] = myjson.String(grant.clientID)
+	}
+	accessToken, err := myjwt.Sign(accessClaims, h.key, h.keyID)
+	if err != nil {
+		h.writeSignError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(h.options.ExpiresIn / time.Second),
+	}
+	if grant.scope != "" {
+		response["scope"] = grant.scope
+	}
+
+	if grant.claims != nil && scopeContains(grant.scope, "openid") {
+		idClaims := h.newClaims(r, grant, now, expiresAt)
+		idClaims["auth_time"] = myjson.Number(now.Unix())
+		if grant.nonce != "" {
+			idClaims["nonce"] = myjson.String(grant.nonce)
+		}
+		idToken, err := myjwt.Sign(idClaims, h.key, h.keyID)
+		if err != nil {
+			h.writeSignError(w, r, err)
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	if refreshable {
+		refreshToken := randomOAuth2Token()
+		refreshed := *grant
+		refreshed.nonce = ""
+		refreshed.expiresAt = now.Add(oauth2RefreshTokenExpiresIn)
+		h.grants.putRefreshToken(refreshToken, &refreshed)
+		response["refresh_token"] = refreshToken
+	}
+
+	w.Header().Set(myhttp.HeaderCacheControl, "no-store")
+	writeOAuth2JSON(w, r, http.StatusOK, response)
+}
+
+// claims of the user along with the registered ones, 'sub' of the user is kept
+func (h *oauth2Handler) newClaims(r *http.Request, grant *oauth2Grant, now, expiresAt time.Time) myjson.Object {
+	claims := make(myjson.Object, len(grant.claims)+5)
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	if _, ok := claims["sub"]; !ok {
+		claims["sub"] = myjson.String(grant.subject)
+	}
+
+	claims["iss"] = myjson.String(h.issuer(r))
+	if grant.clientID != "" {
+		claims["aud"] = myjson.String(grant.clientID)
+	}
+	claims["iat"] = myjson.Number(now.Unix())
+	claims["exp"] = myjson.Number(expiresAt.Unix())
+	return claims
+}
+
+func (h *oauth2Handler) writeSignError(w http.ResponseWriter, r *http.Request, err error) {
+	handlerLog.Errorf("error    : %s %s => fail to sign token: %v", r.Method, r.URL, err)
+	writeOAuth2Error(w, r, http.StatusInternalServerError, "server_error", "fail to sign token")
+}
+
+// returns claims of the access token, excluding the registered ones
+func (h *oauth2Handler) serveUserInfo(w http.ResponseWriter, r *http.Request) {
+	rawToken, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth2"`)
+		writeOAuth2Error(w, r, http.StatusUnauthorized, "invalid_token", "missing bearer token")
+		return
+	}
+
+	token, err := myjwt.Parse(rawToken)
+	if err == nil {
+		err = token.Verify(&h.key.PublicKey)
+	}
+	if err == nil {
+		err = token.Validate(time.Now())
+	}
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth2", error="invalid_token"`)
+		writeOAuth2Error(w, r, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	userInfo := make(myjson.Object, len(token.Claims))
+	for name, value := range token.Claims {
+		userInfo[name] = value
+	}
+	for _, name := range oauth2RegisteredClaims {
+		delete(userInfo, name)
+	}
+	writeOAuth2JSON(w, r, http.StatusOK, userInfo)
+}
+
+func scopeContains(scope string, s string) bool {
+	for _, _s := range strings.Fields(scope) {
+		if _s == s {
+			return true
+		}
+	}
+	return false
+}
+
+func randomOAuth2Token() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeOAuth2Error(w http.ResponseWriter, r *http.Request, statusCode int, code, desc string) {
+	writeOAuth2JSON(w, r, statusCode, map[string]string{"error": code, "error_description": desc})
+}
+
+func writeOAuth2JSON(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		handlerLog.Errorf("error    : %s %s => fail to encode response: %v", r.Method, r.URL, err)
+		statusCode, data = http.StatusInternalServerError, []byte(`{"error":"server_error"}`)
+	}
+
+	w.Header().Set(myhttp.HeaderContentType, myhttp.ContentTypeJSON)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(data); err != nil {
+		handlerLog.Errorf("error    : %s %s => fail to write response: %v", r.Method, r.URL, err)
+	}
+}
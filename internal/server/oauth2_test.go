@@ -0,0 +1,373 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/kumasuke120/mockuma/internal/myjwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mappingsWithOAuth2 = &mckmaps.MockuMappings{
+	Mappings: mappings.Mappings,
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{
+			Enabled: false,
+		},
+		OAuth2: &mckmaps.OAuth2Options{
+			Path:      "/oauth2",
+			ExpiresIn: time.Hour,
+			Clients: []*mckmaps.OAuth2Client{
+				{ID: "app", Secret: "s3cret", RedirectURIs: []string{"http://app.local/callback"}},
+				{ID: "spa"},
+			},
+			Users: []*mckmaps.OAuth2User{
+				{
+					Username: "kumasuke120",
+					Password: "pa$$w0rd",
+					Claims:   myjson.Object{"email": myjson.String("kumasuke120@example.com")},
+				},
+				{Username: "jane.doe", Password: "jane", Claims: myjson.Object{}},
+			},
+		},
+	},
+}
+
+func serveOAuth2(handler http.Handler, method, target string, form url.Values, prepare func(r *http.Request)) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, target, nil)
+	}
+	if prepare != nil {
+		prepare(r)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+	return rr
+}
+
+func decodeOAuth2JSON(t *testing.T, rr *httptest.ResponseRecorder) map[string]interface{} {
+	var v map[string]interface{}
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &v), rr.Body.String())
+	return v
+}
+
+func TestOAuth2Handler_ServeHTTP(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(mappingsWithOAuth2)
+	h, ok := handler.(*mockHandler)
+	assert.True(ok && h.oauth2 != nil)
+
+	rr1 := serveOAuth2(handler, "POST", "/hello", nil, nil)
+	assert.Equal(http.StatusOK, rr1.Code)
+
+	rr2 := serveOAuth2(handler, "GET", "/oauth2/.well-known/openid-configuration", nil, nil)
+	if assert.Equal(http.StatusOK, rr2.Code) {
+		discovery := decodeOAuth2JSON(t, rr2)
+		assert.Equal("http://example.com/oauth2", discovery["issuer"])
+		assert.Equal("http://example.com/oauth2/token", discovery["token_endpoint"])
+		assert.Equal("http://example.com/oauth2/jwks", discovery["jwks_uri"])
+	}
+
+	rr3 := serveOAuth2(handler, "GET", "/oauth2/jwks", nil, nil)
+	if assert.Equal(http.StatusOK, rr3.Code) {
+		keys := decodeOAuth2JSON(t, rr3)["keys"].([]interface{})
+		if assert.Len(keys, 1) {
+			key, keyID := loadOAuth2Key()
+			jwk := keys[0].(map[string]interface{})
+			assert.Equal(keyID, jwk["kid"])
+			assert.Equal(base64.RawURLEncoding.EncodeToString(key.N.Bytes()), jwk["n"])
+		}
+	}
+
+	rr4 := serveOAuth2(handler, "GET", "/oauth2/unknown", nil, nil)
+	assert.Equal(http.StatusNotFound, rr4.Code)
+}
+
+func TestOAuth2Handler_password(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	handler := newMockHandler(mappingsWithOAuth2)
+	key, _ := loadOAuth2Key()
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"username":   {"kumasuke120"},
+		"password":   {"pa$$w0rd"},
+		"scope":      {"openid email"},
+	}
+	withClient := func(r *http.Request) { r.SetBasicAuth("app", "s3cret") }
+
+	rr1 := serveOAuth2(handler, "POST", "/oauth2/token", form, withClient)
+	require.Equal(http.StatusOK, rr1.Code, rr1.Body.String())
+	assert.Equal("no-store", rr1.Header().Get("Cache-Control"))
+	tokens := decodeOAuth2JSON(t, rr1)
+	assert.Equal("Bearer", tokens["token_type"])
+	assert.Equal(float64(3600), tokens["expires_in"])
+	assert.Equal("openid email", tokens["scope"])
+
+	idToken, err := myjwt.Parse(tokens["id_token"].(string))
+	require.Nil(err)
+	assert.Nil(idToken.Verify(&key.PublicKey))
+	assert.Equal(myjson.String("kumasuke120"), idToken.Claims.Get("sub"))
+	assert.Equal(myjson.String("app"), idToken.Claims.Get("aud"))
+	assert.Equal(myjson.String("http://example.com/oauth2"), idToken.Claims.Get("iss"))
+	assert.Equal(myjson.String("kumasuke120@example.com"), idToken.Claims.Get("email"))
+
+	rr2 := serveOAuth2(handler, "GET", "/oauth2/userinfo", nil, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+tokens["access_token"].(string))
+	})
+	if assert.Equal(http.StatusOK, rr2.Code) {
+		assert.Equal(map[string]interface{}{
+			"sub":   "kumasuke120",
+			"email": "kumasuke120@example.com",
+		}, decodeOAuth2JSON(t, rr2))
+	}
+
+	rr3 := serveOAuth2(handler, "GET", "/oauth2/userinfo", nil, func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+tokens["access_token"].(string)+"x")
+	})
+	assert.Equal(http.StatusUnauthorized, rr3.Code)
+
+	refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens["refresh_token"].(string)}}
+	rr4 := serveOAuth2(handler, "POST", "/oauth2/token", refresh, withClient)
+	assert.Equal(http.StatusOK, rr4.Code)
+	rr5 := serveOAuth2(handler, "POST", "/oauth2/token", refresh, withClient) // rotated
+	assert.Equal(http.StatusBadRequest, rr5.Code)
+	assert.Equal("invalid_grant", decodeOAuth2JSON(t, rr5)["error"])
+
+	form.Set("password", "password")
+	rr6 := serveOAuth2(handler, "POST", "/oauth2/token", form, withClient)
+	assert.Equal(http.StatusBadRequest, rr6.Code)
+
+	rr7 := serveOAuth2(handler, "POST", "/oauth2/token", form, func(r *http.Request) {
+		r.SetBasicAuth("app", "wrong")
+	})
+	assert.Equal(http.StatusUnauthorized, rr7.Code)
+	assert.Equal("invalid_client", decodeOAuth2JSON(t, rr7)["error"])
+
+	rr8 := serveOAuth2(handler, "GET", "/oauth2/token", nil, nil)
+	assert.Equal(http.StatusMethodNotAllowed, rr8.Code)
+
+	credentials := url.Values{"grant_type": {"client_credentials"}, "client_id": {"spa"}}
+	rr9 := serveOAuth2(handler, "POST", "/oauth2/token", credentials, nil)
+	if assert.Equal(http.StatusOK, rr9.Code) {
+		tokens := decodeOAuth2JSON(t, rr9)
+		assert.NotContains(tokens, "id_token")
+		assert.NotContains(tokens, "refresh_token")
+	}
+}
+
+func TestOAuth2Handler_authorizationCode(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	handler := newMockHandler(mappingsWithOAuth2)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"app"},
+		"scope":                 {"openid"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"login_hint":            {"jane.doe"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authorize := func(q url.Values) *url.URL {
+		rr := serveOAuth2(handler, "GET", "/oauth2/authorize?"+q.Encode(), nil, nil)
+		require.Equal(http.StatusFound, rr.Code, rr.Body.String())
+		location, err := url.Parse(rr.Header().Get("Location"))
+		require.Nil(err)
+		return location
+	}
+
+	location := authorize(query)
+	assert.Equal("app.local", location.Host)
+	assert.Equal("/callback", location.Path)
+	assert.Equal("xyz", location.Query().Get("state"))
+	code := location.Query().Get("code")
+	require.NotEmpty(code)
+
+	exchange := func(code, verifier string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {"http://app.local/callback"},
+			"client_id":     {"app"},
+			"client_secret": {"s3cret"},
+			"code_verifier": {verifier},
+		}
+		return serveOAuth2(handler, "POST", "/oauth2/token", form, nil)
+	}
+
+	rr1 := exchange(code, verifier)
+	require.Equal(http.StatusOK, rr1.Code, rr1.Body.String())
+	idToken, err := myjwt.Parse(decodeOAuth2JSON(t, rr1)["id_token"].(string))
+	require.Nil(err)
+	assert.Equal(myjson.String("jane.doe"), idToken.Claims.Get("sub"))
+	assert.Equal(myjson.String("n-0S6"), idToken.Claims.Get("nonce"))
+
+	rr2 := exchange(code, verifier) // codes are single-use
+	assert.Equal(http.StatusBadRequest, rr2.Code)
+
+	rr3 := exchange(authorize(query).Query().Get("code"), "wrong-verifier")
+	assert.Equal(http.StatusBadRequest, rr3.Code)
+
+	query.Set("login_hint", "nobody")
+	assert.Equal("access_denied", authorize(query).Query().Get("error"))
+
+	query.Set("response_type", "token")
+	assert.Equal("unsupported_response_type", authorize(query).Query().Get("error"))
+
+	query.Set("redirect_uri", "http://evil.local/callback")
+	rr4 := serveOAuth2(handler, "GET", "/oauth2/authorize?"+query.Encode(), nil, nil)
+	assert.Equal(http.StatusBadRequest, rr4.Code)
+
+	query.Set("client_id", "unknown")
+	rr5 := serveOAuth2(handler, "GET", "/oauth2/authorize?"+query.Encode(), nil, nil)
+	assert.Equal(http.StatusBadRequest, rr5.Code)
+}
+
+func TestOAuth2Handler_anyUser(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(&mckmaps.MockuMappings{
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+			OAuth2: &mckmaps.OAuth2Options{
+				Path:      "/idp",
+				Issuer:    "https://idp.local",
+				ExpiresIn: time.Minute,
+			},
+		},
+	})
+
+	form := url.Values{"grant_type": {"password"}, "username": {"anyone"}, "password": {"any"}, "scope": {"openid"}}
+	rr := serveOAuth2(handler, "POST", "/idp/token", form, nil)
+	if assert.Equal(http.StatusOK, rr.Code) {
+		tokens := decodeOAuth2JSON(t, rr)
+		assert.Equal(float64(60), tokens["expires_in"])
+		idToken, err := myjwt.Parse(tokens["id_token"].(string))
+		if assert.Nil(err) {
+			assert.Equal(myjson.String("anyone"), idToken.Claims.Get("sub"))
+			assert.Equal(myjson.String("https://idp.local"), idToken.Claims.Get("iss"))
+		}
+	}
+
+	q := url.Values{"response_type": {"code"}, "redirect_uri": {"http://localhost/cb?x=1"}}
+	rr2 := serveOAuth2(handler, "GET", "/idp/authorize?"+q.Encode(), nil, nil)
+	if assert.Equal(http.StatusFound, rr2.Code) {
+		assert.True(strings.HasPrefix(rr2.Header().Get("Location"), "http://localhost/cb?x=1&code="))
+	}
+}
+
+func TestOAuth2Handler_reload(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	form := url.Values{"grant_type": {"password"}, "username": {"jane.doe"}, "password": {"jane"}}
+	withClient := func(r *http.Request) { r.SetBasicAuth("app", "s3cret") }
+	rr1 := serveOAuth2(newMockHandler(mappingsWithOAuth2), "POST", "/oauth2/token", form, withClient)
+	require.Equal(http.StatusOK, rr1.Code, rr1.Body.String())
+
+	reloaded := newMockHandler(mappingsWithOAuth2) // handlers are recreated when mappings are reloaded
+	refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {decodeOAuth2JSON(t, rr1)["refresh_token"].(string)}}
+	rr2 := serveOAuth2(reloaded, "POST", "/oauth2/token", refresh, withClient)
+	assert.Equal(http.StatusOK, rr2.Code, rr2.Body.String())
+}
+
+func TestOAuth2GrantStore_putCode(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	s := newOAuth2GrantStore()
+	s.putCode("expired", &oauth2Grant{expiresAt: time.Now().Add(-time.Second)})
+	s.putCode("valid", &oauth2Grant{expiresAt: time.Now().Add(time.Minute)})
+	assert.NotContains(s.codes, "expired")
+	assert.Contains(s.codes, "valid")
+
+	_, ok := s.takeCode("valid")
+	assert.True(ok)
+	_, ok = s.takeCode("valid")
+	assert.False(ok)
+}
+
+func TestOAuth2GrantStore_putRefreshToken(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	s := newOAuth2GrantStore()
+	s.putRefreshToken("expired", &oauth2Grant{expiresAt: time.Now().Add(-time.Second)})
+	s.putRefreshToken("valid", &oauth2Grant{expiresAt: time.Now().Add(time.Minute)})
+	assert.NotContains(s.refreshTokens, "expired")
+	assert.Contains(s.refreshTokens, "valid")
+
+	_, ok := s.takeRefreshToken("valid")
+	assert.True(ok)
+	_, ok = s.takeRefreshToken("valid")
+	assert.False(ok)
+
+	// expired refresh tokens are rejected even if not swept yet
+	oauth2Grants.putRefreshToken("TestOAuth2GrantStore_putRefreshToken", &oauth2Grant{
+		clientID:  "app",
+		subject:   "jane.doe",
+		expiresAt: time.Now().Add(-time.Second),
+	})
+	refresh := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"TestOAuth2GrantStore_putRefreshToken"}}
+	rr := serveOAuth2(newMockHandler(mappingsWithOAuth2), "POST", "/oauth2/token", refresh,
+		func(r *http.Request) { r.SetBasicAuth("app", "s3cret") })
+	assert.Equal(http.StatusBadRequest, rr.Code)
+	assert.Equal("invalid or expired refresh_token", decodeOAuth2JSON(t, rr)["error_description"])
+}
+
+func TestOAuth2Handler_recorded(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	handler := newMockHandler(&mckmaps.MockuMappings{
+		Mappings: mappings.Mappings,
+		Config: &mckmaps.Config{
+			CORS:   &mckmaps.CORSOptions{Enabled: false},
+			Admin:  &mckmaps.AdminOptions{Enabled: true, Path: "/__admin"},
+			OAuth2: mappingsWithOAuth2.Config.OAuth2,
+		},
+	})
+
+	rr1 := serveOAuth2(handler, "GET", "/oauth2/jwks", nil, nil)
+	assert.Equal(http.StatusOK, rr1.Code)
+	assert.NotEmpty(rr1.Header().Get("X-Request-Id"))
+
+	rr2 := serveOAuth2(handler, "GET", "/__admin/metrics", nil, nil)
+	assert.Contains(rr2.Body.String(), `mockuma_requests_total{uri="/oauth2/jwks",method="GET",policy="",status="200"}`)
+
+	rr3 := serveOAuth2(handler, "GET", "/__admin/requests", nil, nil)
+	assert.Contains(rr3.Body.String(), `"/oauth2/jwks"`)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+)
+
+// the number of writes per second when throttling
+const throttleWritesPerSecond = 10
+
+// writes the body at the given pace, flushing after each write
+func writePacedBody(ctx context.Context, w http.ResponseWriter, body []byte, pacing *mckmaps.Pacing) error {
+	for idx, chunk := range splitIntoChunks(body, pacing.Chunks) {
+		if idx != 0 && pacing.Delay != nil {
+			if err := sleepWithContext(ctx, randomDuration(pacing.Delay)); err != nil {
+				return err
+			}
+		}
+
+		if err := writeThrottled(ctx, w, chunk, pacing.Throttle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splits the body into n chunks of nearly the same size
+func splitIntoChunks(body []byte, n int) [][]byte {
+	if n <= 1 || len(body) == 0 {
+		return [][]byte{body}
+	}
+	if n > len(body) {
+		n = len(body)
+	}
+
+	chunks := make([][]byte, 0, n)
+	size, remainder := len(body)/n, len(body)%n
+	for i := 0; i < n; i++ {
+		l := size
+		if i < remainder {
+			l++
+		}
+		chunks = append(chunks, body[:l])
+		body = body[l:]
+	}
+	return chunks
+}
+
+// writes data no faster than throttle bytes per second, 0 means unlimited
+func writeThrottled(ctx context.Context, w http.ResponseWriter, data []byte, throttle int) error {
+	piece := len(data)
+	if throttle > 0 {
+		piece = throttle / throttleWritesPerSecond
+		if piece < 1 {
+			piece = 1
+		}
+	}
+
+	for len(data) != 0 {
+		n := piece
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		data = data[n:]
+
+		if throttle > 0 {
+			d := time.Duration(n) * time.Second / time.Duration(throttle)
+			if err := sleepWithContext(ctx, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sleeps for the given duration, returns early with an error if the context is done
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
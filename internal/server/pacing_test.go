@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitIntoChunks(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	body := []byte("0123456789")
+	assert.Equal([][]byte{body}, splitIntoChunks(body, 0))
+	assert.Equal([][]byte{body}, splitIntoChunks(body, 1))
+	assert.Equal([][]byte{[]byte("0123"), []byte("456"), []byte("789")}, splitIntoChunks(body, 3))
+	assert.Equal(10, len(splitIntoChunks(body, 20)))
+	assert.Equal([][]byte{{}}, splitIntoChunks([]byte{}, 3))
+}
+
+func TestWriteThrottled(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	w1 := httptest.NewRecorder()
+	start1 := time.Now()
+	err1 := writeThrottled(context.Background(), w1, []byte("0123456789"), 100)
+	if assert.Nil(err1) {
+		assert.Equal("0123456789", w1.Body.String())
+		assert.True(w1.Flushed)
+		assert.True(time.Since(start1) >= 100*time.Millisecond)
+	}
+
+	w2 := httptest.NewRecorder()
+	err2 := writeThrottled(context.Background(), w2, []byte("0123456789"), 0)
+	if assert.Nil(err2) {
+		assert.Equal("0123456789", w2.Body.String())
+	}
+
+	ctx3, cancel3 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel3()
+	w3 := httptest.NewRecorder()
+	err3 := writeThrottled(ctx3, w3, []byte("0123456789"), 1)
+	assert.Equal(context.DeadlineExceeded, err3)
+	assert.Equal("0", w3.Body.String())
+}
+
+var mappingsWithPacing = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		{
+			URI:    "/chunks",
+			Method: myhttp.MethodGet,
+			Policies: []*mckmaps.Policy{
+				{
+					CmdType: mckmaps.CmdTypeReturns,
+					Returns: &mckmaps.Returns{
+						StatusCode: myhttp.StatusOK,
+						Body:       []byte("0123456789"),
+						Pacing: &mckmaps.Pacing{
+							Chunks: 5,
+							Delay:  &mckmaps.Interval{Min: 20, Max: 20},
+						},
+					},
+				},
+			},
+		},
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestPolicyExecutor_executePacing(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	s := httptest.NewServer(newMockHandler(mappingsWithPacing))
+	defer s.Close()
+
+	start := time.Now()
+	resp, err := http.Get(s.URL + "/chunks")
+	if assert.Nil(err) {
+		assert.Equal(http.StatusOK, resp.StatusCode)
+		assert.Equal("10", resp.Header.Get(myhttp.HeaderContentLength))
+		body, err := ioutil.ReadAll(resp.Body)
+		if assert.Nil(err) {
+			assert.Equal("0123456789", string(body))
+		}
+		_ = resp.Body.Close()
+		assert.True(time.Since(start) >= 80*time.Millisecond)
+	}
+}
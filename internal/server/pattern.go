@@ -0,0 +1,132 @@
+package server
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+)
+
+// uriPattern holds mappings of the same uri with pathVars
+type uriPattern struct {
+	uri         string
+	regexp      *regexp.Regexp
+	mappings    []*mckmaps.Mapping
+	priority    int
+	specificity []int
+}
+
+func newURIPattern(uri, pattern string) *uriPattern {
+	return &uriPattern{
+		uri:         uri,
+		regexp:      regexp.MustCompile(pattern),
+		specificity: mckmaps.URISpecificity(uri),
+	}
+}
+
+func (up *uriPattern) add(m *mckmaps.Mapping) {
+	if len(up.mappings) == 0 || up.priority < m.Priority {
+		up.priority = m.Priority
+	}
+	up.mappings = append(up.mappings, m)
+}
+
+// compares the precedence of uri patterns, a negative result means that a
+// precedes b, and zero means that neither is more specific:
+//  1. the higher priority precedes;
+//  2. segments are compared from left to right, literals precede pathVars
+//     mixed with literals, which precede typed pathVars, then pathVars
+//     without types, then catch-all pathVars;
+//  3. the longer precedes if all segments of the shorter are as specific.
+func comparePrecedence(a, b *uriPattern) int {
+	if a.priority != b.priority {
+		return b.priority - a.priority
+	}
+
+	for i := 0; i < len(a.specificity) && i < len(b.specificity); i++ {
+		if a.specificity[i] != b.specificity[i] {
+			return b.specificity[i] - a.specificity[i]
+		}
+	}
+	return len(b.specificity) - len(a.specificity)
+}
+
+// sorts uri patterns by their precedence, the ones declared earlier go first
+// if neither is more specific
+func sortURIPatterns(patterns []*uriPattern) {
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return comparePrecedence(patterns[i], patterns[j]) < 0
+	})
+}
+
+// finds pairs of uri patterns of the same precedence which may match the
+// same uri, the former of each pair is matched first.
+// It is a best-effort heuristic: a pair is reported only if one pattern matches
+// the sample of the other (see sampleOf), so some overlaps are not reported.
+func findURIPatternConflicts(patterns []*uriPattern) [][2]*uriPattern {
+	var conflicts [][2]*uriPattern
+	for i, a := range patterns {
+		for _, b := range patterns[i+1:] {
+			if comparePrecedence(a, b) != 0 {
+				break // sorted, the following ones are less specific
+			}
+			if b.regexp.MatchString(sampleOf(a.regexp)) || a.regexp.MatchString(sampleOf(b.regexp)) {
+				conflicts = append(conflicts, [2]*uriPattern{a, b})
+			}
+		}
+	}
+	return conflicts
+}
+
+// makes one of the shortest strings matched by the regexp, with which it
+// could be told whether two patterns may match the same uri. Only one string is
+// sampled: optional parts ('*', '?') are omitted and only the first branch of
+// alternations is taken, so overlaps in other branches are not detected.
+func sampleOf(re *regexp.Regexp) string {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		panic("Shouldn't happen")
+	}
+
+	var builder strings.Builder
+	writeSample(&builder, parsed)
+	return builder.String()
+}
+
+func writeSample(builder *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		builder.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		builder.WriteRune(sampleRuneOf(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		builder.WriteRune('a')
+	case syntax.OpCapture, syntax.OpPlus, syntax.OpAlternate:
+		writeSample(builder, re.Sub[0])
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			writeSample(builder, re.Sub[0])
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeSample(builder, sub)
+		}
+	}
+}
+
+// prefers letters and digits, which are usual in uris, to the first rune
+func sampleRuneOf(ranges []rune) rune {
+	if len(ranges) == 0 {
+		return 0
+	}
+	for _, r := range "a0" {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= r && r <= ranges[i+1] {
+				return r
+			}
+		}
+	}
+	return ranges[0]
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortURIPatterns(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	uris := []string{
+		"/api/{0:*}",
+		"/api/{0}/{1}",
+		"/api/{0}/x",
+		"/api/{0:int}/{1}",
+		"/api/{0}.json/{1}",
+		"/api/{0:*}/edit",
+		"/api/{0}",
+	}
+	var patterns []*uriPattern
+	for _, uri := range uris {
+		up := newURIPattern(uri, mckmaps.URIPattern(uri))
+		up.add(&mckmaps.Mapping{URI: uri})
+		patterns = append(patterns, up)
+	}
+	patterns[0].priority = 1
+
+	sortURIPatterns(patterns)
+	var sorted []string
+	for _, up := range patterns {
+		sorted = append(sorted, up.uri)
+	}
+	assert.Equal([]string{
+		"/api/{0:*}",
+		"/api/{0}.json/{1}",
+		"/api/{0:int}/{1}",
+		"/api/{0}/x",
+		"/api/{0}/{1}",
+		"/api/{0}",
+		"/api/{0:*}/edit",
+	}, sorted)
+}
+
+func TestFindURIPatternConflicts(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	newPatterns := func(uris ...string) []*uriPattern {
+		var patterns []*uriPattern
+		for _, uri := range uris {
+			patterns = append(patterns, newURIPattern(uri, mckmaps.URIPattern(uri)))
+		}
+		sortURIPatterns(patterns)
+		return patterns
+	}
+
+	c1 := findURIPatternConflicts(newPatterns("/b/{0:int}", "/b/{0:[a-z-]+}", "/u/{0:uuid}", "/u/{0:int}"))
+	assert.Empty(c1)
+
+	c2 := findURIPatternConflicts(newPatterns("/b/{0:int}", "/b/{0:[0-9a-f]+}", "/c/{0}"))
+	if assert.Len(c2, 1) {
+		assert.Equal("/b/{0:int}", c2[0][0].uri)
+		assert.Equal("/b/{0:[0-9a-f]+}", c2[0][1].uri)
+	}
+
+	c3 := findURIPatternConflicts(newPatterns("/a/{0}/x", "/a/{0}/{1}"))
+	assert.Empty(c3)
+
+	// alternations are sampled by their first branches only
+	c4 := findURIPatternConflicts(newPatterns("/f/{0:(png|jpg)}", "/f/{0:png}"))
+	assert.Len(c4, 1)
+	c5 := findURIPatternConflicts(newPatterns("/f/{0:(png|jpg)}", "/f/{0:(gif|jpg)}"))
+	assert.Empty(c5) // overlaps on 'jpg', but not detected
+
+	// optional parts are omitted from samples
+	c6 := findURIPatternConflicts(newPatterns("/v/{0:v?[0-9]}", "/v/{0:[0-9]}"))
+	assert.Len(c6, 1)
+	c7 := findURIPatternConflicts(newPatterns("/v/{0:x?v[0-9]}", "/v/{0:xv?[0-9]}"))
+	assert.Empty(c7) // overlaps on 'xv1', but not detected
+}
+
+func TestSampleOf(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	assert.Equal("/a/a", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0}"))))
+	assert.Equal("/a/0.json", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0:int}.json"))))
+	assert.Equal("/a/", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0:*}"))))
+	assert.Equal("/a/AAA", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0:[A-Z]{3}}"))))
+	assert.Equal("/a/png", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0:(png|jpg)}"))))
+	assert.Equal("/a/b", sampleOf(regexp.MustCompile(mckmaps.URIPattern("/a/{0:x?b(yz)*}"))))
+}
+
+func TestPathMatcher_matches_precedence(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	overlapping := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{URI: "/api/{0}/{1}", Method: myhttp.MethodGet},
+			{URI: "/api/{0}/x", Method: myhttp.MethodGet},
+			{URI: "/api/{0}/{1}", Method: myhttp.MethodPost},
+			{URI: "/files/{0:*}", Method: myhttp.MethodGet, Priority: 1},
+			{URI: "/files/{0}/raw", Method: myhttp.MethodGet},
+		},
+		Config: &mckmaps.Config{CORS: &mckmaps.CORSOptions{Enabled: false}},
+	}
+
+	matched := func(method, target string) *mckmaps.Mapping {
+		bound := newPathMatcher(overlapping).bind(httptest.NewRequest(method, target, nil))
+		if !bound.matches() {
+			return nil
+		}
+		return bound.matchedMapping
+	}
+
+	for i := 0; i < 10; i++ { // the result never varies
+		assert.Equal(overlapping.Mappings[1], matched("GET", "/api/a/x"))
+	}
+	assert.Equal(overlapping.Mappings[0], matched("GET", "/api/a/y"))
+	assert.Equal(overlapping.Mappings[2], matched("POST", "/api/a/y"))
+	assert.Equal(overlapping.Mappings[2], matched("POST", "/api/a/x"))
+	assert.Equal(overlapping.Mappings[3], matched("GET", "/files/a/raw"))
+
+	bound := newPathMatcher(overlapping).bind(httptest.NewRequest("DELETE", "/api/a/x", nil))
+	assert.True(bound.matches())
+	assert.Equal(matchState(matchURI), bound.matchState)
+}
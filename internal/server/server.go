@@ -2,16 +2,21 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/mylog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+var serverLog = mylog.New("server")
+
 type MockServer struct {
 	port      int
 	server    *http.Server
@@ -29,19 +34,52 @@ func (s *MockServer) ListenAndServe(mappings *mckmaps.MockuMappings) {
 		panic("parameter 'mappings' should not be nil")
 	}
 
-	handler := newMockHandler(mappings)
+	conf := mappings.Config
+	var handler http.Handler = newMockHandler(mappings)
+	if conf.HTTP2 && conf.TLS == nil {
+		// serves HTTP/2 without TLS (h2c) as well, which is required by gRPC
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
 	addr := fmt.Sprintf(":%d", s.port)
-	server := &http.Server{Addr: addr, Handler: handler}
+
+	// contexts of requests are canceled on shutdown, so that long-running
+	// responses end without blocking the restart
+	baseCtx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	server.RegisterOnShutdown(cancel)
+	server.RegisterOnShutdown(defaultInspector.disconnectAll)
+
+	if conf.TLS != nil {
+		tlsConfig, err := newTLSConfig(conf.TLS)
+		if err != nil {
+			serverLog.Fatalf("cannot configure TLS: %v", err)
+		}
+		server.TLSConfig = tlsConfig
+		if !conf.HTTP2 { // HTTP/2 is negotiated over TLS unless TLSNextProto is non-nil
+			server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		}
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		log.Println("[server  ] listening on " + strconv.Itoa(s.port) + "...")
-		if err := server.ListenAndServe(); err != nil {
+		var err error
+		if server.TLSConfig != nil {
+			serverLog.Infof("listening on %d with TLS...", s.port)
+			err = server.ListenAndServeTLS("", "") // certificates are in the TLSConfig
+		} else {
+			serverLog.Infof("listening on %d...", s.port)
+			err = server.ListenAndServe()
+		}
+		if err != nil {
 			if err != http.ErrServerClosed {
-				log.Fatalln("[server  ] cannot start:", err)
+				serverLog.Fatalf("cannot start: %v", err)
 			}
 		}
 	}()
@@ -56,7 +94,7 @@ func (s *MockServer) SetMappings(mappings *mckmaps.MockuMappings) {
 	}
 
 	if ok := s.shutdown(); ok {
-		log.Println("[server  ] restarting with the new mockuMappings...")
+		serverLog.Infof("restarting with the new mockuMappings...")
 		go s.ListenAndServe(mappings)
 	}
 }
@@ -67,7 +105,7 @@ func (s *MockServer) shutdown() bool {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := server.Shutdown(ctx); err != nil {
-			log.Fatalln("[server  ] cannot shutdown server in order to restart with the new mappings:", err)
+			serverLog.Fatalf("cannot shutdown server in order to restart with the new mappings: %v", err)
 		}
 
 		return true
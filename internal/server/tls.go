@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+)
+
+// the self-signed certificate is generated once, so that clients trusting it
+// keep working after mappings are reloaded
+var (
+	selfSignedOnce sync.Once
+	selfSignedCert tls.Certificate
+	selfSignedErr  error
+)
+
+func newTLSConfig(o *mckmaps.TLSOptions) (*tls.Config, error) {
+	var cert tls.Certificate
+	if o.SelfSigned {
+		selfSignedOnce.Do(func() {
+			selfSignedCert, selfSignedErr = generateSelfSignedCert(time.Now())
+		})
+		if selfSignedErr != nil {
+			return nil, selfSignedErr
+		}
+		cert = selfSignedCert
+	} else {
+		cert = *o.Certificate
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// generates a certificate for localhost, which is valid for a year since now
+func generateSelfSignedCert(now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"MockuMappings"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
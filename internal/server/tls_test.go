@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	now := time.Now()
+	cert, err := generateSelfSignedCert(now)
+	require.Nil(err)
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	require.Nil(err)
+	assert.Nil(c.VerifyHostname("localhost"))
+	assert.Nil(c.VerifyHostname("127.0.0.1"))
+	assert.True(c.NotAfter.After(now.AddDate(0, 11, 0)))
+
+	c1, err := newTLSConfig(&mckmaps.TLSOptions{SelfSigned: true})
+	require.Nil(err)
+	c2, err := newTLSConfig(&mckmaps.TLSOptions{SelfSigned: true})
+	require.Nil(err)
+	assert.Equal(c1.Certificates, c2.Certificates)
+}
+
+func TestMockServer_TLS(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	mappingsWithPush := &mckmaps.MockuMappings{
+		Mappings: []*mckmaps.Mapping{
+			{
+				URI:    "/index.html",
+				Method: myhttp.MethodGet,
+				Policies: []*mckmaps.Policy{
+					{
+						CmdType: mckmaps.CmdTypeReturns,
+						Returns: &mckmaps.Returns{
+							StatusCode: myhttp.StatusOK,
+							Body:       []byte("<script src=\"/app.js\"></script>"),
+							Push:       []string{"/app.js"},
+						},
+					},
+				},
+			},
+		},
+		Config: &mckmaps.Config{
+			CORS: &mckmaps.CORSOptions{Enabled: false},
+		},
+	}
+
+	tlsConfig, err := newTLSConfig(&mckmaps.TLSOptions{SelfSigned: true})
+	require.Nil(err)
+	s := httptest.NewUnstartedServer(newMockHandler(mappingsWithPush))
+	s.TLS = tlsConfig
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	// pushes are refused by the client, which must not fail the response
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(s.URL + "/index.html")
+	if assert.Nil(err) {
+		assert.Equal(2, resp.ProtoMajor)
+		body, err := ioutil.ReadAll(resp.Body)
+		if assert.Nil(err) {
+			assert.Equal("<script src=\"/app.js\"></script>", string(body))
+		}
+		_ = resp.Body.Close()
+	}
+}
+
+func TestPolicyExecutor_pushResources(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	pr := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	var w http.ResponseWriter = newRecordingWriter(pr)
+	e := &policyExecutor{r: httptest.NewRequest(http.MethodGet, "/", nil), w: &w}
+	assert.Nil(e.writeResponseForReturns(&mckmaps.Returns{
+		StatusCode: myhttp.StatusOK,
+		Push:       []string{"/app.js", "/app.css"},
+	}))
+	assert.Equal([]string{"/app.js", "/app.css"}, pr.pushed)
+}
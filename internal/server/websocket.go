@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+)
+
+// the time allowed to write a close message to the client
+const wsCloseTimeout = time.Second
+
+// a websocket connection talking to the client as the mapping scripts
+type wsSession struct {
+	ws   *mckmaps.WebSocket
+	conn *websocket.Conn
+
+	writeMu sync.Mutex // at most one concurrent writer is allowed on a connection
+	done    chan struct{}
+	once    sync.Once
+}
+
+// upgrades the connection, returns after the connection is closed; errors after
+// the upgrade are only logged, since no response could be written any more
+func (e *policyExecutor) executeWebSocket() error {
+	ws := e.policy.WebSocket
+
+	upgrader := &websocket.Upgrader{
+		Subprotocols: ws.Subprotocols,
+		CheckOrigin:  func(r *http.Request) bool { return true }, // a mock server accepts every origin
+	}
+	header := make(http.Header)
+	for _, pair := range ws.Headers {
+		header[pair.Name] = pair.Values
+	}
+
+	// the upgrader replies with an error status if the request is not a valid upgrade
+	conn, err := upgrader.Upgrade(*e.w, e.r, header)
+	if err != nil {
+		executorLog.Debugf("websocket: %s %s => %v", e.r.Method, e.r.URL, err)
+		return nil
+	}
+	e.recordStatus(http.StatusSwitchingProtocols)
+
+	s := &wsSession{ws: ws, conn: conn, done: make(chan struct{})}
+	if err := s.run(e.r.Context().Done()); err != nil {
+		executorLog.Debugf("websocket: %s %s => %v", e.r.Method, e.r.URL, err)
+	}
+	return nil
+}
+
+// runs the session until the client disconnects, the session is closed as
+// scripted or the server is shutting down
+func (s *wsSession) run(shutdown <-chan struct{}) error {
+	defer func() {
+		_ = s.conn.Close()
+	}()
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- s.readLoop()
+	}()
+
+	if err := s.send(s.ws.OnConnect); err != nil {
+		s.finish()
+		return err
+	}
+	for _, push := range s.ws.Pushes {
+		go s.pushLoop(push)
+	}
+	if c := s.ws.Close; c != nil {
+		go func() {
+			if s.sleep(randomDuration(c.After)) {
+				s.close(c)
+			}
+		}()
+	}
+
+	select {
+	case err := <-readErr:
+		s.finish()
+		return err
+	case <-s.done:
+	case <-shutdown:
+		s.close(&mckmaps.WSClose{Code: websocket.CloseGoingAway})
+	}
+
+	// waits for the client to acknowledge the close message
+	select {
+	case <-readErr:
+	case <-time.After(wsCloseTimeout):
+	}
+	return nil
+}
+
+// replies to incoming messages until the connection is closed
+func (s *wsSession) readLoop() error {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+
+		reply := s.matchReply(data)
+		if reply == nil {
+			continue
+		}
+		if err := s.send(reply.Messages); err != nil {
+			return err
+		}
+		if reply.Close != nil {
+			s.close(reply.Close)
+		}
+	}
+}
+
+func (s *wsSession) matchReply(data []byte) *mckmaps.WSReply {
+	for _, reply := range s.ws.Replies {
+		if wsReplyMatches(reply, data) {
+			return reply
+		}
+	}
+	return nil
+}
+
+func wsReplyMatches(reply *mckmaps.WSReply, data []byte) bool {
+	if reply.Body != nil {
+		return bytes.Equal(reply.Body, data)
+	} else if reply.BodyRegexp != nil {
+		return reply.BodyRegexp.Match(data)
+	} else if reply.BodyJSON != nil {
+		json, err := myjson.Unmarshal(data)
+		if err != nil {
+			return false
+		}
+		return reply.BodyJSON.Matches(json)
+	} else {
+		return true
+	}
+}
+
+func (s *wsSession) pushLoop(push *mckmaps.WSPush) {
+	for n := 0; push.Times == 0 || n < push.Times; n++ {
+		if !s.sleep(randomDuration(push.Interval)) {
+			return
+		}
+		if err := s.send([]*mckmaps.WSMessage{push.Message}); err != nil {
+			return
+		}
+	}
+}
+
+// sends the messages in order, waiting for their delays
+func (s *wsSession) send(messages []*mckmaps.WSMessage) error {
+	for _, m := range messages {
+		if m.Delay != nil && !s.sleep(randomDuration(m.Delay)) {
+			return nil
+		}
+
+		messageType := websocket.TextMessage
+		if m.Binary {
+			messageType = websocket.BinaryMessage
+		}
+		s.writeMu.Lock()
+		err := s.conn.WriteMessage(messageType, m.Data)
+		s.writeMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sends a close message and ends the session
+func (s *wsSession) close(c *mckmaps.WSClose) {
+	s.writeMu.Lock()
+	deadline := time.Now().Add(wsCloseTimeout)
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.Code, c.Reason), deadline)
+	s.writeMu.Unlock()
+	s.finish()
+}
+
+func (s *wsSession) finish() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// sleeps for the given duration, returns false if the session ends in the meantime
+func (s *wsSession) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-s.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kumasuke120/mockuma/internal/mckmaps"
+	"github.com/kumasuke120/mockuma/internal/myhttp"
+	"github.com/kumasuke120/mockuma/internal/myjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebSocketMapping(uri string, ws *mckmaps.WebSocket) *mckmaps.Mapping {
+	return &mckmaps.Mapping{
+		URI:    uri,
+		Method: myhttp.MethodGet,
+		Policies: []*mckmaps.Policy{
+			{CmdType: mckmaps.CmdTypeWebSocket, WebSocket: ws},
+		},
+	}
+}
+
+var mappingsWithWebSocket = &mckmaps.MockuMappings{
+	Mappings: []*mckmaps.Mapping{
+		newWebSocketMapping("/chat", &mckmaps.WebSocket{
+			Headers:      []*mckmaps.NameValuesPair{{Name: "X-Room", Values: []string{"lobby"}}},
+			Subprotocols: []string{"chat"},
+			OnConnect:    []*mckmaps.WSMessage{{Data: []byte("welcome")}},
+			Replies: []*mckmaps.WSReply{
+				{Body: []byte("ping"), Messages: []*mckmaps.WSMessage{{Data: []byte("pong")}}},
+				{
+					BodyJSON: myjson.NewExtJSONMatcher(myjson.Object{"type": myjson.String("join")}),
+					Messages: []*mckmaps.WSMessage{
+						{Data: []byte(`{"type":"joined"}`)},
+						{Data: []byte{0, 1}, Binary: true, Delay: &mckmaps.Interval{Min: 10, Max: 10}},
+					},
+				},
+				{
+					BodyRegexp: regexp.MustCompile("^bye"),
+					Messages:   []*mckmaps.WSMessage{{Data: []byte("see you")}},
+					Close:      &mckmaps.WSClose{Code: 4000, Reason: "bye"},
+				},
+			},
+		}),
+		newWebSocketMapping("/ticker", &mckmaps.WebSocket{
+			Pushes: []*mckmaps.WSPush{
+				{Interval: &mckmaps.Interval{Min: 10, Max: 10}, Message: &mckmaps.WSMessage{Data: []byte("tick")}, Times: 2},
+			},
+			Close: &mckmaps.WSClose{After: &mckmaps.Interval{Min: 100, Max: 100}, Code: 1000},
+		}),
+	},
+	Config: &mckmaps.Config{
+		CORS: &mckmaps.CORSOptions{Enabled: false},
+	},
+}
+
+func TestPolicyExecutor_executeWebSocket(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+	//noinspection GoImportUsedAsName
+	require := require.New(t)
+
+	s := httptest.NewServer(newMockHandler(mappingsWithWebSocket))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+	dialer := &websocket.Dialer{Subprotocols: []string{"chat"}, HandshakeTimeout: time.Second}
+
+	conn1, resp1, err1 := dialer.Dial(wsURL+"/chat", nil)
+	require.Nil(err1)
+	assert.Equal(http.StatusSwitchingProtocols, resp1.StatusCode)
+	assert.Equal("lobby", resp1.Header.Get("X-Room"))
+	assert.Equal("chat", conn1.Subprotocol())
+	_ = conn1.SetReadDeadline(time.Now().Add(time.Second))
+
+	assertMessage := func(conn *websocket.Conn, messageType int, data string) {
+		mt, d, err := conn.ReadMessage()
+		if assert.Nil(err) {
+			assert.Equal(messageType, mt)
+			assert.Equal(data, string(d))
+		}
+	}
+	assertMessage(conn1, websocket.TextMessage, "welcome")
+
+	require.Nil(conn1.WriteMessage(websocket.TextMessage, []byte("ping")))
+	assertMessage(conn1, websocket.TextMessage, "pong")
+
+	require.Nil(conn1.WriteMessage(websocket.TextMessage, []byte(`{"type": "join", "name": "kuma"}`)))
+	assertMessage(conn1, websocket.TextMessage, `{"type":"joined"}`)
+	assertMessage(conn1, websocket.BinaryMessage, "\x00\x01")
+
+	// unmatched messages are ignored
+	require.Nil(conn1.WriteMessage(websocket.TextMessage, []byte("hello")))
+	require.Nil(conn1.WriteMessage(websocket.TextMessage, []byte("bye-bye")))
+	assertMessage(conn1, websocket.TextMessage, "see you")
+	_, _, err1 = conn1.ReadMessage()
+	assert.True(websocket.IsCloseError(err1, 4000))
+	_ = conn1.Close()
+
+	conn2, _, err2 := websocket.DefaultDialer.Dial(wsURL+"/ticker", nil)
+	require.Nil(err2)
+	_ = conn2.SetReadDeadline(time.Now().Add(time.Second))
+	assertMessage(conn2, websocket.TextMessage, "tick")
+	assertMessage(conn2, websocket.TextMessage, "tick")
+	_, _, err2 = conn2.ReadMessage()
+	assert.True(websocket.IsCloseError(err2, websocket.CloseNormalClosure))
+	_ = conn2.Close()
+
+	// not an upgrade request
+	resp3, err3 := http.Get(s.URL + "/chat")
+	if assert.Nil(err3) {
+		assert.Equal(http.StatusBadRequest, resp3.StatusCode)
+		_ = resp3.Body.Close()
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// records the status code and the size of the response written through it
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	written    int64
+
+	bodyLimit int    // the maximum number of bytes of the body to keep
+	body      []byte // the beginning of the body, at most bodyLimit bytes
+}
+
+func newRecordingWriter(w http.ResponseWriter) *recordingWriter {
+	return &recordingWriter{ResponseWriter: w}
+}
+
+func (w *recordingWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 { // only the first status code takes effect
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	if remains := w.bodyLimit - len(w.body); remains > 0 {
+		if remains > n {
+			remains = n
+		}
+		w.body = append(w.body, b[:remains]...)
+	}
+	return n, err
+}
+
+func (w *recordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("the response writer does not support hijacking")
+}
+
+func (w *recordingWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *recordingWriter) status() int {
+	if w.statusCode == 0 { // nothing has been written yet
+		return http.StatusOK
+	}
+	return w.statusCode
+}
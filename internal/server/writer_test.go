@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingWriter(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	rr1 := httptest.NewRecorder()
+	w1 := newRecordingWriter(rr1)
+	assert.Equal(http.StatusOK, w1.status())
+	w1.WriteHeader(http.StatusNotFound)
+	w1.WriteHeader(http.StatusOK)
+	n, err := w1.Write([]byte("test"))
+	if assert.Nil(err) {
+		assert.Equal(4, n)
+	}
+	w1.Flush()
+	assert.Equal(http.StatusNotFound, w1.status())
+	assert.Equal(int64(4), w1.written)
+	assert.True(rr1.Flushed)
+
+	w2 := newRecordingWriter(httptest.NewRecorder())
+	_, _ = w2.Write([]byte("test"))
+	assert.Equal(http.StatusOK, w2.status())
+	assert.Empty(w2.body)
+
+	w3 := newRecordingWriter(httptest.NewRecorder())
+	w3.bodyLimit = 6
+	_, _ = w3.Write([]byte("test"))
+	_, _ = w3.Write([]byte("test"))
+	_, _ = w3.Write([]byte("test"))
+	assert.Equal("testte", string(w3.body))
+	assert.Equal(int64(12), w3.written)
+}
+
+// records targets pushed through it
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (r *pushRecorder) Push(target string, _ *http.PushOptions) error {
+	r.pushed = append(r.pushed, target)
+	return nil
+}
+
+func TestRecordingWriter_Push(t *testing.T) {
+	//noinspection GoImportUsedAsName
+	assert := assert.New(t)
+
+	pr := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w1 := newRecordingWriter(pr)
+	assert.Nil(w1.Push("/app.js", nil))
+	assert.Equal([]string{"/app.js"}, pr.pushed)
+
+	w2 := newRecordingWriter(httptest.NewRecorder())
+	assert.Equal(http.ErrNotSupported, w2.Push("/app.js", nil))
+}